@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/chirik/products/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func main() {
+	var (
+		serverAddr = flag.String("addr", "localhost:50051", "gRPC server address")
+		apiKey     = flag.String("api-key", "", "API key sent in the x-api-key header")
+		out        = flag.String("out", "-", "File to write the products to; - writes to stdout")
+		category   = flag.String("category", "", "Only export products in this category")
+		minPrice   = flag.Float64("min-price", 0, "Only export products costing at least this")
+		maxPrice   = flag.Float64("max-price", 0, "Only export products costing at most this; 0 means no limit")
+		progress   = flag.Duration("progress", 10*time.Second, "Interval between progress reports")
+	)
+	flag.Parse()
+
+	// Logs go to stderr, leaving stdout to the export.
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if *apiKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", *apiKey)
+	}
+
+	conn, err := grpc.NewClient(*serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		logger.Fatal("Failed to connect", zap.String("server", *serverAddr), zap.Error(err))
+	}
+	defer conn.Close()
+
+	var file *os.File
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		file, err = os.Create(*out)
+		if err != nil {
+			logger.Fatal("Failed to create output file", zap.String("path", *out), zap.Error(err))
+		}
+		w = file
+	}
+
+	logger.Info("Starting export",
+		zap.String("server", *serverAddr),
+		zap.String("out", *out),
+		zap.String("category", *category),
+		zap.Float64("min_price", *minPrice),
+		zap.Float64("max_price", *maxPrice),
+	)
+
+	start := time.Now()
+	var exported atomic.Int64
+	go reportProgress(ctx, logger, *progress, &exported)
+
+	err = export(ctx, proto.NewProductsServiceClient(conn), &proto.ListProductsRequest{
+		Category: *category,
+		MinPrice: *minPrice,
+		MaxPrice: *maxPrice,
+	}, w, &exported)
+	if file != nil {
+		err = errors.Join(err, file.Close())
+	}
+	if err != nil {
+		logger.Error("Export failed",
+			zap.Int64("exported", exported.Load()),
+			zap.Duration("elapsed", time.Since(start)),
+			zap.Error(err),
+		)
+		logger.Sync()
+		os.Exit(1)
+	}
+
+	logger.Info("Export completed",
+		zap.Int64("exported", exported.Load()),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+}
+
+// export streams the products matching req to w as newline-delimited
+// protobuf JSON, one product per line, counting them in exported. Products
+// are written as they arrive, so memory use does not grow with the catalog.
+func export(ctx context.Context, client proto.ProductsServiceClient, req *proto.ListProductsRequest, w io.Writer, exported *atomic.Int64) error {
+	stream, err := client.StreamProducts(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	for {
+		product, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line, err := protojson.Marshal(product)
+		if err != nil {
+			return err
+		}
+		if _, err := buf.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		exported.Add(1)
+	}
+	return buf.Flush()
+}
+
+func reportProgress(ctx context.Context, logger *zap.Logger, interval time.Duration, exported *atomic.Int64) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logger.Info("Export progress",
+				zap.Int64("exported", exported.Load()),
+				zap.Duration("elapsed", time.Since(start)),
+			)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"maps"
+	"net"
+	"slices"
+	"sort"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chirik/products/internal/repository"
+	"github.com/chirik/products/internal/server"
+	"github.com/chirik/products/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// newExportTestClient serves the products service over an in-memory
+// connection, backed by an in-process Redis holding products.
+func newExportTestClient(t *testing.T, products []*repository.Product) proto.ProductsServiceClient {
+	t.Helper()
+
+	redis := miniredis.RunT(t)
+	repo, err := repository.NewRedisRepository(repository.Options{
+		RedisMode:       repository.RedisModeSingle,
+		RedisAddrs:      []string{redis.Addr()},
+		MGetBatchSize:   100,
+		MGetParallelism: 4,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRedisRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	for _, product := range products {
+		if err := repo.CreateProduct(context.Background(), product); err != nil {
+			t.Fatalf("CreateProduct(%s): %v", product.ID, err)
+		}
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	proto.RegisterProductsServiceServer(grpcServer, server.NewProductsServer(repo, zap.NewNop(), server.Options{
+		DefaultPageSize: 10,
+		MaxPageSize:     100,
+		MaxPrice:        1_000_000,
+		MaxStock:        1_000_000,
+	}))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewProductsServiceClient(conn)
+}
+
+// readExport decodes the lines written by export, sorted by ID.
+func readExport(t *testing.T, data []byte) []*proto.Product {
+	t.Helper()
+
+	var products []*proto.Product
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var product proto.Product
+		if err := protojson.Unmarshal(scanner.Bytes(), &product); err != nil {
+			t.Fatalf("line %d does not parse: %v\n%s", len(products)+1, err, scanner.Bytes())
+		}
+		products = append(products, &product)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Id < products[j].Id })
+	return products
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	seeded := []*repository.Product{
+		{ID: "p1", Name: "Mouse", Category: "Electronics", Price: 19.99, Stock: 5, SKU: "MOUSE-1", Tags: []string{"wireless"}, Attributes: map[string]string{"color": "black"}},
+		{ID: "p2", Name: "Keyboard", Category: "Electronics", Price: 49.5, Stock: 2},
+		{ID: "p3", Name: "Novel", Description: "A long read", Category: "Books", Price: 12, Stock: 40},
+		{ID: "p4", Name: "Monitor", Category: "Electronics", Price: 199, Stock: 0},
+	}
+	client := newExportTestClient(t, seeded)
+
+	var out bytes.Buffer
+	var exported atomic.Int64
+	if err := export(context.Background(), client, &proto.ListProductsRequest{}, &out, &exported); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if exported.Load() != int64(len(seeded)) {
+		t.Errorf("counted %d exported products, want %d", exported.Load(), len(seeded))
+	}
+
+	products := readExport(t, out.Bytes())
+	if len(products) != len(seeded) {
+		t.Fatalf("read back %d products, want %d", len(products), len(seeded))
+	}
+	for i, want := range seeded {
+		got := products[i]
+		if got.Id != want.ID || got.Name != want.Name || got.Description != want.Description ||
+			got.Category != want.Category || got.Price != want.Price || got.Stock != want.Stock || got.Sku != want.SKU {
+			t.Errorf("read back %v, want %+v", got, want)
+		}
+		if !slices.Equal(got.Tags, want.Tags) || !maps.Equal(got.Attributes, want.Attributes) {
+			t.Errorf("%s tags = %v, attributes = %v; want %v and %v", got.Id, got.Tags, got.Attributes, want.Tags, want.Attributes)
+		}
+		if got.CreatedAt == "" && got.CreatedTime == nil {
+			t.Errorf("%s was exported without its creation time", got.Id)
+		}
+	}
+}
+
+func TestExportFilters(t *testing.T) {
+	client := newExportTestClient(t, []*repository.Product{
+		{ID: "p1", Name: "Mouse", Category: "Electronics", Price: 19.99},
+		{ID: "p2", Name: "Keyboard", Category: "Electronics", Price: 49.5},
+		{ID: "p3", Name: "Novel", Category: "Books", Price: 12},
+		{ID: "p4", Name: "Monitor", Category: "Electronics", Price: 199},
+	})
+
+	var out bytes.Buffer
+	var exported atomic.Int64
+	req := &proto.ListProductsRequest{Category: "Electronics", MinPrice: 20, MaxPrice: 100}
+	if err := export(context.Background(), client, req, &out, &exported); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	products := readExport(t, out.Bytes())
+	if len(products) != 1 || products[0].Id != "p2" {
+		t.Errorf("exported %v, want only p2", products)
+	}
+	if exported.Load() != 1 {
+		t.Errorf("counted %d exported products, want 1", exported.Load())
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/chirik/products/internal/config"
+	"github.com/chirik/products/internal/repository"
+	"github.com/chirik/products/proto"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxLineSize bounds the length of one NDJSON record.
+const maxLineSize = 16 << 20
+
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+func main() {
+	var (
+		in        = flag.String("in", "-", "NDJSON file to import; - reads from stdin")
+		batchSize = flag.Int("batch", 500, "Number of products written per Redis pipeline")
+		dryRun    = flag.Bool("dry-run", false, "Parse and validate the input without writing anything")
+		progress  = flag.Duration("progress", 10*time.Second, "Interval between progress reports")
+	)
+	flag.Parse()
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Sync()
+
+	if *batchSize < 1 {
+		logger.Fatal("-batch must be at least 1", zap.Int("batch", *batchSize))
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			logger.Fatal("Failed to open input file", zap.String("path", *in), zap.Error(err))
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// write stores a batch; a dry run only counts it.
+	write := func(context.Context, []*repository.Product) error { return nil }
+	if !*dryRun {
+		cfg, err := config.Load()
+		if err != nil {
+			logger.Fatal("Failed to load configuration", zap.Error(err))
+		}
+		if err := cfg.Validate(); err != nil {
+			logger.Fatal("Invalid configuration", zap.Error(err))
+		}
+		// The import is the data, so seeding stays off: don't top it up
+		// with generated products.
+		repo, err := repository.NewRedisRepository(repository.Options{
+			RedisMode:       cfg.RedisMode,
+			RedisAddrs:      cfg.RedisAddrs,
+			RedisMasterName: cfg.RedisMasterName,
+			ClientName:      cfg.ServiceName(),
+			PoolSize:        cfg.RedisPoolSize,
+			DialTimeout:     cfg.RedisDialTimeout,
+			ReadTimeout:     cfg.RedisReadTimeout,
+			WriteTimeout:    cfg.RedisWriteTimeout,
+			MaxRetries:      cfg.RedisMaxRetries,
+			SearchAddr:      cfg.SearchAddr,
+
+			MGetBatchSize:    cfg.RedisMGetBatchSize,
+			MGetParallelism:  cfg.RedisMGetParallelism,
+			RecreateIndex:    cfg.SearchIndexRecreate,
+			IndexConcurrency: cfg.IndexConcurrency,
+		}, logger)
+		if err != nil {
+			logger.Fatal("Failed to create repository", zap.Error(err))
+		}
+		defer repo.Close()
+		write = repo.UpsertProducts
+	}
+
+	logger.Info("Starting import",
+		zap.String("in", *in),
+		zap.Int("batch", *batchSize),
+		zap.Bool("dry_run", *dryRun),
+	)
+
+	start := time.Now()
+	stats, err := importProducts(ctx, r, *batchSize, write, logger, *progress)
+	fields := []zap.Field{
+		zap.Int("lines", stats.lines),
+		zap.Int("imported", stats.imported),
+		zap.Int("skipped", stats.skipped),
+		zap.Bool("dry_run", *dryRun),
+		zap.Duration("elapsed", time.Since(start)),
+	}
+	if err != nil {
+		logger.Error("Import failed", append(fields, zap.Error(err))...)
+		logger.Sync()
+		os.Exit(1)
+	}
+	if stats.skipped > 0 {
+		logger.Warn("Import completed with skipped lines", fields...)
+	} else {
+		logger.Info("Import completed", fields...)
+	}
+}
+
+// importStats counts the input lines read, the products imported (or, in a
+// dry run, found valid) and the malformed lines skipped.
+type importStats struct {
+	lines    int
+	imported int
+	skipped  int
+}
+
+// importProducts reads one product per line from r and passes them to
+// write in batches of batchSize. Blank lines are ignored; malformed or
+// invalid records are logged and skipped. It stops at the first write
+// error.
+func importProducts(ctx context.Context, r io.Reader, batchSize int, write func(context.Context, []*repository.Product) error, logger *zap.Logger, progress time.Duration) (importStats, error) {
+	var stats importStats
+	batch := make([]*repository.Product, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := write(ctx, batch); err != nil {
+			return fmt.Errorf("failed to write products ending at line %d: %w", stats.lines, err)
+		}
+		stats.imported += len(batch)
+		batch = make([]*repository.Product, 0, batchSize)
+		return nil
+	}
+
+	lastReport := time.Now()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		stats.lines++
+
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		product, err := parseProduct(line)
+		if err != nil {
+			stats.skipped++
+			logger.Warn("Skipping malformed line", zap.Int("line", stats.lines), zap.Error(err))
+			continue
+		}
+
+		batch = append(batch, product)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+
+		if progress > 0 && time.Since(lastReport) >= progress {
+			logger.Info("Import progress",
+				zap.Int("lines", stats.lines),
+				zap.Int("imported", stats.imported),
+				zap.Int("skipped", stats.skipped),
+			)
+			lastReport = time.Now()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, fmt.Errorf("failed to read line %d: %w", stats.lines+1, err)
+	}
+	return stats, flush()
+}
+
+// parseProduct decodes one record as written by the export tool: a Product
+// in protobuf JSON, with timestamps either as created_time/updated_time or,
+// for API version 1 exports, as RFC 3339 created_at/updated_at strings.
+// Records from the HTTP export parse too.
+func parseProduct(line []byte) (*repository.Product, error) {
+	var msg proto.Product
+	if err := unmarshalOptions.Unmarshal(line, &msg); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case msg.Name == "":
+		return nil, errors.New("name is required")
+	case math.IsNaN(msg.Price) || math.IsInf(msg.Price, 0) || msg.Price < 0:
+		return nil, fmt.Errorf("price %g must be a non-negative number", msg.Price)
+	case msg.Stock < 0:
+		return nil, fmt.Errorf("stock %d must not be negative", msg.Stock)
+	}
+
+	createdAt, err := parseTime(msg.CreatedTime.AsTime(), msg.CreatedTime != nil, msg.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid created_at: %w", err)
+	}
+	updatedAt, err := parseTime(msg.UpdatedTime.AsTime(), msg.UpdatedTime != nil, msg.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid updated_at: %w", err)
+	}
+
+	return &repository.Product{
+		ID:          msg.Id,
+		Name:        msg.Name,
+		Description: msg.Description,
+		Price:       msg.Price,
+		Category:    msg.Category,
+		Stock:       msg.Stock,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+		Slug:        msg.Slug,
+		SKU:         msg.Sku,
+		Tags:        msg.Tags,
+		Attributes:  msg.Attributes,
+		Currency:    msg.Currency,
+		Version:     msg.Version,
+	}, nil
+}
+
+// parseTime returns timestamp when set, else the RFC 3339 text, else the
+// zero time.
+func parseTime(timestamp time.Time, set bool, text string) (time.Time, error) {
+	switch {
+	case set:
+		return timestamp, nil
+	case text != "":
+		return time.Parse(time.RFC3339Nano, text)
+	default:
+		return time.Time{}, nil
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chirik/products/internal/repository"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const importInput = `{"id": "p1", "name": "Mouse", "description": "Wireless", "price": 19.99, "category": "Electronics", "stock": 5, "sku": "MOUSE-1", "tags": ["wireless", "usb"], "attributes": {"color": "black"}, "currency": "USD", "createdTime": "2024-01-02T03:04:05Z", "updatedTime": "2024-02-03T04:05:06Z"}
+not json
+{"id": "p2", "name": "Novel", "price": 12.5, "category": "Books", "stock": 40, "created_at": "2023-05-06T07:08:09Z", "updated_at": "2023-06-07T08:09:10Z"}
+
+{"id": "p3", "price": 5}
+{"id": "p4", "name": "Cable", "price": -1}
+{"id": "p5", "name": "Lamp", "price": 30, "category": "Home", "stock": 2}
+`
+
+func TestImportProducts(t *testing.T) {
+	redis := miniredis.RunT(t)
+	repo, err := repository.NewRedisRepository(repository.Options{
+		RedisMode:       repository.RedisModeSingle,
+		RedisAddrs:      []string{redis.Addr()},
+		MGetBatchSize:   100,
+		MGetParallelism: 4,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRedisRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	core, logs := observer.New(zap.WarnLevel)
+	ctx := context.Background()
+	stats, err := importProducts(ctx, strings.NewReader(importInput), 2, repo.UpsertProducts, zap.New(core), 0)
+	if err != nil {
+		t.Fatalf("importProducts: %v", err)
+	}
+	if stats != (importStats{lines: 7, imported: 3, skipped: 3}) {
+		t.Errorf("stats = %+v, want 7 lines, 3 imported, 3 skipped", stats)
+	}
+	if got := logs.FilterMessage("Skipping malformed line").Len(); got != 3 {
+		t.Errorf("logged %d skipped lines, want 3", got)
+	}
+
+	mouse, err := repo.GetProduct(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetProduct(p1): %v", err)
+	}
+	if mouse.Name != "Mouse" || mouse.Description != "Wireless" || mouse.Price != 19.99 || mouse.Category != "Electronics" ||
+		mouse.Stock != 5 || mouse.SKU != "MOUSE-1" || !slices.Equal(mouse.Tags, []string{"wireless", "usb"}) || mouse.Attributes["color"] != "black" || mouse.Currency != "USD" {
+		t.Errorf("imported p1 = %+v", mouse)
+	}
+	checkTime(t, "p1 created", mouse.CreatedAt, "2024-01-02T03:04:05Z")
+	checkTime(t, "p1 updated", mouse.UpdatedAt, "2024-02-03T04:05:06Z")
+
+	// Version 1 exports carry RFC 3339 strings instead of timestamps.
+	novel, err := repo.GetProduct(ctx, "p2")
+	if err != nil {
+		t.Fatalf("GetProduct(p2): %v", err)
+	}
+	if novel.Name != "Novel" || novel.Price != 12.5 || novel.Category != "Books" || novel.Stock != 40 {
+		t.Errorf("imported p2 = %+v", novel)
+	}
+	checkTime(t, "p2 created", novel.CreatedAt, "2023-05-06T07:08:09Z")
+	checkTime(t, "p2 updated", novel.UpdatedAt, "2023-06-07T08:09:10Z")
+
+	if _, err := repo.GetProduct(ctx, "p5"); err != nil {
+		t.Errorf("GetProduct(p5): %v", err)
+	}
+	for _, id := range []string{"p3", "p4"} {
+		if _, err := repo.GetProduct(ctx, id); err == nil {
+			t.Errorf("malformed product %s was imported", id)
+		}
+	}
+}
+
+func TestImportProductsDryRun(t *testing.T) {
+	// A dry run validates with a writer that stores nothing.
+	var written int
+	write := func(_ context.Context, batch []*repository.Product) error {
+		written += len(batch)
+		return nil
+	}
+	stats, err := importProducts(context.Background(), strings.NewReader(importInput), 500, write, zap.NewNop(), 0)
+	if err != nil {
+		t.Fatalf("importProducts: %v", err)
+	}
+	if stats.imported != 3 || stats.skipped != 3 || written != 3 {
+		t.Errorf("dry run found %d valid and %d malformed, passed on %d; want 3, 3 and 3", stats.imported, stats.skipped, written)
+	}
+}
+
+// checkTime fails unless got is the RFC 3339 time want.
+func checkTime(t *testing.T, what string, got time.Time, want string) {
+	t.Helper()
+
+	wantTime, err := time.Parse(time.RFC3339, want)
+	if err != nil {
+		t.Fatalf("time.Parse(%q): %v", want, err)
+	}
+	if !got.Equal(wantTime) {
+		t.Errorf("%s at %v, want %v", what, got, wantTime)
+	}
+}
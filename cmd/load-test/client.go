@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/chirik/products/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	gproto "google.golang.org/protobuf/proto"
+)
+
+// Protocols the virtual users can speak.
+const (
+	protocolGRPC = "grpc"
+	protocolHTTP = "http"
+)
+
+// productsClient makes the operations of the mix, so the same mix runs
+// against the gRPC service and against its REST gateway.
+type productsClient interface {
+	ListProducts(ctx context.Context, req *proto.ListProductsRequest) error
+	GetProduct(ctx context.Context, req *proto.GetProductRequest) error
+	CreateProduct(ctx context.Context, req *proto.CreateProductRequest) error
+}
+
+// grpcClient makes the operations over a gRPC connection.
+type grpcClient struct {
+	client proto.ProductsServiceClient
+}
+
+func newGRPCClient(conn *grpc.ClientConn) *grpcClient {
+	return &grpcClient{client: proto.NewProductsServiceClient(conn)}
+}
+
+func (c *grpcClient) ListProducts(ctx context.Context, req *proto.ListProductsRequest) error {
+	_, err := c.client.ListProducts(ctx, req)
+	return err
+}
+
+func (c *grpcClient) GetProduct(ctx context.Context, req *proto.GetProductRequest) error {
+	_, err := c.client.GetProduct(ctx, req)
+	return err
+}
+
+func (c *grpcClient) CreateProduct(ctx context.Context, req *proto.CreateProductRequest) error {
+	_, err := c.client.CreateProduct(ctx, req)
+	return err
+}
+
+// httpClient makes the operations against the REST gateway routes at
+// baseURL.
+type httpClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// newHTTPClient returns a client for the gateway at addr, keeping up to
+// maxConns idle connections to it so the virtual users reuse connections
+// as they do over gRPC.
+func newHTTPClient(addr string, maxConns int) *httpClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = maxConns
+	transport.MaxIdleConnsPerHost = maxConns
+	return &httpClient{
+		baseURL: "http://" + addr,
+		client:  &http.Client{Transport: transport},
+	}
+}
+
+func (c *httpClient) ListProducts(ctx context.Context, req *proto.ListProductsRequest) error {
+	query := url.Values{}
+	if req.Page != 0 {
+		query.Set("page", strconv.Itoa(int(req.Page)))
+	}
+	if req.PageSize != 0 {
+		query.Set("page_size", strconv.Itoa(int(req.PageSize)))
+	}
+	if req.Category != "" {
+		query.Set("category", req.Category)
+	}
+	if req.SearchQuery != "" {
+		query.Set("search_query", req.SearchQuery)
+	}
+	return c.do(ctx, http.MethodGet, "/v1/products?"+query.Encode(), nil)
+}
+
+func (c *httpClient) GetProduct(ctx context.Context, req *proto.GetProductRequest) error {
+	return c.do(ctx, http.MethodGet, "/v1/products/"+url.PathEscape(req.Id), nil)
+}
+
+func (c *httpClient) CreateProduct(ctx context.Context, req *proto.CreateProductRequest) error {
+	return c.do(ctx, http.MethodPost, "/v1/products", req)
+}
+
+// do sends a request with body encoded as JSON, if any, and reads the whole
+// response so its connection can be reused. Statuses other than 200 are
+// errors.
+func (c *httpClient) do(ctx context.Context, method, path string, body gproto.Message) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := protojson.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(respBody))
+	}
+	return nil
+}
+
+// close releases the idle connections.
+func (c *httpClient) close() {
+	c.client.CloseIdleConnections()
+}
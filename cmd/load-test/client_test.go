@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chirik/products/proto"
+)
+
+func TestHTTPClientRoutes(t *testing.T) {
+	var got []string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.Error(w, `{"code": 5, "message": "product not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer gateway.Close()
+
+	client := newHTTPClient(strings.TrimPrefix(gateway.URL, "http://"), 1)
+	defer client.close()
+	ctx := context.Background()
+
+	if err := client.ListProducts(ctx, &proto.ListProductsRequest{Page: 2, PageSize: 10, Category: "Home & Garden", SearchQuery: "rake"}); err != nil {
+		t.Errorf("ListProducts: %v", err)
+	}
+	if err := client.GetProduct(ctx, &proto.GetProductRequest{Id: "p1"}); err != nil {
+		t.Errorf("GetProduct: %v", err)
+	}
+	if err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Rake", Price: 20}); err != nil {
+		t.Errorf("CreateProduct: %v", err)
+	}
+	if err := client.GetProduct(ctx, &proto.GetProductRequest{Id: "missing"}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("GetProduct(missing) = %v, want a 404 error", err)
+	}
+
+	want := []string{
+		"GET /v1/products?category=Home+%26+Garden&page=2&page_size=10&search_query=rake ",
+		"GET /v1/products/p1 ",
+		`POST /v1/products {"name":"Rake","price":20}`,
+		"GET /v1/products/missing ",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("gateway got %q, want %q", got, want)
+	}
+	for i := range want {
+		// protojson output may vary in whitespace.
+		if strings.ReplaceAll(got[i], " ", "") != strings.ReplaceAll(want[i], " ", "") {
+			t.Errorf("request %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// dialAttemptTimeout bounds how long one attempt waits for a connection
+	// to become ready.
+	dialAttemptTimeout = 5 * time.Second
+
+	dialInitialBackoff = 250 * time.Millisecond
+	dialMaxBackoff     = 5 * time.Second
+)
+
+// dialConfig is how the pool's connections are made.
+type dialConfig struct {
+	addr             string
+	keepaliveTime    time.Duration
+	keepaliveTimeout time.Duration
+	retries          int
+}
+
+// connPool shares gRPC connections between virtual users. Each virtual user
+// is assigned a slot by poolSlot; the connection in a slot is dialled by the
+// first user to need it and redialled if that dial failed.
+type connPool struct {
+	config dialConfig
+	logger *zap.Logger
+	slots  []connSlot
+}
+
+type connSlot struct {
+	mu   sync.Mutex
+	conn *grpc.ClientConn
+}
+
+// newConnPool returns a pool of size connections, or one per virtual user
+// when size is 0 or more than vusers.
+func newConnPool(config dialConfig, size, vusers int, logger *zap.Logger) *connPool {
+	if size <= 0 || size > vusers {
+		size = vusers
+	}
+	return &connPool{
+		config: config,
+		logger: logger,
+		slots:  make([]connSlot, max(size, 1)),
+	}
+}
+
+// poolSlot spreads virtual users over size connections round-robin, so
+// that the number of users per connection differs by at most one.
+func poolSlot(userID, size int) int {
+	return userID % size
+}
+
+// get returns the connection for userID, dialling it first if needed.
+func (p *connPool) get(ctx context.Context, userID int) (*grpc.ClientConn, error) {
+	slot := &p.slots[poolSlot(userID, len(p.slots))]
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	if slot.conn != nil {
+		return slot.conn, nil
+	}
+	conn, err := dialWithRetry(ctx, p.config, p.logger.With(zap.Int("user", userID)))
+	if err != nil {
+		return nil, err
+	}
+	slot.conn = conn
+	return conn, nil
+}
+
+// close closes every connection dialled so far.
+func (p *connPool) close() {
+	for i := range p.slots {
+		slot := &p.slots[i]
+		slot.mu.Lock()
+		if slot.conn != nil {
+			slot.conn.Close()
+			slot.conn = nil
+		}
+		slot.mu.Unlock()
+	}
+}
+
+// dialWithRetry connects to config.addr and waits for the connection to be
+// ready, retrying up to config.retries times with exponential backoff so
+// that virtual users starting together don't all hammer a server that is
+// still coming up.
+func dialWithRetry(ctx context.Context, config dialConfig, logger *zap.Logger) (*grpc.ClientConn, error) {
+	backoff := dialInitialBackoff
+	for attempt := 0; ; attempt++ {
+		conn, err := dial(ctx, config)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= config.retries {
+			return nil, fmt.Errorf("connecting to %s failed after %d attempts: %w", config.addr, attempt+1, err)
+		}
+
+		logger.Warn("Failed to connect, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, dialMaxBackoff)
+	}
+}
+
+// dial makes one connection attempt.
+func dial(ctx context.Context, config dialConfig) (*grpc.ClientConn, error) {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if config.keepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    config.keepaliveTime,
+			Timeout: config.keepaliveTimeout,
+		}))
+	}
+
+	conn, err := grpc.NewClient(config.addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dialAttemptTimeout)
+	defer cancel()
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return conn, nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			conn.Close()
+			if state == connectivity.TransientFailure {
+				return nil, errors.New("connection failed")
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestPoolSlotDistribution(t *testing.T) {
+	for _, tc := range []struct {
+		users, size int
+	}{
+		{users: 10, size: 3},
+		{users: 9, size: 3},
+		{users: 2, size: 5},
+		{users: 100, size: 1},
+	} {
+		counts := make([]int, tc.size)
+		for user := 0; user < tc.users; user++ {
+			counts[poolSlot(user, tc.size)]++
+		}
+		least, most := counts[0], counts[0]
+		for _, count := range counts {
+			least, most = min(least, count), max(most, count)
+		}
+		if most-least > 1 {
+			t.Errorf("%d users over %d connections = %v, want at most one apart", tc.users, tc.size, counts)
+		}
+	}
+}
+
+func TestNewConnPoolSize(t *testing.T) {
+	for _, tc := range []struct {
+		size, vusers, want int
+	}{
+		{size: 0, vusers: 8, want: 8},
+		{size: 3, vusers: 8, want: 3},
+		{size: 20, vusers: 8, want: 8},
+		{size: 0, vusers: 0, want: 1},
+	} {
+		pool := newConnPool(dialConfig{}, tc.size, tc.vusers, zap.NewNop())
+		if got := len(pool.slots); got != tc.want {
+			t.Errorf("newConnPool(size %d, %d users) has %d slots, want %d", tc.size, tc.vusers, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// requestCounts is a snapshot of the request counters.
+type requestCounts struct {
+	total  int64
+	failed int64
+}
+
+func loadRequestCounts() requestCounts {
+	return requestCounts{
+		total:  atomic.LoadInt64(&totalRequests),
+		failed: atomic.LoadInt64(&failedRequests),
+	}
+}
+
+// errorRateVerdict is the outcome of the -max-error-rate gate.
+type errorRateVerdict struct {
+	pass     bool
+	rate     float64
+	requests int64
+	reason   string
+}
+
+// evaluateErrorRate compares the error rate, in percent, of the requests
+// made between the end of the warmup and the end of the run against
+// maxErrorRate. A run without steady-state requests fails, since nothing
+// was measured.
+func evaluateErrorRate(warm, final requestCounts, maxErrorRate float64) errorRateVerdict {
+	requests := final.total - warm.total
+	if requests <= 0 {
+		return errorRateVerdict{reason: "no requests were made after the warmup"}
+	}
+
+	rate := float64(final.failed-warm.failed) / float64(requests) * 100
+	verdict := errorRateVerdict{pass: rate <= maxErrorRate, rate: rate, requests: requests}
+	if verdict.pass {
+		verdict.reason = fmt.Sprintf("error rate %.2f%% is within the maximum of %.2f%%", rate, maxErrorRate)
+	} else {
+		verdict.reason = fmt.Sprintf("error rate %.2f%% exceeds the maximum of %.2f%%", rate, maxErrorRate)
+	}
+	return verdict
+}
+
+// warmupCounts snapshots the request counters once warmup has elapsed, so
+// that requests made while caches and connections warm up don't count
+// towards the gate. If done is closed first, the snapshot is taken then and
+// leaves no steady-state requests.
+func warmupCounts(warmup time.Duration, done <-chan struct{}) <-chan requestCounts {
+	result := make(chan requestCounts, 1)
+	if warmup <= 0 {
+		result <- requestCounts{}
+		return result
+	}
+	go func() {
+		timer := time.NewTimer(warmup)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-done:
+		}
+		result <- loadRequestCounts()
+	}()
+	return result
+}
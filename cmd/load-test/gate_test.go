@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestEvaluateErrorRate(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		warm, final  requestCounts
+		maxErrorRate float64
+		wantPass     bool
+		wantRate     float64
+		wantRequests int64
+	}{
+		{
+			name:  "within the maximum",
+			final: requestCounts{total: 200, failed: 2}, maxErrorRate: 1,
+			wantPass: true, wantRate: 1, wantRequests: 200,
+		},
+		{
+			name:  "above the maximum",
+			final: requestCounts{total: 200, failed: 3}, maxErrorRate: 1,
+			wantRate: 1.5, wantRequests: 200,
+		},
+		{
+			// The warmup had every failure, and it doesn't count.
+			name: "warmup failures excluded",
+			warm: requestCounts{total: 50, failed: 40}, final: requestCounts{total: 150, failed: 40}, maxErrorRate: 0,
+			wantPass: true, wantRate: 0, wantRequests: 100,
+		},
+		{
+			name: "steady-state failures counted",
+			warm: requestCounts{total: 50}, final: requestCounts{total: 150, failed: 10}, maxErrorRate: 5,
+			wantRate: 10, wantRequests: 100,
+		},
+		{
+			name: "no steady-state requests",
+			warm: requestCounts{total: 50, failed: 1}, final: requestCounts{total: 50, failed: 1}, maxErrorRate: 100,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := evaluateErrorRate(tc.warm, tc.final, tc.maxErrorRate)
+			if got.pass != tc.wantPass || got.rate != tc.wantRate || got.requests != tc.wantRequests {
+				t.Errorf("evaluateErrorRate = pass %t, rate %v, requests %d; want %t, %v, %d",
+					got.pass, got.rate, got.requests, tc.wantPass, tc.wantRate, tc.wantRequests)
+			}
+			if got.reason == "" {
+				t.Error("verdict has no reason")
+			}
+		})
+	}
+}
+
+func TestWarmupCountsWithoutWarmup(t *testing.T) {
+	if got := <-warmupCounts(0, nil); got != (requestCounts{}) {
+		t.Errorf("counts without a warmup = %+v, want zero", got)
+	}
+}
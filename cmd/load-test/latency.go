@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// latencies records the latency of every request made by the virtual users.
+var latencies latencyRecorder
+
+// latencyRecorder keeps every latency sample, both since the last periodic
+// report and for the whole run by operation, along with each operation's
+// outcomes. It is safe for concurrent use.
+type latencyRecorder struct {
+	mu         sync.Mutex
+	window     []time.Duration
+	operations map[operation]*operationStats
+}
+
+// operationStats are the outcomes and latencies of one operation.
+type operationStats struct {
+	success, failed int64
+	samples         []time.Duration
+}
+
+func (r *latencyRecorder) record(op operation, d time.Duration, failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.operations == nil {
+		r.operations = make(map[operation]*operationStats)
+	}
+	stats, ok := r.operations[op]
+	if !ok {
+		stats = &operationStats{}
+		r.operations[op] = stats
+	}
+	if failed {
+		stats.failed++
+	} else {
+		stats.success++
+	}
+	stats.samples = append(stats.samples, d)
+	r.window = append(r.window, d)
+}
+
+// takeWindow returns the samples recorded since the previous call.
+func (r *latencyRecorder) takeWindow() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	window := r.window
+	r.window = nil
+	return window
+}
+
+// snapshot returns every sample recorded so far.
+func (r *latencyRecorder) snapshot() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all []time.Duration
+	for _, stats := range r.operations {
+		all = append(all, stats.samples...)
+	}
+	return all
+}
+
+// byOperation returns a copy of the statistics of every operation made.
+func (r *latencyRecorder) byOperation() map[operation]operationStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	byOp := make(map[operation]operationStats, len(r.operations))
+	for op, stats := range r.operations {
+		byOp[op] = operationStats{
+			success: stats.success,
+			failed:  stats.failed,
+			samples: slices.Clone(stats.samples),
+		}
+	}
+	return byOp
+}
+
+// latencySummary describes a set of latency samples.
+type latencySummary struct {
+	min, max, mean time.Duration
+	p50, p90, p99  time.Duration
+}
+
+// summarizeLatencies computes the summary of samples, which it sorts in
+// place.
+func summarizeLatencies(samples []time.Duration) latencySummary {
+	if len(samples) == 0 {
+		return latencySummary{}
+	}
+
+	slices.Sort(samples)
+	var total time.Duration
+	for _, sample := range samples {
+		total += sample
+	}
+
+	return latencySummary{
+		min:  samples[0],
+		max:  samples[len(samples)-1],
+		mean: total / time.Duration(len(samples)),
+		p50:  percentile(samples, 50),
+		p90:  percentile(samples, 90),
+		p99:  percentile(samples, 99),
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted, which must
+// not be empty.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func (s latencySummary) fields() []zap.Field {
+	return []zap.Field{
+		zap.Duration("latency_min", s.min),
+		zap.Duration("latency_mean", s.mean),
+		zap.Duration("latency_p50", s.p50),
+		zap.Duration("latency_p90", s.p90),
+		zap.Duration("latency_p99", s.p99),
+		zap.Duration("latency_max", s.max),
+	}
+}
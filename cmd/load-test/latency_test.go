@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	// 1ms to 100ms, so the nearest-rank percentile p is p milliseconds.
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: time.Millisecond},
+		{p: 50, want: 50 * time.Millisecond},
+		{p: 90, want: 90 * time.Millisecond},
+		{p: 99, want: 99 * time.Millisecond},
+		{p: 99.5, want: 100 * time.Millisecond},
+		{p: 100, want: 100 * time.Millisecond},
+	} {
+		if got := percentile(sorted, tc.p); got != tc.want {
+			t.Errorf("percentile(%v) = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+
+	if got := percentile([]time.Duration{7 * time.Millisecond}, 99); got != 7*time.Millisecond {
+		t.Errorf("percentile of one sample = %v, want 7ms", got)
+	}
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	// 90 fast requests and 10 slow ones, shuffled.
+	var samples []time.Duration
+	for i := 0; i < 90; i++ {
+		samples = append(samples, 10*time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		samples = append(samples, time.Duration(100+i*10)*time.Millisecond)
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(samples), func(i, j int) { samples[i], samples[j] = samples[j], samples[i] })
+
+	got := summarizeLatencies(samples)
+	want := latencySummary{
+		min:  10 * time.Millisecond,
+		max:  190 * time.Millisecond,
+		mean: 23500 * time.Microsecond,
+		p50:  10 * time.Millisecond,
+		p90:  10 * time.Millisecond,
+		p99:  180 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("summarizeLatencies = %+v, want %+v", got, want)
+	}
+
+	if got := summarizeLatencies(nil); got != (latencySummary{}) {
+		t.Errorf("summary of no samples = %+v, want zero", got)
+	}
+}
+
+func TestLatencyRecorderConcurrent(t *testing.T) {
+	var recorder latencyRecorder
+	var wg sync.WaitGroup
+	for user := 0; user < 8; user++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				recorder.record(opGet, time.Millisecond, i%10 == 0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := recorder.byOperation()[opGet]
+	if stats.success != 720 || stats.failed != 80 || len(stats.samples) != 800 {
+		t.Errorf("recorded %d successes, %d failures and %d samples; want 720, 80 and 800",
+			stats.success, stats.failed, len(stats.samples))
+	}
+	if window := recorder.takeWindow(); len(window) != 800 {
+		t.Errorf("window has %d samples, want 800", len(window))
+	}
+	if window := recorder.takeWindow(); len(window) != 0 {
+		t.Errorf("window after taking it has %d samples, want 0", len(window))
+	}
+}
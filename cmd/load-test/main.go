@@ -6,28 +6,43 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/chirik/products/proto"
 	"go.uber.org/zap"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 var (
 	totalRequests   int64
 	failedRequests  int64
 	successRequests int64
+
+	// lastError holds the most recent request error for diagnostics.
+	lastError atomic.Value
 )
 
 func main() {
 	var (
-		serverAddr = flag.String("addr", "localhost:50051", "gRPC server address")
+		serverAddr = flag.String("addr", "", "Server address: the gRPC port, or the REST gateway port with -protocol http (default localhost:50051 with -protocol grpc)")
+		protocol   = flag.String("protocol", protocolGRPC, "Protocol to make requests with: grpc, or http to go through the REST gateway")
 		vusers     = flag.Int("vusers", 10, "Number of virtual users")
 		rpm        = flag.Int("rpm", 60, "Requests per minute")
 		duration   = flag.Duration("duration", 5*time.Minute, "Test duration")
+		rampup     = flag.Duration("rampup", 0, "Stagger virtual user starts evenly over this period; 0 starts them all at once")
+		mixSpec    = flag.String("mix", defaultMix, "Operation mix in percent, as list=N,get=N,create=N adding up to 100")
+		out        = flag.String("out", "", "Write the final results to this file, as CSV if it ends in .csv and JSON otherwise")
+
+		failFastThreshold = flag.Float64("fail-fast-threshold", 0, "Abort when the success rate (percent) stays below this over -fail-fast-window; 0 disables")
+		failFastWindow    = flag.Duration("fail-fast-window", 30*time.Second, "Window over which -fail-fast-threshold is evaluated")
+		connections       = flag.Int("connections", 0, "Number of connections shared round-robin by the virtual users; 0 gives each its own")
+		keepaliveTime     = flag.Duration("keepalive-time", 5*time.Minute, "Ping the server after this long without activity on a connection; 0 disables keepalive")
+		keepaliveTimeout  = flag.Duration("keepalive-timeout", 20*time.Second, "Close a connection when a keepalive ping is not answered within this")
+		dialRetries       = flag.Int("dial-retries", 5, "Retry a failed connection attempt this many times, with exponential backoff")
+		warmup            = flag.Duration("warmup", 0, "Exclude requests made during this initial period from -max-error-rate")
+		maxErrorRate      = flag.Float64("max-error-rate", -1, "Exit non-zero when the error rate (percent) after -warmup exceeds this; negative disables")
 	)
 	flag.Parse()
 
@@ -37,11 +52,34 @@ func main() {
 	}
 	defer logger.Sync()
 
+	mix, err := parseMix(*mixSpec)
+	if err != nil {
+		logger.Fatal("Invalid -mix", zap.Error(err))
+	}
+
+	switch *protocol {
+	case protocolGRPC:
+		if *serverAddr == "" {
+			*serverAddr = "localhost:50051"
+		}
+	case protocolHTTP:
+		if *serverAddr == "" {
+			logger.Fatal("-protocol http needs -addr set to the REST gateway address")
+		}
+	default:
+		logger.Fatal("Invalid -protocol; use grpc or http", zap.String("protocol", *protocol))
+	}
+
 	logger.Info("Starting load test",
 		zap.String("server", *serverAddr),
+		zap.String("protocol", *protocol),
 		zap.Int("vusers", *vusers),
 		zap.Int("rpm", *rpm),
 		zap.Duration("duration", *duration),
+		zap.Duration("rampup", *rampup),
+		zap.String("mix", *mixSpec),
+		zap.Duration("warmup", *warmup),
+		zap.Int("connections", *connections),
 	)
 
 	// Calculate request interval per user
@@ -53,43 +91,146 @@ func main() {
 		zap.Duration("interval", requestInterval),
 	)
 
+	startTime := time.Now()
 	ctx, cancel := context.WithTimeout(context.Background(), *duration)
 	defer cancel()
 
 	var wg sync.WaitGroup
 
+	// Over HTTP the virtual users share one client and its connection
+	// pool; over gRPC each gets a connection from the pool.
+	var connect func(ctx context.Context, userID int) (productsClient, error)
+	if *protocol == protocolHTTP {
+		client := newHTTPClient(*serverAddr, *vusers)
+		defer client.close()
+		connect = func(context.Context, int) (productsClient, error) { return client, nil }
+	} else {
+		pool := newConnPool(dialConfig{
+			addr:             *serverAddr,
+			keepaliveTime:    *keepaliveTime,
+			keepaliveTimeout: *keepaliveTimeout,
+			retries:          *dialRetries,
+		}, *connections, *vusers, logger)
+		defer pool.close()
+		connect = func(ctx context.Context, userID int) (productsClient, error) {
+			conn, err := pool.get(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			return newGRPCClient(conn), nil
+		}
+	}
+
+	warmCounts := warmupCounts(*warmup, ctx.Done())
+
 	// Start metrics reporter
 	go reportMetrics(ctx, logger, *duration)
 
-	// Start virtual users
-	for i := 0; i < *vusers; i++ {
+	// Abort early when nothing is getting through
+	var aborted atomic.Bool
+	if *failFastThreshold > 0 {
+		go func() {
+			if watchSuccessRate(ctx, *failFastThreshold, *failFastWindow, *serverAddr, logger) {
+				aborted.Store(true)
+				cancel()
+			}
+		}()
+	}
+
+	// Start virtual users, staggered over the ramp-up
+	for i, offset := range startOffsets(*vusers, *rampup) {
 		wg.Add(1)
-		go func(userID int) {
+		go func(userID int, offset time.Duration) {
 			defer wg.Done()
-			runVirtualUser(ctx, *serverAddr, userID, requestInterval, logger)
-		}(i)
+
+			timer := time.NewTimer(offset)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			runVirtualUser(ctx, connect, userID, requestInterval, mix, logger)
+		}(i, offset)
 	}
 
 	// Wait for all virtual users to complete
 	wg.Wait()
 
-	logger.Info("Load test completed",
+	fields := []zap.Field{
 		zap.Int64("total_requests", atomic.LoadInt64(&totalRequests)),
 		zap.Int64("success_requests", atomic.LoadInt64(&successRequests)),
 		zap.Int64("failed_requests", atomic.LoadInt64(&failedRequests)),
-	)
+		zap.Bool("aborted", aborted.Load()),
+	}
+	fields = append(fields, summarizeLatencies(latencies.snapshot()).fields()...)
+	logger.Info("Load test completed", fields...)
+
+	failed := aborted.Load()
+	if *maxErrorRate >= 0 {
+		verdict := evaluateErrorRate(<-warmCounts, loadRequestCounts(), *maxErrorRate)
+		fields := []zap.Field{
+			zap.Float64("error_rate", verdict.rate),
+			zap.Float64("max_error_rate", *maxErrorRate),
+			zap.Int64("steady_state_requests", verdict.requests),
+			zap.Duration("warmup", *warmup),
+		}
+		if verdict.pass {
+			logger.Info("PASS: "+verdict.reason, fields...)
+		} else {
+			logger.Error("FAIL: "+verdict.reason, fields...)
+			failed = true
+		}
+	}
+	if *out != "" {
+		results := collectResults(runConfig{
+			Addr:     *serverAddr,
+			Protocol: *protocol,
+			VUsers:   *vusers,
+			RPM:      *rpm,
+			Duration: duration.String(),
+			Rampup:   rampup.String(),
+			Mix:      *mixSpec,
+		}, time.Since(startTime), aborted.Load())
+		if err := writeResults(*out, results); err != nil {
+			logger.Error("Failed to write results", zap.String("path", *out), zap.Error(err))
+			failed = true
+		} else {
+			logger.Info("Wrote results", zap.String("path", *out))
+		}
+	}
+
+	if failed {
+		logger.Sync()
+		os.Exit(1)
+	}
+}
+
+// startOffsets spreads the starts of vusers virtual users linearly over
+// rampup: the first starts immediately, the last at the end of the ramp and
+// the rest at even intervals in between.
+func startOffsets(vusers int, rampup time.Duration) []time.Duration {
+	offsets := make([]time.Duration, vusers)
+	if rampup <= 0 || vusers < 2 {
+		return offsets
+	}
+	for i := range offsets {
+		offsets[i] = rampup * time.Duration(i) / time.Duration(vusers-1)
+	}
+	return offsets
 }
 
-func runVirtualUser(ctx context.Context, serverAddr string, userID int, interval time.Duration, logger *zap.Logger) {
-	// Create gRPC connection
-	conn, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func runVirtualUser(ctx context.Context, connect func(context.Context, int) (productsClient, error), userID int, interval time.Duration, mix operationMix, logger *zap.Logger) {
+	// Get or dial this user's connection
+	client, err := connect(ctx, userID)
 	if err != nil {
-		logger.Error("Failed to connect", zap.Int("user", userID), zap.Error(err))
+		if ctx.Err() == nil {
+			lastError.Store(err.Error())
+			logger.Error("Failed to connect, virtual user stopped", zap.Int("user", userID), zap.Error(err))
+		}
 		return
 	}
-	defer conn.Close()
-
-	client := proto.NewProductsServiceClient(conn)
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -99,20 +240,23 @@ func runVirtualUser(ctx context.Context, serverAddr string, userID int, interval
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			makeRequest(ctx, client, userID, logger)
+			makeRequest(ctx, client, userID, mix, logger)
 		}
 	}
 }
 
-func makeRequest(ctx context.Context, client proto.ProductsServiceClient, userID int, logger *zap.Logger) {
+func makeRequest(ctx context.Context, client productsClient, userID int, mix operationMix, logger *zap.Logger) {
 	atomic.AddInt64(&totalRequests, 1)
 
 	// Randomly choose between different operations
-	operation := rand.Intn(100)
 	var err error
+	op := mix.pick(rand.Intn(100))
+
+	start := time.Now()
+	defer func() { latencies.record(op, time.Since(start), err != nil) }()
 
-	switch {
-	case operation < 70: // 70% list products
+	switch op {
+	case opList:
 		req := &proto.ListProductsRequest{
 			Page:     int32(rand.Intn(5) + 1),
 			PageSize: int32(rand.Intn(20) + 10),
@@ -125,16 +269,16 @@ func makeRequest(ctx context.Context, client proto.ProductsServiceClient, userID
 			searchTerms := []string{"laptop", "chair", "coffee", "shoes", "mouse"}
 			req.SearchQuery = searchTerms[rand.Intn(len(searchTerms))]
 		}
-		_, err = client.ListProducts(ctx, req)
+		err = client.ListProducts(ctx, req)
 
-	case operation < 90: // 20% get product
+	case opGet:
 		productIDs := []string{"1", "2", "3", "4", "5"}
 		req := &proto.GetProductRequest{
 			Id: productIDs[rand.Intn(len(productIDs))],
 		}
-		_, err = client.GetProduct(ctx, req)
+		err = client.GetProduct(ctx, req)
 
-	default: // 10% create product
+	case opCreate:
 		req := &proto.CreateProductRequest{
 			Name:        fmt.Sprintf("Test Product %d", time.Now().UnixNano()),
 			Description: "Load test product",
@@ -142,11 +286,12 @@ func makeRequest(ctx context.Context, client proto.ProductsServiceClient, userID
 			Category:    "Test",
 			Stock:       int32(rand.Intn(100)),
 		}
-		_, err = client.CreateProduct(ctx, req)
+		err = client.CreateProduct(ctx, req)
 	}
 
 	if err != nil {
 		atomic.AddInt64(&failedRequests, 1)
+		lastError.Store(err.Error())
 		logger.Debug("Request failed", zap.Int("user", userID), zap.Error(err))
 	} else {
 		atomic.AddInt64(&successRequests, 1)
@@ -173,17 +318,85 @@ func reportMetrics(ctx context.Context, logger *zap.Logger, duration time.Durati
 			requestsSinceLastReport := total - lastTotal
 			rps := float64(requestsSinceLastReport) / 10.0
 
-			logger.Info("Metrics",
+			fields := []zap.Field{
 				zap.Duration("elapsed", elapsed),
 				zap.Int64("total", total),
 				zap.Int64("success", success),
 				zap.Int64("failed", failed),
 				zap.Float64("rps", rps),
 				zap.Float64("success_rate", float64(success)/float64(total)*100),
-			)
+			}
+			// Latencies cover the requests since the previous report
+			fields = append(fields, summarizeLatencies(latencies.takeWindow()).fields()...)
+			logger.Info("Metrics", fields...)
 
 			lastTotal = total
 		}
 	}
 }
 
+// successSample is a snapshot of the request counters.
+type successSample struct {
+	at      time.Time
+	total   int64
+	success int64
+}
+
+// watchSuccessRate samples the request counters every second and reports
+// true, after logging a diagnosis, once the success rate over the trailing
+// window has fallen below threshold percent. It returns false when ctx ends
+// first. No verdict is reached before a full window has elapsed or while no
+// requests were made in it.
+func watchSuccessRate(ctx context.Context, threshold float64, window time.Duration, serverAddr string, logger *zap.Logger) bool {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	samples := []successSample{{
+		at:      time.Now(),
+		total:   atomic.LoadInt64(&totalRequests),
+		success: atomic.LoadInt64(&successRequests),
+	}}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case now := <-ticker.C:
+			samples = append(samples, successSample{
+				at:      now,
+				total:   atomic.LoadInt64(&totalRequests),
+				success: atomic.LoadInt64(&successRequests),
+			})
+			// Keep the newest sample at or before the window start as the
+			// baseline.
+			for len(samples) > 1 && now.Sub(samples[1].at) >= window {
+				samples = samples[1:]
+			}
+
+			oldest, latest := samples[0], samples[len(samples)-1]
+			if now.Sub(oldest.at) < window {
+				continue
+			}
+			requests := latest.total - oldest.total
+			if requests == 0 {
+				continue
+			}
+
+			rate := float64(latest.success-oldest.success) / float64(requests) * 100
+			if rate >= threshold {
+				continue
+			}
+
+			lastErr, _ := lastError.Load().(string)
+			logger.Error("Aborting load test: success rate below fail-fast threshold",
+				zap.Float64("success_rate", rate),
+				zap.Float64("threshold", threshold),
+				zap.Duration("window", window),
+				zap.Int64("requests_in_window", requests),
+				zap.String("last_error", lastErr),
+				zap.String("hint", fmt.Sprintf("check that the products service is running and reachable at %s", serverAddr)),
+			)
+			return true
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStartOffsets(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		vusers int
+		rampup time.Duration
+		want   []time.Duration
+	}{
+		{name: "no ramp", vusers: 3, want: []time.Duration{0, 0, 0}},
+		{name: "one user", vusers: 1, rampup: time.Minute, want: []time.Duration{0}},
+		{
+			name:   "even spread",
+			vusers: 5,
+			rampup: 40 * time.Second,
+			want:   []time.Duration{0, 10 * time.Second, 20 * time.Second, 30 * time.Second, 40 * time.Second},
+		},
+		{
+			name:   "uneven spread",
+			vusers: 4,
+			rampup: 10 * time.Second,
+			want:   []time.Duration{0, 3333333333, 6666666666, 10 * time.Second},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := startOffsets(tc.vusers, tc.rampup); !slices.Equal(got, tc.want) {
+				t.Errorf("startOffsets(%d, %v) = %v, want %v", tc.vusers, tc.rampup, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// operation is a kind of request the virtual users make.
+type operation string
+
+const (
+	opList   operation = "list"
+	opGet    operation = "get"
+	opCreate operation = "create"
+)
+
+// defaultMix is the 70/20/10 list/get/create split of a browsing-heavy
+// storefront.
+const defaultMix = "list=70,get=20,create=10"
+
+// operationWeight is one entry of an operation mix, in percent.
+type operationWeight struct {
+	op     operation
+	weight int
+}
+
+// operationMix decides which operation each request makes.
+type operationMix []operationWeight
+
+// parseMix parses a spec of the form "list=70,get=20,create=10". Every
+// operation may appear at most once, omitted operations are never made and
+// the weights must add up to 100.
+func parseMix(spec string) (operationMix, error) {
+	var (
+		mix   operationMix
+		total int
+		seen  = make(map[operation]bool)
+	)
+	for _, entry := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("mix entry %q must have the form operation=percent", entry)
+		}
+
+		op := operation(strings.TrimSpace(name))
+		switch op {
+		case opList, opGet, opCreate:
+		default:
+			return nil, fmt.Errorf("unknown operation %q in mix; use %s, %s or %s", op, opList, opGet, opCreate)
+		}
+		if seen[op] {
+			return nil, fmt.Errorf("operation %q appears more than once in mix", op)
+		}
+		seen[op] = true
+
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight %q for %s must be a non-negative integer", value, op)
+		}
+		total += weight
+		if weight > 0 {
+			mix = append(mix, operationWeight{op: op, weight: weight})
+		}
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("mix weights add up to %d, not 100", total)
+	}
+	return mix, nil
+}
+
+// pick returns the operation for roll, a number in [0, 100).
+func (m operationMix) pick(roll int) operation {
+	for _, entry := range m {
+		if roll < entry.weight {
+			return entry.op
+		}
+		roll -= entry.weight
+	}
+	return m[len(m)-1].op
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMix(t *testing.T) {
+	for _, tc := range []struct {
+		spec    string
+		want    operationMix
+		wantErr string
+	}{
+		{spec: defaultMix, want: operationMix{{opList, 70}, {opGet, 20}, {opCreate, 10}}},
+		{spec: " get = 50 , create=50 ", want: operationMix{{opGet, 50}, {opCreate, 50}}},
+		{spec: "list=100,get=0", want: operationMix{{opList, 100}}},
+		{spec: "list=70,get=20", wantErr: "add up to 90"},
+		{spec: "list=70,get=40", wantErr: "add up to 110"},
+		{spec: "list=70,delete=30", wantErr: "unknown operation"},
+		{spec: "list=50,list=50", wantErr: "more than once"},
+		{spec: "list", wantErr: "operation=percent"},
+		{spec: "list=-10,get=110", wantErr: "non-negative"},
+		{spec: "list=half,get=50", wantErr: "non-negative"},
+	} {
+		got, err := parseMix(tc.spec)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("parseMix(%q) = %v, want an error containing %q", tc.spec, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMix(%q): %v", tc.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseMix(%q) = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestOperationMixPick(t *testing.T) {
+	mix, err := parseMix(defaultMix)
+	if err != nil {
+		t.Fatalf("parseMix: %v", err)
+	}
+	counts := make(map[operation]int)
+	for roll := 0; roll < 100; roll++ {
+		counts[mix.pick(roll)]++
+	}
+	if want := map[operation]int{opList: 70, opGet: 20, opCreate: 10}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("picks over every roll = %v, want %v", counts, want)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runConfig is the configuration a run was made with.
+type runConfig struct {
+	Addr     string `json:"addr"`
+	Protocol string `json:"protocol"`
+	VUsers   int    `json:"vusers"`
+	RPM      int    `json:"rpm"`
+	Duration string `json:"duration"`
+	Rampup   string `json:"rampup"`
+	Mix      string `json:"mix"`
+}
+
+// latencyResult is a latencySummary in milliseconds.
+type latencyResult struct {
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// requestResult counts the requests of a run, or of one of its operations.
+type requestResult struct {
+	Total   int64         `json:"total"`
+	Success int64         `json:"success"`
+	Failed  int64         `json:"failed"`
+	RPS     float64       `json:"rps"`
+	Latency latencyResult `json:"latency"`
+}
+
+// runResults is what -out writes.
+type runResults struct {
+	Config     runConfig                   `json:"config"`
+	Elapsed    string                      `json:"elapsed"`
+	Aborted    bool                        `json:"aborted"`
+	Requests   requestResult               `json:"requests"`
+	Operations map[operation]requestResult `json:"operations"`
+}
+
+func newLatencyResult(s latencySummary) latencyResult {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return latencyResult{
+		MinMs:  ms(s.min),
+		MeanMs: ms(s.mean),
+		P50Ms:  ms(s.p50),
+		P90Ms:  ms(s.p90),
+		P99Ms:  ms(s.p99),
+		MaxMs:  ms(s.max),
+	}
+}
+
+// collectResults gathers the results of a run that lasted elapsed from the
+// recorded latencies.
+func collectResults(config runConfig, elapsed time.Duration, aborted bool) runResults {
+	rps := func(requests int64) float64 {
+		if elapsed <= 0 {
+			return 0
+		}
+		return float64(requests) / elapsed.Seconds()
+	}
+
+	results := runResults{
+		Config:     config,
+		Elapsed:    elapsed.Round(time.Millisecond).String(),
+		Aborted:    aborted,
+		Operations: make(map[operation]requestResult),
+	}
+
+	var all []time.Duration
+	for op, stats := range latencies.byOperation() {
+		total := stats.success + stats.failed
+		results.Operations[op] = requestResult{
+			Total:   total,
+			Success: stats.success,
+			Failed:  stats.failed,
+			RPS:     rps(total),
+			Latency: newLatencyResult(summarizeLatencies(stats.samples)),
+		}
+		results.Requests.Total += total
+		results.Requests.Success += stats.success
+		results.Requests.Failed += stats.failed
+		all = append(all, stats.samples...)
+	}
+	results.Requests.RPS = rps(results.Requests.Total)
+	results.Requests.Latency = newLatencyResult(summarizeLatencies(all))
+	return results
+}
+
+// writeResults writes results to path as CSV when it ends in .csv and as
+// JSON otherwise.
+func writeResults(path string, results runResults) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = writeResultsCSV(f, results)
+	} else {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(results)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
+
+// resultsCSVHeader are the CSV columns: one row covers all requests
+// ("all") and one each operation, with the run configuration repeated on
+// every row so each stands alone.
+var resultsCSVHeader = []string{
+	"scope", "total", "success", "failed", "rps",
+	"latency_min_ms", "latency_mean_ms", "latency_p50_ms", "latency_p90_ms", "latency_p99_ms", "latency_max_ms",
+	"addr", "protocol", "vusers", "rpm", "duration", "rampup", "mix", "elapsed", "aborted",
+}
+
+func writeResultsCSV(f *os.File, results runResults) error {
+	w := csv.NewWriter(f)
+	if err := w.Write(resultsCSVHeader); err != nil {
+		return err
+	}
+
+	formatFloat := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	row := func(scope string, r requestResult) []string {
+		c := results.Config
+		return []string{
+			scope,
+			strconv.FormatInt(r.Total, 10),
+			strconv.FormatInt(r.Success, 10),
+			strconv.FormatInt(r.Failed, 10),
+			formatFloat(r.RPS),
+			formatFloat(r.Latency.MinMs),
+			formatFloat(r.Latency.MeanMs),
+			formatFloat(r.Latency.P50Ms),
+			formatFloat(r.Latency.P90Ms),
+			formatFloat(r.Latency.P99Ms),
+			formatFloat(r.Latency.MaxMs),
+			c.Addr,
+			c.Protocol,
+			strconv.Itoa(c.VUsers),
+			strconv.Itoa(c.RPM),
+			c.Duration,
+			c.Rampup,
+			c.Mix,
+			results.Elapsed,
+			strconv.FormatBool(results.Aborted),
+		}
+	}
+
+	if err := w.Write(row("all", results.Requests)); err != nil {
+		return err
+	}
+	ops := make([]operation, 0, len(results.Operations))
+	for op := range results.Operations {
+		ops = append(ops, op)
+	}
+	slices.Sort(ops)
+	for _, op := range ops {
+		if err := w.Write(row(string(op), results.Operations[op])); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// recordTestRun replaces the recorded latencies with those of a short run.
+func recordTestRun(t *testing.T) {
+	t.Helper()
+
+	latencies = latencyRecorder{}
+	t.Cleanup(func() { latencies = latencyRecorder{} })
+	for _, ms := range []int{10, 20, 30, 40} {
+		latencies.record(opList, time.Duration(ms)*time.Millisecond, false)
+	}
+	latencies.record(opGet, 5*time.Millisecond, false)
+	latencies.record(opGet, 15*time.Millisecond, true)
+}
+
+var testRunConfig = runConfig{
+	Addr:     "localhost:50051",
+	Protocol: protocolGRPC,
+	VUsers:   4,
+	RPM:      120,
+	Duration: "1m0s",
+	Rampup:   "10s",
+	Mix:      "list=70,get=30",
+}
+
+func TestCollectResults(t *testing.T) {
+	recordTestRun(t)
+
+	results := collectResults(testRunConfig, 2*time.Second, false)
+	want := runResults{
+		Config:  testRunConfig,
+		Elapsed: "2s",
+		Requests: requestResult{
+			Total: 6, Success: 5, Failed: 1, RPS: 3,
+			Latency: latencyResult{MinMs: 5, MeanMs: 20, P50Ms: 15, P90Ms: 40, P99Ms: 40, MaxMs: 40},
+		},
+		Operations: map[operation]requestResult{
+			opList: {
+				Total: 4, Success: 4, RPS: 2,
+				Latency: latencyResult{MinMs: 10, MeanMs: 25, P50Ms: 20, P90Ms: 40, P99Ms: 40, MaxMs: 40},
+			},
+			opGet: {
+				Total: 2, Success: 1, Failed: 1, RPS: 1,
+				Latency: latencyResult{MinMs: 5, MeanMs: 10, P50Ms: 5, P90Ms: 15, P99Ms: 15, MaxMs: 15},
+			},
+		},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("collectResults =\n%+v\nwant\n%+v", results, want)
+	}
+}
+
+func TestWriteResultsJSONRoundTrip(t *testing.T) {
+	recordTestRun(t)
+	results := collectResults(testRunConfig, 2*time.Second, true)
+
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := writeResults(path, results); err != nil {
+		t.Fatalf("writeResults: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var got runResults
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(got, results) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, results)
+	}
+}
+
+func TestWriteResultsCSV(t *testing.T) {
+	recordTestRun(t)
+	results := collectResults(testRunConfig, 2*time.Second, false)
+
+	path := filepath.Join(t.TempDir(), "results.CSV")
+	if err := writeResults(path, results); err != nil {
+		t.Fatalf("writeResults: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+
+	config := []string{"localhost:50051", "grpc", "4", "120", "1m0s", "10s", "list=70,get=30", "2s", "false"}
+	want := [][]string{
+		resultsCSVHeader,
+		append([]string{"all", "6", "5", "1", "3", "5", "20", "15", "40", "40", "40"}, config...),
+		append([]string{"get", "2", "1", "1", "1", "5", "10", "5", "15", "15", "15"}, config...),
+		append([]string{"list", "4", "4", "0", "2", "10", "25", "20", "40", "40", "40"}, config...),
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("CSV has %d rows, want %d:\n%q", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if !slices.Equal(rows[i], want[i]) {
+			t.Errorf("CSV row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
@@ -1,28 +1,44 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/chirik/products/internal/config"
+	"github.com/chirik/products/internal/middleware"
 	"github.com/chirik/products/internal/observability"
 	"github.com/chirik/products/internal/repository"
 	"github.com/chirik/products/internal/server"
 	"github.com/chirik/products/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
 	// Initialize logger
-	logger, err := observability.NewLogger(cfg.LogFilePath)
+	logger, err := observability.NewLogger(cfg.LogFilePath, cfg.LogLevel, observability.LogRotation{
+		MaxSizeMB:  cfg.LogMaxSizeMB,
+		MaxBackups: cfg.LogMaxBackups,
+		MaxAgeDays: cfg.LogMaxAgeDays,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create logger: %v", err)
 	}
@@ -31,6 +47,9 @@ func main() {
 	logger.Info("Starting products service",
 		zap.String("port", cfg.GRPCPort),
 		zap.String("redis_addr", cfg.RedisAddr),
+		zap.Bool("auth_enabled", len(cfg.APIKeys) > 0),
+		zap.Bool("tls_enabled", cfg.TLSCertFile != ""),
+		zap.Bool("mtls_enabled", cfg.TLSClientCAFile != ""),
 	)
 
 	// Initialize observability
@@ -41,22 +60,89 @@ func main() {
 	defer shutdown()
 
 	// Initialize repository
-	repo, err := repository.NewRedisRepository(cfg.RedisAddr, logger)
+	repo, err := repository.NewRedisRepository(repositoryOptions(cfg), logger)
 	if err != nil {
 		logger.Fatal("Failed to create repository", zap.Error(err))
 	}
 	defer repo.Close()
+	observability.SetReadinessChecker(repo)
 
 	// Initialize gRPC server
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(observability.UnaryServerInterceptor(logger)),
-	)
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit, cfg.RateLimitMethods, time.Now)
+	concurrencyLimiter := middleware.NewClientConcurrencyLimiter(cfg.ClientConcurrencyLimit)
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			observability.UnaryServerInterceptor(logger, observability.RequestLogging{
+				Full:            cfg.LogFullRequests,
+				MaxStringLength: cfg.LogRequestMaxStringLength,
+			}),
+			observability.ServerTimingInterceptor(cfg.ServerTimingEnabled, logger),
+			middleware.APIKeyAuthInterceptor(cfg.APIKeys),
+			rateLimiter.UnaryServerInterceptor(),
+			concurrencyLimiter.UnaryServerInterceptor(),
+			middleware.APIVersionInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			observability.StreamServerInterceptor(logger),
+			middleware.APIKeyAuthStreamInterceptor(cfg.APIKeys),
+			concurrencyLimiter.StreamServerInterceptor(),
+			middleware.APIVersionStreamInterceptor(),
+		),
+	}
+
+	creds, err := transportCredentials(cfg)
+	if err != nil {
+		logger.Fatal("Failed to configure TLS", zap.Error(err))
+	}
+	if creds != nil {
+		serverOpts = append(serverOpts, creds)
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
 
 	// Register service
-	productsServer := server.NewProductsServer(repo, logger)
+	productsServer := server.NewProductsServer(repo, logger, server.Options{
+		LowStockThreshold:  int32(cfg.StockStatusLowThreshold),
+		DefaultPageSize:    int32(cfg.DefaultPageSize),
+		MaxPageSize:        int32(cfg.MaxPageSize),
+		MaxPrice:           cfg.MaxProductPrice,
+		MaxStock:           int32(cfg.MaxProductStock),
+		MaxBatchSize:       cfg.MaxBatchSize,
+		MaxTags:            cfg.MaxTagsPerProduct,
+		MaxTagLength:       cfg.MaxTagLength,
+		MaxAttributes:      cfg.MaxAttributesPerProduct,
+		MaxAttributeLength: cfg.MaxAttributeLength,
+	})
 	proto.RegisterProductsServiceServer(grpcServer, productsServer)
 	reflection.Register(grpcServer)
 
+	// Register health service; the repository has already pinged Redis
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	setServingStatus(healthServer, healthpb.HealthCheckResponse_SERVING)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Monitor Redis liveness and downgrade health when it is unreachable
+	go observability.MonitorPing(ctx, repo, cfg.RedisPingInterval, logger, func(err error) {
+		if err != nil {
+			setServingStatus(healthServer, healthpb.HealthCheckResponse_NOT_SERVING)
+			return
+		}
+		setServingStatus(healthServer, healthpb.HealthCheckResponse_SERVING)
+	})
+
+	// Track products that need reordering
+	go observability.MonitorLowStock(ctx, repo, int32(cfg.LowStockThreshold), cfg.LowStockRefreshInterval, logger)
+
+	// Sample product ages to track catalog freshness
+	go observability.MonitorProductAge(ctx, repo, cfg.ProductAgeSampleSize, cfg.ProductAgeRefreshInterval, logger)
+
+	// Keep the eagerly loaded category list current
+	if cfg.CategoriesEager {
+		go repo.RefreshCategoriesEvery(ctx, cfg.CategoriesRefreshInterval)
+	}
+
 	// Start server
 	lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
 	if err != nil {
@@ -69,6 +155,58 @@ func main() {
 		}
 	}()
 
+	// Serve the NDJSON catalog export
+	var exportServer *http.Server
+	if cfg.ExportHTTPPort != "" {
+		exportLis, err := net.Listen("tcp", ":"+cfg.ExportHTTPPort)
+		if err != nil {
+			logger.Fatal("Failed to listen for export HTTP", zap.Error(err))
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/products", middleware.APIKeyAuthHandler(cfg.APIKeys, server.NDJSONExportHandler(repo, logger)))
+		exportServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		go func() {
+			if err := exportServer.Serve(exportLis); err != nil && err != http.ErrServerClosed {
+				logger.Error("Export HTTP server failed", zap.Error(err))
+			}
+		}()
+		logger.Info("Serving NDJSON export", zap.String("address", exportLis.Addr().String()))
+	}
+
+	// Serve the REST gateway
+	var gatewayServer *http.Server
+	if cfg.GatewayPort != "" {
+		dialOpts, err := gatewayDialOptions(cfg)
+		if err != nil {
+			logger.Fatal("Failed to configure REST gateway", zap.Error(err))
+		}
+		// The gateway calls the gRPC server over loopback so REST requests
+		// pass through the same interceptors as gRPC ones.
+		endpoint := net.JoinHostPort("localhost", cfg.GRPCPort)
+		gatewayHandler, err := server.GatewayHandler(ctx, endpoint, dialOpts)
+		if err != nil {
+			logger.Fatal("Failed to create REST gateway", zap.Error(err))
+		}
+		gatewayLis, err := net.Listen("tcp", ":"+cfg.GatewayPort)
+		if err != nil {
+			logger.Fatal("Failed to listen for REST gateway", zap.Error(err))
+		}
+
+		gatewayServer = &http.Server{
+			Handler:           gatewayHandler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		go func() {
+			if err := gatewayServer.Serve(gatewayLis); err != nil && err != http.ErrServerClosed {
+				logger.Error("REST gateway failed", zap.Error(err))
+			}
+		}()
+		logger.Info("Serving REST gateway", zap.String("address", gatewayLis.Addr().String()))
+	}
+
 	logger.Info("Products service is running",
 		zap.String("address", lis.Addr().String()),
 	)
@@ -79,6 +217,107 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down products service...")
-	grpcServer.GracefulStop()
+	healthServer.Shutdown()
+	if exportServer != nil {
+		// Exports are long-running requests; they are cut off rather than
+		// drained.
+		exportServer.Close()
+	}
+	// The gateway and the gRPC server share one deadline, so shutdown as a
+	// whole takes at most ShutdownTimeout.
+	shutdownCtx, cancelShutdown := shutdownContext(cfg.ShutdownTimeout)
+	defer cancelShutdown()
+	if gatewayServer != nil {
+		stopHTTPServer(shutdownCtx, gatewayServer, logger)
+	}
+	stopServer(shutdownCtx, grpcServer, logger)
 	logger.Info("Products service stopped")
 }
+
+// shutdownContext returns the context bounding shutdown. A non-positive
+// timeout waits indefinitely.
+func shutdownContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// stopServer drains in-flight calls with GracefulStop, falling back to a
+// hard Stop if they haven't finished when ctx is done.
+func stopServer(ctx context.Context, grpcServer *grpc.Server, logger *zap.Logger) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		logger.Info("Graceful shutdown completed")
+	case <-ctx.Done():
+		logger.Warn("Graceful shutdown timed out; forcing stop")
+		grpcServer.Stop()
+		<-stopped
+	}
+}
+
+// stopHTTPServer drains in-flight requests like stopServer, closing the
+// remaining connections if they haven't finished when ctx is done.
+func stopHTTPServer(ctx context.Context, httpServer *http.Server, logger *zap.Logger) {
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Warn("HTTP shutdown timed out; closing connections")
+		httpServer.Close()
+	}
+}
+
+// repositoryOptions maps the service configuration onto the repository's
+// options.
+func repositoryOptions(cfg *config.Config) repository.Options {
+	return repository.Options{
+		RedisMode:       cfg.RedisMode,
+		RedisAddrs:      cfg.RedisAddrs,
+		RedisMasterName: cfg.RedisMasterName,
+		ClientName:      cfg.ServiceName(),
+		PoolSize:        cfg.RedisPoolSize,
+		DialTimeout:     cfg.RedisDialTimeout,
+		ReadTimeout:     cfg.RedisReadTimeout,
+		WriteTimeout:    cfg.RedisWriteTimeout,
+		MaxRetries:      cfg.RedisMaxRetries,
+		SearchAddr:      cfg.SearchAddr,
+
+		NotifyExpirations: cfg.RedisNotifyExpirations,
+		MGetBatchSize:     cfg.RedisMGetBatchSize,
+		MGetParallelism:   cfg.RedisMGetParallelism,
+
+		CategoriesCacheTTL:  cfg.CategoriesCacheTTL,
+		CategoriesEager:     cfg.CategoriesEager,
+		StatsCacheTTL:       cfg.CatalogStatsCacheTTL,
+		SuggestionsCacheTTL: cfg.SuggestionsCacheTTL,
+		ProductCacheSize:    cfg.ProductCacheSize,
+		ProductCacheTTL:     cfg.ProductCacheTTL,
+
+		MemoryIndexEnabled:     cfg.MemoryIndexEnabled,
+		MemoryIndexMaxProducts: cfg.MemoryIndexMaxProducts,
+
+		SeedEnabled:     cfg.SeedEnabled,
+		SeedTargetCount: cfg.SeedTargetCount,
+		SeedUpsertBase:  cfg.SeedUpsertBase,
+
+		SchemaRewrite:     cfg.ProductSchemaRewrite,
+		ReindexRate:       cfg.ReindexRate,
+		RecreateIndex:     cfg.SearchIndexRecreate,
+		DedupResults:      cfg.SearchDedupResults,
+		ListAllWithSearch: cfg.SearchListAll,
+		IndexConcurrency:  cfg.IndexConcurrency,
+		Enricher:          cfg.ProductEnricher,
+
+		IdempotencyKeyTTL: cfg.IdempotencyKeyTTL,
+		CreateDedupWindow: cfg.CreateDedupWindow,
+	}
+}
+
+func setServingStatus(healthServer *health.Server, servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	healthServer.SetServingStatus("", servingStatus)
+	healthServer.SetServingStatus(proto.ProductsService_ServiceDesc.ServiceName, servingStatus)
+}
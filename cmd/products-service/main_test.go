@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/chirik/products/internal/config"
+	"github.com/chirik/products/internal/repository"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestShutdownSharesOneDeadline(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	// A gateway request and a gRPC call that both outlive the timeout.
+	httpEntered := make(chan struct{})
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(httpEntered)
+		<-release
+	})}
+	httpLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go httpServer.Serve(httpLis)
+	go http.Get("http://" + httpLis.Addr().String())
+
+	grpcEntered := make(chan struct{})
+	grpcServer := grpc.NewServer(grpc.UnknownServiceHandler(func(srv any, stream grpc.ServerStream) error {
+		close(grpcEntered)
+		select {
+		case <-release:
+		case <-stream.Context().Done():
+		}
+		return stream.Context().Err()
+	}))
+	grpcLis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go grpcServer.Serve(grpcLis)
+	conn, err := grpc.NewClient(grpcLis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	callErr := make(chan error, 1)
+	go func() {
+		callErr <- conn.Invoke(context.Background(), "/test.Slow/Call", &emptypb.Empty{}, &emptypb.Empty{})
+	}()
+
+	for _, entered := range []chan struct{}{httpEntered, grpcEntered} {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("slow handler was not called")
+		}
+	}
+
+	const timeout = 300 * time.Millisecond
+	start := time.Now()
+	ctx, cancel := shutdownContext(timeout)
+	defer cancel()
+	stopHTTPServer(ctx, httpServer, zap.NewNop())
+	stopServer(ctx, grpcServer, zap.NewNop())
+	elapsed := time.Since(start)
+
+	// The gateway uses up the deadline, so the gRPC server is stopped right
+	// away rather than given a timeout of its own.
+	if elapsed < timeout || elapsed >= 2*timeout {
+		t.Errorf("shutdown took %v, want about %v", elapsed, timeout)
+	}
+	select {
+	case err := <-callErr:
+		if status.Code(err) != codes.Unavailable && status.Code(err) != codes.Canceled {
+			t.Errorf("in-flight call ended with %v, want it cut off", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("in-flight call still running after a forced stop")
+	}
+}
+
+func TestStopServerGraceful(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go grpcServer.Serve(lis)
+
+	ctx, cancel := shutdownContext(time.Minute)
+	defer cancel()
+	start := time.Now()
+	stopServer(ctx, grpcServer, zap.NewNop())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("idle server took %v to stop", elapsed)
+	}
+}
+
+func TestRepositoryOptionsFromConfig(t *testing.T) {
+	cfg := &config.Config{
+		RedisMode:         repository.RedisModeSentinel,
+		RedisAddrs:        []string{"sentinel-1:26379", "sentinel-2:26379"},
+		RedisMasterName:   "mymaster",
+		RedisPoolSize:     42,
+		RedisDialTimeout:  2 * time.Second,
+		RedisReadTimeout:  750 * time.Millisecond,
+		RedisWriteTimeout: 900 * time.Millisecond,
+		RedisMaxRetries:   7,
+	}
+	opts := repositoryOptions(cfg)
+
+	if opts.RedisMode != cfg.RedisMode || !slices.Equal(opts.RedisAddrs, cfg.RedisAddrs) || opts.RedisMasterName != "mymaster" {
+		t.Errorf("connection options = %s %v %q, want %s %v mymaster", opts.RedisMode, opts.RedisAddrs, opts.RedisMasterName, cfg.RedisMode, cfg.RedisAddrs)
+	}
+	if opts.PoolSize != 42 || opts.DialTimeout != 2*time.Second || opts.ReadTimeout != 750*time.Millisecond ||
+		opts.WriteTimeout != 900*time.Millisecond || opts.MaxRetries != 7 {
+		t.Errorf("pool options = pool %d, dial %v, read %v, write %v, retries %d; want 42, 2s, 750ms, 900ms, 7",
+			opts.PoolSize, opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout, opts.MaxRetries)
+	}
+}
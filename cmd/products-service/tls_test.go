@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chirik/products/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("ParseCertificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate for localhost signed by the CA, as PEM
+// certificate and key, usable for the given purposes.
+func (ca *testCA) issue(t *testing.T, serial int64, usages ...x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  usages,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to name in dir and returns its path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+// serveTLS starts a health server with the TLS settings of cfg and returns
+// its address.
+func serveTLS(t *testing.T, cfg *config.Config) string {
+	t.Helper()
+
+	creds, err := transportCredentials(cfg)
+	if err != nil {
+		t.Fatalf("transportCredentials: %v", err)
+	}
+	server := grpc.NewServer(creds)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+// checkHealth calls the health service at addr over a connection dialled
+// with opts.
+func checkHealth(t *testing.T, addr string, opts ...grpc.DialOption) error {
+	t.Helper()
+
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, 2, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)
+	clientCert, clientKey := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+
+	cfg := &config.Config{
+		TLSCertFile:     writeFile(t, dir, "server.crt", serverCert),
+		TLSKeyFile:      writeFile(t, dir, "server.key", serverKey),
+		TLSClientCAFile: writeFile(t, dir, "ca.crt", ca.pem),
+	}
+	addr := serveTLS(t, cfg)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	keyPair, err := tls.X509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("X509KeyPair: %v", err)
+	}
+
+	withCert := credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{keyPair}, ServerName: "localhost"})
+	if err := checkHealth(t, addr, grpc.WithTransportCredentials(withCert)); err != nil {
+		t.Errorf("call with a client certificate: %v", err)
+	}
+
+	withoutCert := credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "localhost"})
+	if err := checkHealth(t, addr, grpc.WithTransportCredentials(withoutCert)); err == nil {
+		t.Error("call without a client certificate succeeded")
+	}
+
+	// The REST gateway dials in with the server's own certificate.
+	gatewayOpts, err := gatewayDialOptions(cfg)
+	if err != nil {
+		t.Fatalf("gatewayDialOptions: %v", err)
+	}
+	if err := checkHealth(t, addr, gatewayOpts...); err != nil {
+		t.Errorf("call from the gateway: %v", err)
+	}
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+
+	cfg := &config.Config{
+		TLSCertFile: writeFile(t, dir, "server.crt", serverCert),
+		TLSKeyFile:  writeFile(t, dir, "server.key", serverKey),
+	}
+	addr := serveTLS(t, cfg)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	creds := credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "localhost"})
+	if err := checkHealth(t, addr, grpc.WithTransportCredentials(creds)); err != nil {
+		t.Errorf("call over TLS: %v", err)
+	}
+
+	// A gateway pinned to another certificate refuses the server.
+	otherCert, otherKey := ca.issue(t, 3, x509.ExtKeyUsageServerAuth)
+	gatewayOpts, err := gatewayDialOptions(&config.Config{
+		TLSCertFile: writeFile(t, dir, "other.crt", otherCert),
+		TLSKeyFile:  writeFile(t, dir, "other.key", otherKey),
+	})
+	if err != nil {
+		t.Fatalf("gatewayDialOptions: %v", err)
+	}
+	if err := checkHealth(t, addr, gatewayOpts...); err == nil {
+		t.Error("gateway pinned to another certificate reached the server")
+	}
+}
+
+func TestTransportCredentialsPlaintext(t *testing.T) {
+	creds, err := transportCredentials(&config.Config{})
+	if err != nil || creds != nil {
+		t.Errorf("transportCredentials without certificates = %v, %v; want plaintext", creds, err)
+	}
+	if _, err := transportCredentials(&config.Config{TLSCertFile: "server.crt"}); err == nil {
+		t.Error("transportCredentials with a certificate but no key succeeded")
+	}
+}
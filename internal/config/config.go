@@ -1,34 +1,464 @@
 package config
 
 import (
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const serviceName = "products-service"
+
+// Redis connection modes.
+const (
+	RedisModeSingle   = "single"
+	RedisModeCluster  = "cluster"
+	RedisModeSentinel = "sentinel"
+)
+
+// DefaultRequestDurationBuckets span 1ms to 5s, the range API latency SLOs
+// are usually set in.
+var DefaultRequestDurationBuckets = []float64{
+	0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5,
+}
+
 type Config struct {
 	GRPCPort       string
 	RedisAddr      string
 	JaegerEndpoint string
+	TraceExporter  string
 	MetricsPort    string
-	Environment    string
-	OTLPEndpoint   string
-	LogFilePath    string
+	// ExportHTTPPort serves the NDJSON catalog export over HTTP when set.
+	ExportHTTPPort string
+	// GatewayPort serves the REST gateway over HTTP when set.
+	GatewayPort  string
+	Environment  string
+	OTLPEndpoint string
+	LogFilePath  string
+	// LogLevel is debug, info, warn or error.
+	LogLevel string
+	// LogMaxSizeMB is the size at which the log file is rotated. Rotated
+	// files are kept up to LogMaxBackups files and LogMaxAgeDays days; zero
+	// keeps them indefinitely.
+	LogMaxSizeMB  int
+	LogMaxBackups int
+	LogMaxAgeDays int
+
+	// LogFullRequests logs every request in full. Otherwise request strings
+	// are truncated to LogRequestMaxStringLength characters.
+	LogFullRequests           bool
+	LogRequestMaxStringLength int
+
+	// MetricsFallbackPorts are tried in order when MetricsPort is taken.
+	// MetricsBindFatal fails startup when no metrics port can be bound
+	// instead of running without metrics.
+	MetricsFallbackPorts []string
+	MetricsBindFatal     bool
+
+	// RequestDurationBuckets are the grpc_request_duration_seconds histogram
+	// boundaries, in seconds.
+	RequestDurationBuckets []float64
+
+	// ShutdownTimeout bounds how long shutdown waits for in-flight calls
+	// before closing them. Zero waits indefinitely.
+	ShutdownTimeout time.Duration
+
+	// RedisMode is single, cluster or sentinel. RedisAddrs lists the node
+	// (single), seed node (cluster) or Sentinel (sentinel) addresses and
+	// defaults to RedisAddr. RedisMasterName names the Sentinel master.
+	RedisMode       string
+	RedisAddrs      []string
+	RedisMasterName string
+
+	// SearchAddr is the RediSearch endpoint when it is not served by Redis
+	// itself, e.g. behind a separate proxy. Empty uses the Redis node.
+	SearchAddr string
+
+	// ServiceInstanceTag distinguishes deployments of the same service
+	// (e.g. "canary") in telemetry.
+	ServiceInstanceTag string
+
+	// RedisPoolSize is the connection pool size per node; zero keeps the
+	// go-redis default of 10 per CPU. RedisMaxRetries of -1 disables retries.
+	RedisPoolSize     int
+	RedisDialTimeout  time.Duration
+	RedisReadTimeout  time.Duration
+	RedisWriteTimeout time.Duration
+	RedisMaxRetries   int
+
+	RedisMGetBatchSize   int
+	RedisMGetParallelism int
+	RedisPingInterval    time.Duration
+
+	// RedisNotifyExpirations enables expired-key notifications on startup
+	// (notify-keyspace-events Ex) for the WatchExpirations stream.
+	RedisNotifyExpirations bool
+
+	// RateLimit applies to every method without an entry in
+	// RateLimitMethods. A zero RPS disables limiting.
+	RateLimit        RateLimit
+	RateLimitMethods map[string]RateLimit
+
+	// ClientConcurrencyLimit caps the calls each client may have in flight.
+	// Zero disables the limit.
+	ClientConcurrencyLimit int
+
+	// TLSCertFile and TLSKeyFile enable TLS on the gRPC server when both are
+	// set. TLSClientCAFile additionally requires client certificates signed
+	// by that CA.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	// APIKeys enables x-api-key authentication when non-empty.
+	APIKeys []string
+
+	// DefaultPageSize is the ListProducts page size when a request sets
+	// none; requests asking for more than MaxPageSize are rejected.
+	DefaultPageSize int
+	MaxPageSize     int
+
+	// MaxProductPrice and MaxProductStock bound the price and stock
+	// CreateProduct and UpdateProduct accept.
+	MaxProductPrice float64
+	MaxProductStock int
+
+	// MaxBatchSize is the most products a CreateProductsBatch call may
+	// create.
+	MaxBatchSize int
+
+	// MaxTagsPerProduct and MaxAttributesPerProduct cap the tags and
+	// attribute entries a product may carry, and MaxTagLength and
+	// MaxAttributeLength the length of each, keeping a client from blowing
+	// up storage and the search index.
+	MaxTagsPerProduct       int
+	MaxTagLength            int
+	MaxAttributesPerProduct int
+	MaxAttributeLength      int
+
+	// ServerTimingEnabled attaches per-phase handler timings to unary
+	// responses as a server-timing trailer.
+	ServerTimingEnabled bool
+
+	LowStockThreshold       int
+	LowStockRefreshInterval time.Duration
+	// StockStatusLowThreshold is the stock below which products are
+	// labelled LOW_STOCK for requests asking for stock_as_status.
+	StockStatusLowThreshold int
+
+	// ProductAgeSampleSize products are sampled every
+	// ProductAgeRefreshInterval for the product_age_seconds histogram.
+	ProductAgeSampleSize      int
+	ProductAgeRefreshInterval time.Duration
+
+	CategoriesCacheTTL time.Duration
+	// CategoriesEager loads the category list at startup and keeps it in
+	// memory, refreshing it every CategoriesRefreshInterval and whenever a
+	// product introduces a new category, instead of expiring it after
+	// CategoriesCacheTTL.
+	CategoriesEager           bool
+	CategoriesRefreshInterval time.Duration
+	// SuggestionsCacheTTL is how long product names are cached for
+	// SuggestProducts when RediSearch is unavailable.
+	SuggestionsCacheTTL time.Duration
+	// CatalogStatsCacheTTL is how long GetCatalogStats results are cached.
+	CatalogStatsCacheTTL time.Duration
+
+	// MemoryIndexEnabled builds an in-memory inverted index of names,
+	// descriptions and categories at startup when RediSearch is unavailable.
+	// It is discarded if the catalog exceeds MemoryIndexMaxProducts.
+	MemoryIndexEnabled     bool
+	MemoryIndexMaxProducts int
+
+	// ProductCacheSize enables an in-memory LRU of this many products in
+	// front of GetProduct. Zero disables the cache.
+	ProductCacheSize int
+	ProductCacheTTL  time.Duration
+
+	// SeedEnabled fills an under-populated catalog with SeedTargetCount
+	// products on startup. It defaults to on only in development.
+	SeedEnabled     bool
+	SeedTargetCount int
+	// SeedUpsertBase overwrites stored base seed products that differ from
+	// the hardcoded seeds instead of only inserting missing ones.
+	SeedUpsertBase bool
+
+	// ProductSchemaRewrite stores products upgraded to the current schema
+	// version on read back to Redis.
+	ProductSchemaRewrite bool
+
+	// ReindexRate throttles ReindexProducts to this many products per
+	// second. Zero means unthrottled.
+	ReindexRate float64
+	// SearchIndexRecreate drops and recreates, then repopulates, a search
+	// index created with an outdated schema at startup.
+	SearchIndexRecreate bool
+	// SearchDedupResults drops repeated products from search result pages,
+	// which a partially failed reindex can leave behind.
+	SearchDedupResults bool
+	// SearchListAll serves ListProducts requests without a search query or
+	// category from the search index too, instead of scanning the keyspace.
+	SearchListAll bool
+	// ProductEnricher names the built-in enricher applied to products before
+	// they are created or updated: none or slug.
+	ProductEnricher string
+
+	// IndexConcurrency is how many batches bulk writes such as seeding
+	// index concurrently while writing the next batch.
+	IndexConcurrency int
+
+	// IdempotencyKeyTTL is how long CreateProduct remembers an
+	// idempotency-key and returns the product created with it.
+	IdempotencyKeyTTL time.Duration
+	// CreateDedupWindow makes a CreateProduct without an idempotency key
+	// return the product created within this window with the same name,
+	// category and price instead of creating a duplicate. Zero disables it.
+	CreateDedupWindow time.Duration
+}
+
+// RateLimit configures a token bucket refilled at RPS tokens per second and
+// holding at most Burst tokens.
+type RateLimit struct {
+	RPS   float64
+	Burst int
 }
 
-func Load() *Config {
+// Load reads the configuration from environment variables, on top of the
+// file named by CONFIG_FILE when it is set.
+func Load() (*Config, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return LoadFromFile(path)
+	}
+	return load(nil), nil
+}
+
+// load builds the configuration from src, environment variables taking
+// precedence over its values and both over the built-in defaults.
+func load(src source) *Config {
+	environment := src.getEnv("ENVIRONMENT", "development")
+	redisAddr := src.getEnv("REDIS_ADDR", "localhost:6379")
+	redisAddrs := src.getEnvList("REDIS_ADDRS")
+	if len(redisAddrs) == 0 {
+		redisAddrs = []string{redisAddr}
+	}
+
 	return &Config{
-		GRPCPort:       getEnv("GRPC_PORT", "50051"),
-		RedisAddr:      getEnv("REDIS_ADDR", "localhost:6379"),
-		JaegerEndpoint: getEnv("JAEGER_ENDPOINT", "http://localhost:14268/api/traces"),
-		OTLPEndpoint:   getEnv("OTLP_ENDPOINT", "localhost:4317"),
-		MetricsPort:    getEnv("METRICS_PORT", "2112"),
-		Environment:    getEnv("ENVIRONMENT", "development"),
-		LogFilePath:    getEnv("LOG_FILE_PATH", "./logs/products-service/service.log"),
+		GRPCPort:       src.getEnv("GRPC_PORT", "50051"),
+		RedisAddr:      redisAddr,
+		JaegerEndpoint: src.getEnv("JAEGER_ENDPOINT", "http://localhost:14268/api/traces"),
+		TraceExporter:  src.getEnv("TRACE_EXPORTER", "jaeger"),
+		OTLPEndpoint:   src.getEnv("OTLP_ENDPOINT", "localhost:4317"),
+		MetricsPort:    src.getEnv("METRICS_PORT", "2112"),
+		ExportHTTPPort: src.getEnv("EXPORT_HTTP_PORT", ""),
+		GatewayPort:    src.getEnv("GATEWAY_PORT", ""),
+		Environment:    environment,
+		LogFilePath:    src.getEnv("LOG_FILE_PATH", "./logs/products-service/service.log"),
+		LogLevel:       src.getEnv("LOG_LEVEL", "info"),
+		LogMaxSizeMB:   src.getEnvInt("LOG_MAX_SIZE_MB", 100),
+		LogMaxBackups:  src.getEnvInt("LOG_MAX_BACKUPS", 5),
+		LogMaxAgeDays:  src.getEnvInt("LOG_MAX_AGE_DAYS", 28),
+
+		LogFullRequests:           src.getEnvBool("LOG_FULL_REQUESTS", false),
+		LogRequestMaxStringLength: src.getEnvInt("LOG_REQUEST_MAX_STRING_LENGTH", 64),
+
+		MetricsFallbackPorts: src.getEnvList("METRICS_FALLBACK_PORTS"),
+		MetricsBindFatal:     src.getEnvBool("METRICS_BIND_FATAL", false),
+
+		RequestDurationBuckets: src.getEnvFloatList("GRPC_DURATION_BUCKETS", DefaultRequestDurationBuckets),
+
+		ShutdownTimeout: src.getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		RedisMode:       src.getEnv("REDIS_MODE", RedisModeSingle),
+		RedisAddrs:      redisAddrs,
+		RedisMasterName: src.getEnv("REDIS_MASTER_NAME", ""),
+
+		SearchAddr: src.getEnv("SEARCH_ADDR", ""),
+
+		ServiceInstanceTag: src.getEnv("SERVICE_INSTANCE_TAG", ""),
+
+		RedisPoolSize:     src.getEnvInt("REDIS_POOL_SIZE", 0),
+		RedisDialTimeout:  src.getEnvDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
+		RedisReadTimeout:  src.getEnvDuration("REDIS_READ_TIMEOUT", 3*time.Second),
+		RedisWriteTimeout: src.getEnvDuration("REDIS_WRITE_TIMEOUT", 3*time.Second),
+		RedisMaxRetries:   src.getEnvInt("REDIS_MAX_RETRIES", 3),
+
+		RedisMGetBatchSize:   src.getEnvInt("REDIS_MGET_BATCH_SIZE", 100),
+		RedisMGetParallelism: src.getEnvInt("REDIS_MGET_PARALLELISM", 4),
+		RedisPingInterval:    src.getEnvDuration("REDIS_PING_INTERVAL", 15*time.Second),
+
+		RedisNotifyExpirations: src.getEnvBool("REDIS_NOTIFY_EXPIRATIONS", false),
+
+		RateLimit: RateLimit{
+			RPS:   src.getEnvFloat("RATE_LIMIT_RPS", 0),
+			Burst: src.getEnvInt("RATE_LIMIT_BURST", 1),
+		},
+		RateLimitMethods: src.getEnvRateLimits("RATE_LIMIT_METHODS"),
+
+		ClientConcurrencyLimit: src.getEnvInt("CLIENT_CONCURRENCY_LIMIT", 0),
+
+		TLSCertFile:     src.getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:      src.getEnv("TLS_KEY_FILE", ""),
+		TLSClientCAFile: src.getEnv("TLS_CLIENT_CA_FILE", ""),
+
+		APIKeys: src.getEnvList("API_KEYS"),
+
+		DefaultPageSize: src.getEnvInt("DEFAULT_PAGE_SIZE", 10),
+		MaxPageSize:     src.getEnvInt("MAX_PAGE_SIZE", 100),
+
+		MaxProductPrice: src.getEnvFloat("MAX_PRODUCT_PRICE", 1_000_000_000),
+		MaxProductStock: src.getEnvInt("MAX_PRODUCT_STOCK", math.MaxInt32),
+		MaxBatchSize:    src.getEnvInt("MAX_BATCH_SIZE", 1000),
+
+		MaxTagsPerProduct:       src.getEnvInt("MAX_TAGS_PER_PRODUCT", 20),
+		MaxTagLength:            src.getEnvInt("MAX_TAG_LENGTH", 64),
+		MaxAttributesPerProduct: src.getEnvInt("MAX_ATTRIBUTES_PER_PRODUCT", 50),
+		MaxAttributeLength:      src.getEnvInt("MAX_ATTRIBUTE_LENGTH", 256),
+
+		ServerTimingEnabled: src.getEnvBool("SERVER_TIMING_ENABLED", false),
+
+		LowStockThreshold:       src.getEnvInt("LOW_STOCK_THRESHOLD", 10),
+		LowStockRefreshInterval: src.getEnvDuration("LOW_STOCK_REFRESH_INTERVAL", time.Minute),
+		StockStatusLowThreshold: src.getEnvInt("STOCK_STATUS_LOW_THRESHOLD", 10),
+
+		ProductAgeSampleSize:      src.getEnvInt("PRODUCT_AGE_SAMPLE_SIZE", 500),
+		ProductAgeRefreshInterval: src.getEnvDuration("PRODUCT_AGE_REFRESH_INTERVAL", 5*time.Minute),
+
+		CategoriesCacheTTL:  src.getEnvDuration("CATEGORIES_CACHE_TTL", 30*time.Second),
+		SuggestionsCacheTTL: src.getEnvDuration("SUGGESTIONS_CACHE_TTL", 30*time.Second),
+
+		CatalogStatsCacheTTL: src.getEnvDuration("CATALOG_STATS_CACHE_TTL", 30*time.Second),
+
+		CategoriesEager:           src.getEnvBool("CATEGORIES_EAGER", false),
+		CategoriesRefreshInterval: src.getEnvDuration("CATEGORIES_REFRESH_INTERVAL", time.Minute),
+
+		MemoryIndexEnabled:     src.getEnvBool("MEMORY_INDEX_ENABLED", false),
+		MemoryIndexMaxProducts: src.getEnvInt("MEMORY_INDEX_MAX_PRODUCTS", 50000),
+
+		ProductCacheSize: src.getEnvInt("PRODUCT_CACHE_SIZE", 0),
+		ProductCacheTTL:  src.getEnvDuration("PRODUCT_CACHE_TTL", 30*time.Second),
+
+		SeedEnabled:     src.getEnvBool("SEED_ENABLED", environment == "development"),
+		SeedTargetCount: src.getEnvInt("SEED_TARGET_COUNT", 100000),
+		SeedUpsertBase:  src.getEnvBool("SEED_UPSERT_BASE", false),
+
+		ProductSchemaRewrite: src.getEnvBool("PRODUCT_SCHEMA_REWRITE", false),
+
+		ReindexRate:         src.getEnvFloat("REINDEX_RATE", 1000),
+		SearchIndexRecreate: src.getEnvBool("SEARCH_INDEX_RECREATE", false),
+		SearchDedupResults:  src.getEnvBool("SEARCH_DEDUP_RESULTS", true),
+		SearchListAll:       src.getEnvBool("SEARCH_LIST_ALL", true),
+		IndexConcurrency:    src.getEnvInt("INDEX_CONCURRENCY", 4),
+		ProductEnricher:     src.getEnv("PRODUCT_ENRICHER", "none"),
+
+		IdempotencyKeyTTL: src.getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		CreateDedupWindow: src.getEnvDuration("CREATE_DEDUP_WINDOW", 0),
+	}
+}
+
+// ServiceName returns the name the service reports itself as, suffixed with
+// the instance tag when one is configured.
+func (c *Config) ServiceName() string {
+	if c.ServiceInstanceTag == "" {
+		return serviceName
 	}
+	return serviceName + "-" + c.ServiceInstanceTag
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+func (s source) getEnv(key, defaultValue string) string {
+	if value := s.get(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func (s source) getEnvInt(key string, defaultValue int) int {
+	if value := s.get(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated value, dropping empty entries.
+func (s source) getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(s.get(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (s source) getEnvFloat(key string, defaultValue float64) float64 {
+	if value := s.get(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvFloatList parses a comma-separated list of numbers, falling back to
+// defaultValue if any entry is malformed.
+func (s source) getEnvFloatList(key string, defaultValue []float64) []float64 {
+	entries := s.getEnvList(key)
+	if len(entries) == 0 {
+		return defaultValue
+	}
+	values := make([]float64, 0, len(entries))
+	for _, entry := range entries {
+		parsed, err := strconv.ParseFloat(entry, 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, parsed)
+	}
+	return values
+}
+
+// getEnvRateLimits parses per-method limits of the form
+// "/pkg.Service/Method=rps:burst,...". Malformed entries are skipped.
+func (s source) getEnvRateLimits(key string) map[string]RateLimit {
+	limits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(s.get(key), ",") {
+		method, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || method == "" {
+			continue
+		}
+		rpsValue, burstValue, ok := strings.Cut(spec, ":")
+		if !ok {
+			continue
+		}
+		rps, err := strconv.ParseFloat(rpsValue, 64)
+		if err != nil {
+			continue
+		}
+		burst, err := strconv.Atoi(burstValue)
+		if err != nil {
+			continue
+		}
+		limits[method] = RateLimit{RPS: rps, Burst: burst}
+	}
+	return limits
+}
+
+func (s source) getEnvBool(key string, defaultValue bool) bool {
+	if value := s.get(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func (s source) getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := s.get(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
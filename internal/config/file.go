@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source holds configuration values read from a file, keyed by the
+// environment variable they correspond to. A nil source is empty.
+type source map[string]string
+
+// get returns the environment variable key if it is set, otherwise the
+// file's value for it.
+func (s source) get(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return s[key]
+}
+
+// LoadFromFile reads the configuration from a YAML or JSON file (chosen by
+// a .json extension, YAML otherwise) whose keys are the environment variable
+// names, matched case-insensitively:
+//
+//	grpc_port: 50051
+//	redis_addrs: [redis-1:6379, redis-2:6379]
+//	rate_limit_methods:
+//	  /products.ProductsService/CreateProduct: "5:10"
+//
+// Lists are joined with commas and maps become key=value lists. Environment
+// variables override the file, and both override the built-in defaults.
+func LoadFromFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &values)
+	} else {
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	src := make(source, len(values))
+	for key, value := range values {
+		formatted, err := formatFileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+		src[strings.ToUpper(key)] = formatted
+	}
+
+	return load(src), nil
+}
+
+// formatFileValue renders a decoded file value the way it would be written
+// in the corresponding environment variable.
+func formatFileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			formatted, err := formatFileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = formatted
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			formatted, err := formatFileValue(v[key])
+			if err != nil {
+				return "", err
+			}
+			items[i] = key + "=" + formatted
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v of type %T", value, value)
+	}
+}
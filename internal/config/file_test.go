@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadFromFilePrecedence(t *testing.T) {
+	for _, name := range []string{"config.yaml", "config.json"} {
+		t.Run(name, func(t *testing.T) {
+			// Unset the variables under test; empty values count as unset.
+			for _, key := range []string{"GRPC_PORT", "METRICS_PORT", "SEED_TARGET_COUNT", "REDIS_ADDRS", "LOG_FILE_PATH"} {
+				t.Setenv(key, "")
+			}
+
+			content := "grpc_port: 6000\nmetrics_port: \"7000\"\nseed_target_count: 50\nredis_addrs: [redis-1:6379, redis-2:6379]\n"
+			if name == "config.json" {
+				content = `{"GRPC_PORT": 6000, "metrics_port": "7000", "seed_target_count": 50, "redis_addrs": ["redis-1:6379", "redis-2:6379"]}`
+			}
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			t.Setenv("METRICS_PORT", "8000")
+			t.Setenv("SEED_TARGET_COUNT", "25")
+
+			cfg, err := LoadFromFile(path)
+			if err != nil {
+				t.Fatalf("LoadFromFile: %v", err)
+			}
+
+			// Defaults apply to what neither the file nor the environment sets.
+			if cfg.LogFilePath != "./logs/products-service/service.log" {
+				t.Errorf("LogFilePath = %q, want the default", cfg.LogFilePath)
+			}
+			// The file overrides defaults.
+			if cfg.GRPCPort != "6000" {
+				t.Errorf("GRPCPort = %q, want 6000 from the file", cfg.GRPCPort)
+			}
+			if want := []string{"redis-1:6379", "redis-2:6379"}; !slices.Equal(cfg.RedisAddrs, want) {
+				t.Errorf("RedisAddrs = %q, want %q from the file", cfg.RedisAddrs, want)
+			}
+			// The environment overrides the file.
+			if cfg.MetricsPort != "8000" {
+				t.Errorf("MetricsPort = %q, want 8000 from the environment", cfg.MetricsPort)
+			}
+			if cfg.SeedTargetCount != 25 {
+				t.Errorf("SeedTargetCount = %d, want 25 from the environment", cfg.SeedTargetCount)
+			}
+		})
+	}
+}
+
+func TestLoadUsesConfigFile(t *testing.T) {
+	t.Setenv("GRPC_PORT", "")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("grpc_port: 6001\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.GRPCPort != "6001" {
+		t.Errorf("GRPCPort = %q, want 6001 from CONFIG_FILE", cfg.GRPCPort)
+	}
+}
+
+func TestLoadFromFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadFromFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadFromFile of a missing file succeeded")
+	}
+	path := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := LoadFromFile(path); err == nil {
+		t.Error("LoadFromFile of malformed JSON succeeded")
+	}
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// Validate checks the configuration for values that would otherwise only
+// fail later, and far from their cause. Every problem found is reported.
+func (c *Config) Validate() error {
+	var errs []error
+
+	errs = append(errs, validatePort("GRPC_PORT", c.GRPCPort))
+	errs = append(errs, validatePort("METRICS_PORT", c.MetricsPort))
+	for _, port := range c.MetricsFallbackPorts {
+		errs = append(errs, validatePort("METRICS_FALLBACK_PORTS", port))
+	}
+	if c.ExportHTTPPort != "" {
+		errs = append(errs, validatePort("EXPORT_HTTP_PORT", c.ExportHTTPPort))
+	}
+	if c.GatewayPort != "" {
+		errs = append(errs, validatePort("GATEWAY_PORT", c.GatewayPort))
+	}
+
+	errs = append(errs, validateHostPort("REDIS_ADDR", c.RedisAddr))
+	for _, addr := range c.RedisAddrs {
+		errs = append(errs, validateHostPort("REDIS_ADDRS", addr))
+	}
+	if c.SearchAddr != "" {
+		errs = append(errs, validateHostPort("SEARCH_ADDR", c.SearchAddr))
+	}
+
+	switch c.RedisMode {
+	case RedisModeSingle, RedisModeCluster:
+	case RedisModeSentinel:
+		if c.RedisMasterName == "" {
+			errs = append(errs, errors.New("REDIS_MASTER_NAME is required when REDIS_MODE is sentinel"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("REDIS_MODE %q must be one of %s, %s or %s",
+			c.RedisMode, RedisModeSingle, RedisModeCluster, RedisModeSentinel))
+	}
+
+	if c.LogMaxSizeMB < 1 {
+		errs = append(errs, fmt.Errorf("LOG_MAX_SIZE_MB %d must be at least 1", c.LogMaxSizeMB))
+	}
+	if c.LogMaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("LOG_MAX_BACKUPS %d must not be negative", c.LogMaxBackups))
+	}
+	if c.LogMaxAgeDays < 0 {
+		errs = append(errs, fmt.Errorf("LOG_MAX_AGE_DAYS %d must not be negative", c.LogMaxAgeDays))
+	}
+
+	if c.LogRequestMaxStringLength < 1 {
+		errs = append(errs, fmt.Errorf("LOG_REQUEST_MAX_STRING_LENGTH %d must be at least 1", c.LogRequestMaxStringLength))
+	}
+
+	if c.IdempotencyKeyTTL <= 0 {
+		errs = append(errs, fmt.Errorf("IDEMPOTENCY_KEY_TTL %s must be positive", c.IdempotencyKeyTTL))
+	}
+	switch c.ProductEnricher {
+	case "none", "slug":
+	default:
+		errs = append(errs, fmt.Errorf("PRODUCT_ENRICHER %q must be none or slug", c.ProductEnricher))
+	}
+	if c.MaxPageSize < 1 {
+		errs = append(errs, fmt.Errorf("MAX_PAGE_SIZE %d must be at least 1", c.MaxPageSize))
+	}
+	if c.DefaultPageSize < 1 || c.DefaultPageSize > c.MaxPageSize {
+		errs = append(errs, fmt.Errorf("DEFAULT_PAGE_SIZE %d must be between 1 and MAX_PAGE_SIZE (%d)", c.DefaultPageSize, c.MaxPageSize))
+	}
+	if math.IsNaN(c.MaxProductPrice) || math.IsInf(c.MaxProductPrice, 0) || c.MaxProductPrice <= 0 {
+		errs = append(errs, fmt.Errorf("MAX_PRODUCT_PRICE %g must be a positive number", c.MaxProductPrice))
+	}
+	if c.MaxProductStock < 0 || c.MaxProductStock > math.MaxInt32 {
+		errs = append(errs, fmt.Errorf("MAX_PRODUCT_STOCK %d must be between 0 and %d", c.MaxProductStock, math.MaxInt32))
+	}
+	if c.MaxBatchSize < 1 {
+		errs = append(errs, fmt.Errorf("MAX_BATCH_SIZE %d must be at least 1", c.MaxBatchSize))
+	}
+	if c.ClientConcurrencyLimit < 0 {
+		errs = append(errs, fmt.Errorf("CLIENT_CONCURRENCY_LIMIT %d must not be negative", c.ClientConcurrencyLimit))
+	}
+	if c.IndexConcurrency < 1 {
+		errs = append(errs, fmt.Errorf("INDEX_CONCURRENCY %d must be at least 1", c.IndexConcurrency))
+	}
+	if c.CreateDedupWindow < 0 {
+		errs = append(errs, fmt.Errorf("CREATE_DEDUP_WINDOW %s must not be negative", c.CreateDedupWindow))
+	}
+
+	errs = append(errs, validateBuckets("GRPC_DURATION_BUCKETS", c.RequestDurationBuckets))
+
+	errs = append(errs, validateURL("JAEGER_ENDPOINT", c.JaegerEndpoint))
+	// The OTLP gRPC exporter takes a bare host:port, but a URL is accepted
+	// too.
+	if validateHostPort("OTLP_ENDPOINT", c.OTLPEndpoint) != nil {
+		errs = append(errs, validateURL("OTLP_ENDPOINT", c.OTLPEndpoint))
+	}
+
+	if c.MaxTagsPerProduct < 0 {
+		errs = append(errs, fmt.Errorf("MAX_TAGS_PER_PRODUCT %d must not be negative", c.MaxTagsPerProduct))
+	}
+	if c.MaxTagLength < 1 {
+		errs = append(errs, fmt.Errorf("MAX_TAG_LENGTH %d must be at least 1", c.MaxTagLength))
+	}
+	if c.MaxAttributesPerProduct < 0 {
+		errs = append(errs, fmt.Errorf("MAX_ATTRIBUTES_PER_PRODUCT %d must not be negative", c.MaxAttributesPerProduct))
+	}
+	if c.MaxAttributeLength < 1 {
+		errs = append(errs, fmt.Errorf("MAX_ATTRIBUTE_LENGTH %d must be at least 1", c.MaxAttributeLength))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validatePort(name, value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%s %q must be a port number between 1 and 65535", name, value)
+	}
+	return nil
+}
+
+func validateHostPort(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s must not be empty", name)
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" {
+		return fmt.Errorf("%s %q must have the form host:port", name, value)
+	}
+	return validatePort(name, port)
+}
+
+func validateBuckets(name string, buckets []float64) error {
+	for i, bucket := range buckets {
+		if bucket <= 0 {
+			return fmt.Errorf("%s bucket %g must be positive", name, bucket)
+		}
+		if i > 0 && bucket <= buckets[i-1] {
+			return fmt.Errorf("%s buckets must be in increasing order", name)
+		}
+	}
+	return nil
+}
+
+func validateURL(name, value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%s %q must be an absolute URL such as http://host:port/path", name, value)
+	}
+	return nil
+}
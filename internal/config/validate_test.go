@@ -0,0 +1,72 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validConfig returns the built-in defaults, which must validate.
+func validConfig() *Config {
+	return load(source{})
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{name: "defaults", modify: func(c *Config) {}},
+		{name: "non-numeric port", modify: func(c *Config) { c.GRPCPort = "grpc" }, wantErr: "GRPC_PORT"},
+		{name: "port out of range", modify: func(c *Config) { c.MetricsPort = "70000" }, wantErr: "METRICS_PORT"},
+		{name: "port zero", modify: func(c *Config) { c.GRPCPort = "0" }, wantErr: "GRPC_PORT"},
+		{name: "bad fallback port", modify: func(c *Config) { c.MetricsFallbackPorts = []string{"2113", "x"} }, wantErr: "METRICS_FALLBACK_PORTS"},
+		{name: "bad gateway port", modify: func(c *Config) { c.GatewayPort = "-1" }, wantErr: "GATEWAY_PORT"},
+		{name: "empty redis address", modify: func(c *Config) { c.RedisAddr = "" }, wantErr: "REDIS_ADDR must not be empty"},
+		{name: "redis address without port", modify: func(c *Config) { c.RedisAddr = "localhost" }, wantErr: "REDIS_ADDR"},
+		{name: "redis address without host", modify: func(c *Config) { c.RedisAddr = ":6379" }, wantErr: "REDIS_ADDR"},
+		{name: "bad redis addrs entry", modify: func(c *Config) { c.RedisAddrs = []string{"redis:6379", "redis"} }, wantErr: "REDIS_ADDRS"},
+		{name: "unknown redis mode", modify: func(c *Config) { c.RedisMode = "ring" }, wantErr: "REDIS_MODE"},
+		{name: "sentinel without master", modify: func(c *Config) { c.RedisMode = RedisModeSentinel }, wantErr: "REDIS_MASTER_NAME"},
+		{name: "relative jaeger endpoint", modify: func(c *Config) { c.JaegerEndpoint = "localhost/api/traces" }, wantErr: "JAEGER_ENDPOINT"},
+		{name: "otlp host and port", modify: func(c *Config) { c.OTLPEndpoint = "collector:4317" }},
+		{name: "otlp url", modify: func(c *Config) { c.OTLPEndpoint = "http://collector:4318" }},
+		{name: "bad otlp endpoint", modify: func(c *Config) { c.OTLPEndpoint = "collector" }, wantErr: "OTLP_ENDPOINT"},
+		{name: "unsorted buckets", modify: func(c *Config) { c.RequestDurationBuckets = []float64{0.1, 0.05} }, wantErr: "GRPC_DURATION_BUCKETS"},
+		{name: "default page size above max", modify: func(c *Config) { c.DefaultPageSize = c.MaxPageSize + 1 }, wantErr: "DEFAULT_PAGE_SIZE"},
+		{name: "unknown enricher", modify: func(c *Config) { c.ProductEnricher = "upper" }, wantErr: "PRODUCT_ENRICHER"},
+		{name: "negative tag cap", modify: func(c *Config) { c.MaxTagsPerProduct = -1 }, wantErr: "MAX_TAGS_PER_PRODUCT"},
+		{name: "zero tag length", modify: func(c *Config) { c.MaxTagLength = 0 }, wantErr: "MAX_TAG_LENGTH"},
+		{name: "negative attribute cap", modify: func(c *Config) { c.MaxAttributesPerProduct = -1 }, wantErr: "MAX_ATTRIBUTES_PER_PRODUCT"},
+		{name: "zero attribute length", modify: func(c *Config) { c.MaxAttributeLength = 0 }, wantErr: "MAX_ATTRIBUTE_LENGTH"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.modify(cfg)
+			err := cfg.Validate()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Errorf("Validate = %v, want no error", err)
+			case tc.wantErr != "" && err == nil:
+				t.Errorf("Validate succeeded, want an error about %s", tc.wantErr)
+			case tc.wantErr != "" && !strings.Contains(err.Error(), tc.wantErr):
+				t.Errorf("Validate = %v, want an error about %s", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := validConfig()
+	cfg.GRPCPort = "x"
+	cfg.RedisAddr = ""
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded")
+	}
+	for _, want := range []string{"GRPC_PORT", "REDIS_ADDR"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, want it to mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	apiKeyHeader = "x-api-key"
+
+	// Health checks come from orchestrators that don't hold API keys.
+	healthServicePrefix = "/grpc.health.v1.Health/"
+)
+
+type apiKeyKey struct{}
+
+// authenticatedAPIKey returns the API key the call was authenticated with,
+// if API-key authentication checked one.
+func authenticatedAPIKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(apiKeyKey{}).(string)
+	return key, ok
+}
+
+// APIKeyAuthInterceptor requires every call to carry one of keys in the
+// x-api-key metadata header. With no keys configured it lets every call
+// through, so authentication stays opt-in.
+func APIKeyAuthInterceptor(keys []string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if len(keys) == 0 || strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		key, err := authenticate(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(context.WithValue(ctx, apiKeyKey{}, key), req)
+	}
+}
+
+// APIKeyAuthStreamInterceptor applies the same checks as
+// APIKeyAuthInterceptor to streaming calls.
+func APIKeyAuthStreamInterceptor(keys []string) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if len(keys) == 0 || strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(srv, ss)
+		}
+
+		key, err := authenticate(ss.Context(), keys)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &contextStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), apiKeyKey{}, key),
+		})
+	}
+}
+
+// authenticate returns the valid API key the call carries.
+func authenticate(ctx context.Context, keys []string) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(apiKeyHeader)
+	if len(values) == 0 || values[0] == "" {
+		return "", status.Errorf(codes.Unauthenticated, "missing %s header", apiKeyHeader)
+	}
+	if !validAPIKey(keys, values[0]) {
+		return "", status.Errorf(codes.PermissionDenied, "invalid API key")
+	}
+	return values[0], nil
+}
+
+func validAPIKey(keys []string, candidate string) bool {
+	valid := false
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// APIKeyAuthHandler applies the same checks as APIKeyAuthInterceptor to
+// HTTP requests, reading the key from the X-Api-Key header.
+func APIKeyAuthHandler(keys []string, next http.Handler) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(apiKeyHeader)
+		if key == "" {
+			http.Error(w, "missing "+apiKeyHeader+" header", http.StatusUnauthorized)
+			return
+		}
+		if !validAPIKey(keys, key) {
+			http.Error(w, "invalid API key", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
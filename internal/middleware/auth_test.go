@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/chirik/products/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// authTestServer answers GetProduct and streams a single product, so the
+// tests see whether a call got past the interceptors.
+type authTestServer struct {
+	proto.UnimplementedProductsServiceServer
+}
+
+func (authTestServer) GetProduct(ctx context.Context, req *proto.GetProductRequest) (*proto.Product, error) {
+	return &proto.Product{Id: req.Id}, nil
+}
+
+func (authTestServer) StreamProducts(req *proto.ListProductsRequest, stream grpc.ServerStreamingServer[proto.Product]) error {
+	return stream.Send(&proto.Product{Id: "p1"})
+}
+
+// newAuthTestClient serves authTestServer over an in-memory connection
+// behind the API key interceptors.
+func newAuthTestClient(t *testing.T, keys []string) proto.ProductsServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(APIKeyAuthInterceptor(keys)),
+		grpc.ChainStreamInterceptor(APIKeyAuthStreamInterceptor(keys)),
+	)
+	proto.RegisterProductsServiceServer(server, authTestServer{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewProductsServiceClient(conn)
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	client := newAuthTestClient(t, []string{"first-key", "second-key"})
+
+	for _, tc := range []struct {
+		name string
+		key  string
+		want codes.Code
+	}{
+		{name: "missing key", want: codes.Unauthenticated},
+		{name: "invalid key", key: "wrong-key", want: codes.PermissionDenied},
+		{name: "valid key", key: "second-key", want: codes.OK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.key != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, apiKeyHeader, tc.key)
+			}
+
+			_, err := client.GetProduct(ctx, &proto.GetProductRequest{Id: "p1"})
+			if got := status.Code(err); got != tc.want {
+				t.Errorf("GetProduct = %v, want %v", err, tc.want)
+			}
+
+			stream, err := client.StreamProducts(ctx, &proto.ListProductsRequest{})
+			if err != nil {
+				t.Fatalf("StreamProducts: %v", err)
+			}
+			_, err = stream.Recv()
+			if got := status.Code(err); got != tc.want {
+				t.Errorf("StreamProducts Recv = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthDisabledWithoutKeys(t *testing.T) {
+	client := newAuthTestClient(t, nil)
+	ctx := context.Background()
+
+	if _, err := client.GetProduct(ctx, &proto.GetProductRequest{Id: "p1"}); err != nil {
+		t.Errorf("GetProduct: %v", err)
+	}
+	stream, err := client.StreamProducts(ctx, &proto.ListProductsRequest{})
+	if err != nil {
+		t.Fatalf("StreamProducts: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Errorf("StreamProducts Recv: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ClientConcurrencyLimiter caps the calls each client may have in flight,
+// so that one aggressive client can't take all of the server's capacity.
+// Clients are told apart by the API key they authenticated with, then by
+// TLS client certificate, then by IP address. The limiter must run after
+// the API-key interceptors; an unchecked key doesn't name a client, since
+// callers could pick a new one for every call.
+type ClientConcurrencyLimiter struct {
+	limit int
+
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// NewClientConcurrencyLimiter allows up to limit concurrent calls per
+// client. A limit of zero or less disables limiting.
+func NewClientConcurrencyLimiter(limit int) *ClientConcurrencyLimiter {
+	return &ClientConcurrencyLimiter{
+		limit:    limit,
+		inFlight: make(map[string]int),
+	}
+}
+
+// acquire reserves a slot for client, reporting false if it has none left.
+func (l *ClientConcurrencyLimiter) acquire(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight[client] >= l.limit {
+		return false
+	}
+	l.inFlight[client]++
+	return true
+}
+
+func (l *ClientConcurrencyLimiter) release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Idle clients are forgotten so the map doesn't grow with every peer
+	if l.inFlight[client]--; l.inFlight[client] <= 0 {
+		delete(l.inFlight, client)
+	}
+}
+
+// UnaryServerInterceptor rejects calls beyond the client's limit with
+// codes.ResourceExhausted.
+func (l *ClientConcurrencyLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if l.limit <= 0 || strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(ctx, req)
+		}
+
+		client := clientIdentity(ctx)
+		if !l.acquire(client) {
+			return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent calls; at most %d are allowed per client", l.limit)
+		}
+		defer l.release(client)
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor applies the same limit to streaming calls, which
+// hold their slot until the stream ends.
+func (l *ClientConcurrencyLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if l.limit <= 0 || strings.HasPrefix(info.FullMethod, healthServicePrefix) {
+			return handler(srv, ss)
+		}
+
+		client := clientIdentity(ss.Context())
+		if !l.acquire(client) {
+			return status.Errorf(codes.ResourceExhausted, "too many concurrent calls; at most %d are allowed per client", l.limit)
+		}
+		defer l.release(client)
+
+		return handler(srv, ss)
+	}
+}
+
+// forwardedForHeader is the metadata key the REST gateway passes the HTTP
+// client's address in. The gateway appends the address it was called from
+// to any X-Forwarded-For the client sent, so only the last entry can be
+// trusted.
+const forwardedForHeader = "x-forwarded-for"
+
+// clientIdentity names the caller of the call in ctx.
+func clientIdentity(ctx context.Context) string {
+	if key, ok := authenticatedAPIKey(ctx); ok {
+		return "key:" + key
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	// The REST gateway calls over loopback on behalf of its HTTP clients
+	// and names them in X-Forwarded-For.
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if forwarded := metadata.ValueFromIncomingContext(ctx, forwardedForHeader); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if last := strings.TrimSpace(entries[len(entries)-1]); last != "" {
+				return "ip:" + last
+			}
+		}
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+		return "cert:" + tlsInfo.State.PeerCertificates[0].Subject.String()
+	}
+	return "ip:" + host
+}
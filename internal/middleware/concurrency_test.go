@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// peerContext is the context of a call from addr carrying md.
+func peerContext(t *testing.T, addr string, md metadata.MD) context.Context {
+	t.Helper()
+
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		t.Fatalf("ResolveTCPAddr: %v", err)
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	if md != nil {
+		ctx = metadata.NewIncomingContext(ctx, md)
+	}
+	return ctx
+}
+
+func TestClientIdentity(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		addr string
+		md   metadata.MD
+		key  string
+		want string
+	}{
+		{name: "authenticated API key", addr: "10.0.0.1:1234", md: metadata.Pairs(apiKeyHeader, "k1"), key: "k1", want: "key:k1"},
+		{name: "unchecked API key", addr: "10.0.0.1:1234", md: metadata.Pairs(apiKeyHeader, "k1"), want: "ip:10.0.0.1"},
+		{name: "peer address", addr: "10.0.0.1:1234", want: "ip:10.0.0.1"},
+		{
+			name: "gateway client",
+			addr: "127.0.0.1:1234",
+			md:   metadata.Pairs(forwardedForHeader, "203.0.113.7"),
+			want: "ip:203.0.113.7",
+		},
+		{
+			name: "gateway client with its own forwarded header",
+			addr: "127.0.0.1:1234",
+			md:   metadata.Pairs(forwardedForHeader, "198.51.100.1, 203.0.113.7"),
+			want: "ip:203.0.113.7",
+		},
+		{
+			name: "forwarded header from a remote peer",
+			addr: "10.0.0.1:1234",
+			md:   metadata.Pairs(forwardedForHeader, "203.0.113.7"),
+			want: "ip:10.0.0.1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := peerContext(t, tc.addr, tc.md)
+			if tc.key != "" {
+				ctx = context.WithValue(ctx, apiKeyKey{}, tc.key)
+			}
+			if got := clientIdentity(ctx); got != tc.want {
+				t.Errorf("clientIdentity = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// holdCall starts a call through the API-key and concurrency interceptors
+// that stays in flight until the test ends.
+func holdCall(t *testing.T, ctx context.Context, call func(context.Context, grpc.UnaryHandler) error) {
+	t.Helper()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	go call(ctx, func(context.Context, any) (any, error) {
+		close(entered)
+		<-release
+		return nil, nil
+	})
+	<-entered
+}
+
+func TestClientConcurrencyLimiterBuckets(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/products.ProductsService/GetProduct"}
+	through := func(keys []string) func(context.Context, grpc.UnaryHandler) error {
+		auth := APIKeyAuthInterceptor(keys)
+		limit := NewClientConcurrencyLimiter(1).UnaryServerInterceptor()
+		return func(ctx context.Context, handler grpc.UnaryHandler) error {
+			_, err := auth(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+				return limit(ctx, req, info, handler)
+			})
+			return err
+		}
+	}
+	done := func(context.Context, any) (any, error) { return nil, nil }
+
+	for _, tc := range []struct {
+		name          string
+		keys          []string
+		first, second context.Context
+		wantCode      codes.Code
+	}{
+		{
+			// Without authentication the keys are made up, so they don't
+			// get a bucket each.
+			name:     "new API key per call without auth",
+			first:    peerContext(t, "10.0.0.1:1000", metadata.Pairs(apiKeyHeader, "a")),
+			second:   peerContext(t, "10.0.0.1:1001", metadata.Pairs(apiKeyHeader, "b")),
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "authenticated API keys",
+			keys:     []string{"a", "b"},
+			first:    peerContext(t, "10.0.0.1:1000", metadata.Pairs(apiKeyHeader, "a")),
+			second:   peerContext(t, "10.0.0.1:1001", metadata.Pairs(apiKeyHeader, "b")),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "spoofed forwarded address through the gateway",
+			first:    peerContext(t, "127.0.0.1:1000", metadata.Pairs(forwardedForHeader, "198.51.100.1, 203.0.113.7")),
+			second:   peerContext(t, "127.0.0.1:1001", metadata.Pairs(forwardedForHeader, "198.51.100.2, 203.0.113.7")),
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "different gateway clients",
+			first:    peerContext(t, "127.0.0.1:1000", metadata.Pairs(forwardedForHeader, "203.0.113.7")),
+			second:   peerContext(t, "127.0.0.1:1001", metadata.Pairs(forwardedForHeader, "203.0.113.8")),
+			wantCode: codes.OK,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			call := through(tc.keys)
+			holdCall(t, tc.first, call)
+			if err := call(tc.second, done); status.Code(err) != tc.wantCode {
+				t.Errorf("second call = %v, want %v", err, tc.wantCode)
+			}
+		})
+	}
+}
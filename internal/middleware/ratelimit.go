@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chirik/products/internal/config"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RateLimiter keeps one token bucket per gRPC method. Methods listed in the
+// per-method limits get their own rate and burst; every other method gets a
+// bucket with the default limit.
+type RateLimiter struct {
+	defaultLimit config.RateLimit
+	methodLimits map[string]config.RateLimit
+	now          func() time.Time
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewRateLimiter creates a limiter that reads the current time from now,
+// which lets tests drive the buckets with a fake clock.
+func NewRateLimiter(defaultLimit config.RateLimit, methodLimits map[string]config.RateLimit, now func() time.Time) *RateLimiter {
+	return &RateLimiter{
+		defaultLimit: defaultLimit,
+		methodLimits: methodLimits,
+		now:          now,
+		limiters:     make(map[string]*rate.Limiter),
+	}
+}
+
+// Allow reports whether a call to method may proceed, consuming a token if so.
+func (l *RateLimiter) Allow(method string) bool {
+	limiter := l.limiterFor(method)
+	if limiter == nil {
+		return true
+	}
+	return limiter.AllowN(l.now(), 1)
+}
+
+func (l *RateLimiter) limiterFor(method string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if limiter, ok := l.limiters[method]; ok {
+		return limiter
+	}
+
+	limit, ok := l.methodLimits[method]
+	if !ok {
+		limit = l.defaultLimit
+	}
+
+	var limiter *rate.Limiter
+	if limit.RPS > 0 {
+		limiter = rate.NewLimiter(rate.Limit(limit.RPS), limit.Burst)
+	}
+	l.limiters[method] = limiter
+	return limiter
+}
+
+// UnaryServerInterceptor rejects calls that exceed their method's rate with
+// codes.ResourceExhausted.
+func (l *RateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !l.Allow(info.FullMethod) {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s", info.FullMethod)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chirik/products/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiterRejectsCallsBeyondBurst(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(
+		config.RateLimit{RPS: 1, Burst: 3},
+		map[string]config.RateLimit{"/products.ProductsService/CreateProduct": {RPS: 1, Burst: 1}},
+		func() time.Time { return now },
+	)
+
+	interceptor := limiter.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	call := func(method string) error {
+		_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	const listMethod = "/products.ProductsService/ListProducts"
+	for i := 0; i < 3; i++ {
+		if err := call(listMethod); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	if err := call(listMethod); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("call 4 = %v, want ResourceExhausted", err)
+	}
+
+	// The per-method limit applies to CreateProduct alone.
+	const createMethod = "/products.ProductsService/CreateProduct"
+	if err := call(createMethod); err != nil {
+		t.Fatalf("first create: %v", err)
+	}
+	if err := call(createMethod); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second create = %v, want ResourceExhausted", err)
+	}
+
+	// A second later each bucket has refilled one token.
+	now = now.Add(time.Second)
+	if err := call(createMethod); err != nil {
+		t.Fatalf("create after refill: %v", err)
+	}
+	if err := call(listMethod); err != nil {
+		t.Fatalf("list after refill: %v", err)
+	}
+	if err := call(listMethod); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second list after refill = %v, want ResourceExhausted", err)
+	}
+}
+
+func TestRateLimiterUnlimitedWithoutRate(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimit{}, nil, time.Now)
+	for i := 0; i < 100; i++ {
+		if !limiter.Allow("/products.ProductsService/GetProduct") {
+			t.Fatalf("call %d rejected without a configured rate", i+1)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// APIVersion1 returns timestamps as RFC 3339 strings.
+	APIVersion1 int32 = 1
+	// APIVersion2 returns timestamps as google.protobuf.Timestamp.
+	APIVersion2 int32 = 2
+
+	apiVersionHeader = "x-api-version"
+)
+
+type apiVersionKey struct{}
+
+// APIVersionFromContext returns the API version negotiated for the call,
+// defaulting to APIVersion1 for clients that don't request one.
+func APIVersionFromContext(ctx context.Context) int32 {
+	if version, ok := ctx.Value(apiVersionKey{}).(int32); ok {
+		return version
+	}
+	return APIVersion1
+}
+
+// APIVersionInterceptor reads the requested version from the x-api-version
+// metadata header, rejects unsupported versions and echoes the negotiated
+// version back as a response header.
+func APIVersionInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		version, err := requestedAPIVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := grpc.SetHeader(ctx, metadata.Pairs(apiVersionHeader, strconv.Itoa(int(version)))); err != nil {
+			return nil, err
+		}
+		return handler(context.WithValue(ctx, apiVersionKey{}, version), req)
+	}
+}
+
+// APIVersionStreamInterceptor is the streaming counterpart of
+// APIVersionInterceptor.
+func APIVersionStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		version, err := requestedAPIVersion(ss.Context())
+		if err != nil {
+			return err
+		}
+		if err := ss.SetHeader(metadata.Pairs(apiVersionHeader, strconv.Itoa(int(version)))); err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), apiVersionKey{}, version),
+		})
+	}
+}
+
+func requestedAPIVersion(ctx context.Context) (int32, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(apiVersionHeader)
+	if len(values) == 0 || values[0] == "" {
+		return APIVersion1, nil
+	}
+
+	version, err := strconv.Atoi(values[0])
+	if err != nil || (int32(version) != APIVersion1 && int32(version) != APIVersion2) {
+		return 0, status.Errorf(codes.InvalidArgument, "unsupported API version %q", values[0])
+	}
+	return int32(version), nil
+}
+
+// contextStream overrides the context of a wrapped server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,68 @@
+package observability
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var productAge metric.Float64Histogram
+
+func init() {
+	meter := otel.Meter("products-service")
+	var err error
+
+	day := (24 * time.Hour).Seconds()
+	productAge, err = meter.Float64Histogram(
+		"product_age_seconds",
+		metric.WithDescription("Age of sampled products since creation in seconds"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(
+			time.Hour.Seconds(), day, 7*day, 30*day, 90*day, 180*day, 365*day, 730*day,
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CreationTimeSampler returns the creation times of a sample of products.
+type CreationTimeSampler interface {
+	SampleCreationTimes(ctx context.Context, size int) ([]time.Time, error)
+}
+
+// MonitorProductAge records the age of sampleSize products in the
+// product_age_seconds histogram every interval until ctx is cancelled.
+func MonitorProductAge(ctx context.Context, sampler CreationTimeSampler, sampleSize int, interval time.Duration, logger *zap.Logger) {
+	if interval <= 0 || sampleSize <= 0 {
+		logger.Warn("Product age monitoring disabled",
+			zap.Duration("interval", interval),
+			zap.Int("sample_size", sampleSize),
+		)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		times, err := sampler.SampleCreationTimes(ctx, sampleSize)
+		if err != nil {
+			logger.Warn("Failed to sample product ages", zap.Error(err))
+		} else {
+			now := time.Now()
+			for _, createdAt := range times {
+				productAge.Record(ctx, now.Sub(createdAt).Seconds())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -4,20 +4,33 @@ import (
 	"context"
 	"time"
 
+	"github.com/chirik/products/internal/config"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 var (
-	requestDuration metric.Float64Histogram
-	requestCount    metric.Int64Counter
+	requestDuration    metric.Float64Histogram
+	requestCount       metric.Int64Counter
+	requestErrors      metric.Int64Counter
+	compressedRequests metric.Int64Counter
+	requestBytes       metric.Int64Histogram
+	responseBytes      metric.Int64Histogram
 )
 
+// payloadBuckets span 100B to 10MB.
+var payloadBuckets = []float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// identityCodec labels requests sent without compression.
+const identityCodec = "identity"
+
 func init() {
 	meter := otel.Meter("products-service")
 	var err error
@@ -26,6 +39,7 @@ func init() {
 		"grpc_request_duration_seconds",
 		metric.WithDescription("Duration of gRPC requests in seconds"),
 		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(config.DefaultRequestDurationBuckets...),
 	)
 	if err != nil {
 		panic(err)
@@ -38,9 +52,70 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	requestErrors, err = meter.Int64Counter(
+		"grpc_request_errors_total",
+		metric.WithDescription("Total number of gRPC requests that failed, by status code"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	requestBytes, err = meter.Int64Histogram(
+		"grpc_request_bytes",
+		metric.WithDescription("Size of gRPC request messages in bytes, before compression"),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(payloadBuckets...),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	responseBytes, err = meter.Int64Histogram(
+		"grpc_response_bytes",
+		metric.WithDescription("Size of gRPC response messages in bytes, before compression"),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(payloadBuckets...),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	compressedRequests, err = meter.Int64Counter(
+		"grpc_requests_by_compression_total",
+		metric.WithDescription("Total number of gRPC requests by negotiated compression codec"),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// messageSize returns the encoded size of msg, or false for values that
+// aren't protobuf messages.
+func messageSize(msg interface{}) (int, bool) {
+	m, ok := msg.(proto.Message)
+	if !ok || m == nil {
+		return 0, false
+	}
+	return proto.Size(m), true
 }
 
-func UnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+// requestCodec returns the compression codec the client used for the
+// request, which grpc-go also uses for the response when it is registered.
+// The grpc-encoding header is reserved and not exposed as metadata, so it is
+// read from the transport stream.
+func requestCodec(ctx context.Context) string {
+	type recvCompressor interface{ RecvCompress() string }
+
+	if rc, ok := grpc.ServerTransportStreamFromContext(ctx).(recvCompressor); ok {
+		if codec := rc.RecvCompress(); codec != "" {
+			return codec
+		}
+	}
+	return identityCodec
+}
+
+func UnaryServerInterceptor(logger *zap.Logger, requestLogging RequestLogging) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
@@ -53,14 +128,33 @@ func UnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 		ctx, span := otel.Tracer("products-service").Start(ctx, info.FullMethod)
 		defer span.End()
 
+		id := requestID(ctx)
 		span.SetAttributes(
 			attribute.String("grpc.method", info.FullMethod),
+			attribute.String("request.id", id),
 		)
 
+		// Tag everything logged while handling the call for correlation
+		logger := requestLogger(ctx, logger, info.FullMethod, id)
+		ctx = WithLogger(ctx, logger)
+
+		// Echo the ID so callers can quote it, even for IDs generated here
+		if err := grpc.SetTrailer(ctx, metadata.Pairs(requestIDHeader, id)); err != nil {
+			logger.Warn("Failed to set request ID trailer", zap.Error(err))
+		}
+
 		// Log request
 		logger.Info("gRPC request started",
-			zap.String("method", info.FullMethod),
-			zap.Any("request", req),
+			requestField(req, requestLogging),
+		)
+
+		codec := requestCodec(ctx)
+		compressedRequests.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("method", info.FullMethod),
+				attribute.String("codec", codec),
+				attribute.Bool("compressed", codec != identityCodec),
+			),
 		)
 
 		// Handle request
@@ -92,6 +186,26 @@ func UnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 			),
 		)
 
+		attrs := metric.WithAttributes(
+			attribute.String("method", info.FullMethod),
+			attribute.String("status", statusCode.String()),
+		)
+		if size, ok := messageSize(req); ok {
+			requestBytes.Record(ctx, int64(size), attrs)
+		}
+		if size, ok := messageSize(resp); ok && err == nil {
+			responseBytes.Record(ctx, int64(size), attrs)
+		}
+
+		if statusCode != codes.OK {
+			requestErrors.Add(ctx, 1,
+				metric.WithAttributes(
+					attribute.String("method", info.FullMethod),
+					attribute.String("code", statusCode.String()),
+				),
+			)
+		}
+
 		// Update span
 		span.SetAttributes(
 			attribute.String("grpc.status", statusCode.String()),
@@ -101,13 +215,11 @@ func UnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 		if err != nil {
 			span.RecordError(err)
 			logger.Error("gRPC request failed",
-				zap.String("method", info.FullMethod),
 				zap.Error(err),
 				zap.Duration("duration", time.Since(start)),
 			)
 		} else {
 			logger.Info("gRPC request completed",
-				zap.String("method", info.FullMethod),
 				zap.Duration("duration", time.Since(start)),
 			)
 		}
@@ -115,3 +227,113 @@ func UnaryServerInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
 		return resp, err
 	}
 }
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor: it traces the stream, tags its logs with the
+// request ID, echoes the ID in the trailer and records the same request
+// metrics, counting the messages sent.
+func StreamServerInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+
+		ctx, span := otel.Tracer("products-service").Start(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		id := requestID(ctx)
+		span.SetAttributes(
+			attribute.String("grpc.method", info.FullMethod),
+			attribute.String("request.id", id),
+		)
+
+		logger := requestLogger(ctx, logger, info.FullMethod, id)
+		ctx = WithLogger(ctx, logger)
+		ss.SetTrailer(metadata.Pairs(requestIDHeader, id))
+
+		logger.Info("gRPC stream started")
+
+		codec := requestCodec(ctx)
+		compressedRequests.Add(ctx, 1,
+			metric.WithAttributes(
+				attribute.String("method", info.FullMethod),
+				attribute.String("codec", codec),
+				attribute.Bool("compressed", codec != identityCodec),
+			),
+		)
+
+		stream := &observedStream{ServerStream: ss, ctx: ctx}
+		err := handler(srv, stream)
+
+		duration := time.Since(start).Seconds()
+
+		statusCode := codes.OK
+		if err != nil {
+			if s, ok := status.FromError(err); ok {
+				statusCode = s.Code()
+			} else {
+				statusCode = codes.Unknown
+			}
+		}
+
+		attrs := metric.WithAttributes(
+			attribute.String("method", info.FullMethod),
+			attribute.String("status", statusCode.String()),
+		)
+		requestDuration.Record(ctx, duration, attrs)
+		requestCount.Add(ctx, 1, attrs)
+		if statusCode != codes.OK {
+			requestErrors.Add(ctx, 1,
+				metric.WithAttributes(
+					attribute.String("method", info.FullMethod),
+					attribute.String("code", statusCode.String()),
+				),
+			)
+		}
+
+		span.SetAttributes(
+			attribute.String("grpc.status", statusCode.String()),
+			attribute.Float64("duration", duration),
+			attribute.Int("messages_sent", stream.sent),
+		)
+
+		if err != nil {
+			span.RecordError(err)
+			logger.Error("gRPC stream failed",
+				zap.Error(err),
+				zap.Int("messages_sent", stream.sent),
+				zap.Duration("duration", time.Since(start)),
+			)
+		} else {
+			logger.Info("gRPC stream completed",
+				zap.Int("messages_sent", stream.sent),
+				zap.Duration("duration", time.Since(start)),
+			)
+		}
+
+		return err
+	}
+}
+
+// observedStream hands the handler the traced, logger-carrying context and
+// counts the messages it sends.
+type observedStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent int
+}
+
+func (s *observedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *observedStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	s.sent++
+	return nil
+}
@@ -0,0 +1,153 @@
+package observability
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/chirik/products/proto"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// requestIDTestServer logs through the request-scoped logger, so the tests
+// see whether handlers inherit the correlation fields.
+type requestIDTestServer struct {
+	proto.UnimplementedProductsServiceServer
+}
+
+func (requestIDTestServer) GetProduct(ctx context.Context, req *proto.GetProductRequest) (*proto.Product, error) {
+	LoggerFromContext(ctx, zap.NewNop()).Info("handler")
+	return &proto.Product{Id: req.Id}, nil
+}
+
+func (requestIDTestServer) StreamProducts(req *proto.ListProductsRequest, stream grpc.ServerStreamingServer[proto.Product]) error {
+	LoggerFromContext(stream.Context(), zap.NewNop()).Info("handler")
+	for _, id := range []string{"p1", "p2"} {
+		if err := stream.Send(&proto.Product{Id: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRequestIDTestClient serves requestIDTestServer over an in-memory
+// connection behind the observability interceptors, which log to the
+// returned observer.
+func newRequestIDTestClient(t *testing.T) (proto.ProductsServiceClient, *observer.ObservedLogs) {
+	t.Helper()
+
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(logger, RequestLogging{})),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(logger)),
+	)
+	proto.RegisterProductsServiceServer(server, requestIDTestServer{})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewProductsServiceClient(conn), logs
+}
+
+// checkRequestIDLogged fails unless every log entry carries the request ID.
+func checkRequestIDLogged(t *testing.T, logs *observer.ObservedLogs, id string) {
+	t.Helper()
+
+	entries := logs.All()
+	if len(entries) == 0 {
+		t.Fatal("nothing was logged")
+	}
+	var handlerLogged bool
+	for _, entry := range entries {
+		if got := entry.ContextMap()["request_id"]; got != id {
+			t.Errorf("%q logged with request_id %v, want %q", entry.Message, got, id)
+		}
+		handlerLogged = handlerLogged || entry.Message == "handler"
+	}
+	if !handlerLogged {
+		t.Error("the handler's log entry is missing")
+	}
+}
+
+func TestUnaryServerInterceptorRequestID(t *testing.T) {
+	client, logs := newRequestIDTestClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDHeader, "req-123")
+
+	var trailer metadata.MD
+	if _, err := client.GetProduct(ctx, &proto.GetProductRequest{Id: "p1"}, grpc.Trailer(&trailer)); err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if got := trailer.Get(requestIDHeader); len(got) != 1 || got[0] != "req-123" {
+		t.Errorf("trailer request ID = %q, want req-123", got)
+	}
+	checkRequestIDLogged(t, logs, "req-123")
+}
+
+func TestStreamServerInterceptorRequestID(t *testing.T) {
+	client, logs := newRequestIDTestClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDHeader, "req-456")
+
+	stream, err := client.StreamProducts(ctx, &proto.ListProductsRequest{})
+	if err != nil {
+		t.Fatalf("StreamProducts: %v", err)
+	}
+	var received int
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		received++
+	}
+	if received != 2 {
+		t.Errorf("received %d products, want 2", received)
+	}
+	if got := stream.Trailer().Get(requestIDHeader); len(got) != 1 || got[0] != "req-456" {
+		t.Errorf("trailer request ID = %q, want req-456", got)
+	}
+	checkRequestIDLogged(t, logs, "req-456")
+
+	completed := logs.FilterMessage("gRPC stream completed").All()
+	if len(completed) != 1 {
+		t.Fatalf("logged %d stream completions, want 1", len(completed))
+	}
+	if got := completed[0].ContextMap()["messages_sent"]; got != int64(2) {
+		t.Errorf("messages_sent = %v, want 2", got)
+	}
+}
+
+func TestStreamServerInterceptorGeneratesRequestID(t *testing.T) {
+	client, _ := newRequestIDTestClient(t)
+
+	stream, err := client.StreamProducts(context.Background(), &proto.ListProductsRequest{})
+	if err != nil {
+		t.Fatalf("StreamProducts: %v", err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+	if got := stream.Trailer().Get(requestIDHeader); len(got) != 1 || got[0] == "" {
+		t.Errorf("trailer request ID = %q, want a generated ID", got)
+	}
+}
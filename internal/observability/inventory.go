@@ -0,0 +1,60 @@
+package observability
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var lowStockCount metric.Int64Gauge
+
+func init() {
+	meter := otel.Meter("products-service")
+	var err error
+
+	lowStockCount, err = meter.Int64Gauge(
+		"products_low_stock_count",
+		metric.WithDescription("Number of products with stock below the low stock threshold"),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// LowStockCounter counts products whose stock is below a threshold.
+type LowStockCounter interface {
+	CountLowStock(ctx context.Context, threshold int32) (int64, error)
+}
+
+// MonitorLowStock refreshes the low stock gauge every interval until ctx is
+// cancelled.
+func MonitorLowStock(ctx context.Context, counter LowStockCounter, threshold int32, interval time.Duration, logger *zap.Logger) {
+	if interval <= 0 {
+		logger.Warn("Low stock monitoring disabled", zap.Duration("interval", interval))
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := counter.CountLowStock(ctx, threshold)
+		if err != nil {
+			logger.Warn("Failed to count low stock products", zap.Error(err))
+		} else {
+			lowStockCount.Record(ctx, count,
+				metric.WithAttributes(attribute.Int("threshold", int(threshold))),
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
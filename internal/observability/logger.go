@@ -3,32 +3,79 @@ package observability
 import (
 	"os"
 	"path/filepath"
+	"strings"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func NewLogger(logFilePath string) (*zap.Logger, error) {
+// LogRotation bounds the log file: it is rotated once it reaches MaxSizeMB
+// megabytes, and rotated files are removed once there are more than
+// MaxBackups of them or they are older than MaxAgeDays. Zero MaxBackups or
+// MaxAgeDays keeps rotated files indefinitely.
+type LogRotation struct {
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+}
+
+// logLevels are the levels accepted for LOG_LEVEL.
+var logLevels = map[string]zapcore.Level{
+	"debug": zapcore.DebugLevel,
+	"info":  zapcore.InfoLevel,
+	"warn":  zapcore.WarnLevel,
+	"error": zapcore.ErrorLevel,
+}
+
+// NewLogger builds the service logger at the given level (debug, info, warn
+// or error). An unknown level falls back to info and is reported once the
+// logger exists. Logs go to stdout and, when logFilePath is set, to a file
+// rotated according to rotation.
+func NewLogger(logFilePath, level string, rotation LogRotation) (*zap.Logger, error) {
 	config := zap.NewProductionConfig()
+
+	logLevel, validLevel := logLevels[strings.ToLower(level)]
+	if !validLevel {
+		logLevel = zapcore.InfoLevel
+	}
+	config.Level = zap.NewAtomicLevelAt(logLevel)
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	config.EncoderConfig.MessageKey = "message"
 	config.EncoderConfig.LevelKey = "level"
 	config.EncoderConfig.CallerKey = "caller"
 
+	var opts []zap.Option
 	if logFilePath != "" {
 		dir := filepath.Dir(logFilePath)
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return nil, err
 		}
-		config.OutputPaths = []string{"stdout", logFilePath}
-		config.ErrorOutputPaths = []string{"stderr", logFilePath}
+
+		file := zapcore.NewCore(
+			zapcore.NewJSONEncoder(config.EncoderConfig),
+			zapcore.AddSync(&lumberjack.Logger{
+				Filename:   logFilePath,
+				MaxSize:    rotation.MaxSizeMB,
+				MaxBackups: rotation.MaxBackups,
+				MaxAge:     rotation.MaxAgeDays,
+			}),
+			config.Level,
+		)
+		opts = append(opts, zap.WrapCore(func(stdout zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(stdout, file)
+		}))
 	}
 
-	logger, err := config.Build()
+	logger, err := config.Build(opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	if !validLevel {
+		logger.Warn("Unknown log level; using info", zap.String("level", level))
+	}
+
 	return logger, nil
 }
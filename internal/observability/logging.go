@@ -0,0 +1,55 @@
+package observability
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDHeader is the metadata key clients use to tag a call for log
+// correlation.
+const requestIDHeader = "x-request-id"
+
+type loggerKey struct{}
+
+// WithLogger returns a context carrying logger for LoggerFromContext.
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the request-scoped logger attached by the
+// server interceptor, or fallback outside of a request.
+func LoggerFromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// requestID returns the request ID the caller sent, or a new one if it
+// sent none.
+func requestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(requestIDHeader); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	return uuid.NewString()
+}
+
+// requestLogger derives a logger tagged with the method, the request ID and
+// the trace ID of the span in ctx.
+func requestLogger(ctx context.Context, logger *zap.Logger, method, requestID string) *zap.Logger {
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.String("request_id", requestID),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+	}
+
+	return logger.With(fields...)
+}
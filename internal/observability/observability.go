@@ -2,7 +2,9 @@ package observability
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -10,7 +12,9 @@ import (
 	clientprom "github.com/prometheus/client_golang/prometheus"
 	promhttp "github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/metric"
@@ -29,12 +33,15 @@ func Init(cfg *config.Config, logger *zap.Logger) (func(), error) {
 	ctx := context.Background()
 
 	// Initialize resource
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String("products-service"),
-			semconv.ServiceVersionKey.String("1.0.0"),
-		),
-	)
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(cfg.ServiceName()),
+		semconv.ServiceVersionKey.String("1.0.0"),
+	}
+	if cfg.ServiceInstanceTag != "" {
+		attrs = append(attrs, attribute.String("service.instance.tag", cfg.ServiceInstanceTag))
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(attrs...))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
@@ -59,8 +66,17 @@ func Init(cfg *config.Config, logger *zap.Logger) (func(), error) {
 	meterProvider = mp
 	otel.SetMeterProvider(mp)
 
-	// Start metrics server
-	go startMetricsServer(cfg.MetricsPort, logger)
+	// Start metrics server. The port is bound here so that a conflict is
+	// reported before the service starts taking traffic.
+	lis, err := listenMetrics(cfg, logger)
+	if err != nil {
+		if cfg.MetricsBindFatal {
+			return nil, err
+		}
+		logger.Error("Metrics server unavailable", zap.Error(err))
+	} else {
+		go startMetricsServer(lis, logger)
+	}
 
 	shutdown := func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -82,10 +98,15 @@ func Init(cfg *config.Config, logger *zap.Logger) (func(), error) {
 	return shutdown, nil
 }
 
+const (
+	TraceExporterJaeger = "jaeger"
+	TraceExporterOTLP   = "otlp"
+)
+
 func initTracer(cfg *config.Config, res *resource.Resource, logger *zap.Logger) (*trace.TracerProvider, error) {
-	exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.JaegerEndpoint)))
+	exporter, endpoint, err := newTraceExporter(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create jaeger exporter: %w", err)
+		return nil, err
 	}
 
 	tp := trace.NewTracerProvider(
@@ -93,10 +114,35 @@ func initTracer(cfg *config.Config, res *resource.Resource, logger *zap.Logger)
 		trace.WithResource(res),
 	)
 
-	logger.Info("Tracer initialized", zap.String("endpoint", cfg.JaegerEndpoint))
+	logger.Info("Tracer initialized",
+		zap.String("exporter", cfg.TraceExporter),
+		zap.String("endpoint", endpoint),
+	)
 	return tp, nil
 }
 
+func newTraceExporter(cfg *config.Config) (trace.SpanExporter, string, error) {
+	switch cfg.TraceExporter {
+	case "", TraceExporterJaeger:
+		exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.JaegerEndpoint)))
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create jaeger exporter: %w", err)
+		}
+		return exporter, cfg.JaegerEndpoint, nil
+	case TraceExporterOTLP:
+		exporter, err := otlptracegrpc.New(context.Background(),
+			otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+			otlptracegrpc.WithInsecure(),
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		return exporter, cfg.OTLPEndpoint, nil
+	default:
+		return nil, "", fmt.Errorf("unknown trace exporter %q (expected %q or %q)", cfg.TraceExporter, TraceExporterJaeger, TraceExporterOTLP)
+	}
+}
+
 var prometheusExporter *otelprometheus.Exporter
 
 func initMetrics(cfg *config.Config, res *resource.Resource, logger *zap.Logger) (*metric.MeterProvider, error) {
@@ -107,16 +153,49 @@ func initMetrics(cfg *config.Config, res *resource.Resource, logger *zap.Logger)
 
 	prometheusExporter = exporter
 
+	// The histogram is created with the default buckets as a hint; the view
+	// applies the configured ones.
+	durationBuckets := metric.NewView(
+		metric.Instrument{Name: "grpc_request_duration_seconds"},
+		metric.Stream{Aggregation: metric.AggregationExplicitBucketHistogram{
+			Boundaries: cfg.RequestDurationBuckets,
+		}},
+	)
+
 	mp := metric.NewMeterProvider(
 		metric.WithReader(exporter),
 		metric.WithResource(res),
+		metric.WithView(durationBuckets),
 	)
 
 	logger.Info("Metrics initialized", zap.String("port", cfg.MetricsPort))
 	return mp, nil
 }
 
-func startMetricsServer(port string, logger *zap.Logger) {
+// listenMetrics binds the metrics port, trying each fallback port in turn
+// if it is taken.
+func listenMetrics(cfg *config.Config, logger *zap.Logger) (net.Listener, error) {
+	ports := append([]string{cfg.MetricsPort}, cfg.MetricsFallbackPorts...)
+
+	var errs []error
+	for _, port := range ports {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+		if err == nil {
+			if port != cfg.MetricsPort {
+				logger.Warn("Metrics port unavailable; using fallback port",
+					zap.String("port", cfg.MetricsPort),
+					zap.String("fallback_port", port),
+				)
+			}
+			return lis, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, fmt.Errorf("failed to bind metrics port: %w", errors.Join(errs...))
+}
+
+func startMetricsServer(lis net.Listener, logger *zap.Logger) {
 	if prometheusExporter == nil {
 		logger.Error("Prometheus exporter not initialized")
 		return
@@ -136,9 +215,9 @@ func startMetricsServer(port string, logger *zap.Logger) {
 	http.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
 		EnableOpenMetrics: true,
 	}))
-	addr := fmt.Sprintf(":%s", port)
-	logger.Info("Starting metrics server", zap.String("address", addr))
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	http.HandleFunc("/ready", readyHandler)
+	logger.Info("Starting metrics server", zap.String("address", lis.Addr().String()))
+	if err := http.Serve(lis, nil); err != nil {
 		logger.Error("Metrics server failed", zap.Error(err))
 	}
 }
@@ -0,0 +1,65 @@
+package observability
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var lastSuccessfulPing metric.Float64Gauge
+
+func init() {
+	meter := otel.Meter("products-service")
+	var err error
+
+	lastSuccessfulPing, err = meter.Float64Gauge(
+		"redis_last_successful_ping_timestamp_seconds",
+		metric.WithDescription("Unix time of the last successful Redis ping"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Pinger is implemented by dependencies whose liveness can be probed.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// MonitorPing pings the dependency every interval until ctx is cancelled,
+// recording the time of each successful ping. If notify is non-nil it is
+// called with the result of every ping.
+func MonitorPing(ctx context.Context, pinger Pinger, interval time.Duration, logger *zap.Logger, notify func(error)) {
+	if interval <= 0 {
+		logger.Warn("Redis ping monitoring disabled", zap.Duration("interval", interval))
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		err := pinger.Ping(pingCtx)
+		cancel()
+
+		if err != nil {
+			logger.Warn("Redis ping failed", zap.Error(err))
+		} else {
+			lastSuccessfulPing.Record(ctx, float64(time.Now().UnixNano())/float64(time.Second))
+		}
+		if notify != nil {
+			notify(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// readinessPingTimeout bounds the Redis ping made for each /ready request.
+const readinessPingTimeout = 2 * time.Second
+
+// ReadinessChecker is implemented by the repository the service serves from.
+type ReadinessChecker interface {
+	Pinger
+	SearchEnabled() bool
+}
+
+var readiness atomic.Pointer[ReadinessChecker]
+
+// SetReadinessChecker registers the repository probed by /ready on the
+// metrics server. Until it is called the service reports itself not ready.
+func SetReadinessChecker(checker ReadinessChecker) {
+	readiness.Store(&checker)
+}
+
+type dependencyStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type searchStatus struct {
+	Enabled bool `json:"enabled"`
+}
+
+type readinessResponse struct {
+	Ready      bool             `json:"ready"`
+	Redis      dependencyStatus `json:"redis"`
+	RediSearch searchStatus     `json:"redisearch"`
+}
+
+// readyHandler answers 200 while Redis is reachable and 503 otherwise.
+// RediSearch is reported but does not gate readiness, since the repository
+// falls back to scanning without it.
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+	resp := readinessResponse{Redis: dependencyStatus{Status: "unavailable"}}
+
+	if checker := readiness.Load(); checker == nil {
+		resp.Redis.Error = "repository not initialized"
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessPingTimeout)
+		err := (*checker).Ping(ctx)
+		cancel()
+
+		if err != nil {
+			resp.Redis.Error = err.Error()
+		} else {
+			resp.Ready = true
+			resp.Redis.Status = "ok"
+		}
+		resp.RediSearch.Enabled = (*checker).SearchEnabled()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
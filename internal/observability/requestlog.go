@@ -0,0 +1,65 @@
+package observability
+
+import (
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// RequestLogging controls how much of each request the interceptor logs.
+// Unless Full is set, string fields longer than MaxStringLength characters
+// are truncated, so bulky fields such as descriptions don't flood the logs.
+type RequestLogging struct {
+	Full            bool
+	MaxStringLength int
+}
+
+// requestField returns the zap field logging req according to opts.
+func requestField(req interface{}, opts RequestLogging) zap.Field {
+	msg, ok := req.(proto.Message)
+	if opts.Full || !ok || msg == nil {
+		return zap.Any("request", req)
+	}
+
+	summary := proto.Clone(msg)
+	truncateStrings(summary.ProtoReflect(), opts.MaxStringLength)
+	return zap.Any("request", summary)
+}
+
+// truncateStrings shortens every string in msg, including those in nested
+// and repeated messages, to at most maxLength characters. Map values are
+// left alone.
+func truncateStrings(msg protoreflect.Message, maxLength int) {
+	msg.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+		case fd.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				switch fd.Kind() {
+				case protoreflect.StringKind:
+					list.Set(i, protoreflect.ValueOfString(truncate(list.Get(i).String(), maxLength)))
+				case protoreflect.MessageKind, protoreflect.GroupKind:
+					truncateStrings(list.Get(i).Message(), maxLength)
+				}
+			}
+		case fd.Kind() == protoreflect.StringKind:
+			msg.Set(fd, protoreflect.ValueOfString(truncate(value.String(), maxLength)))
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			truncateStrings(value.Message(), maxLength)
+		}
+		return true
+	})
+}
+
+// truncate cuts s to maxLength characters, marking the cut with an
+// ellipsis.
+func truncate(s string, maxLength int) string {
+	if utf8.RuneCountInString(s) <= maxLength {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:maxLength]) + "…"
+}
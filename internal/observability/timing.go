@@ -0,0 +1,97 @@
+package observability
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// serverTimingTrailer carries the timings in the W3C Server-Timing format,
+// e.g. "repository;dur=1.204, serialization;dur=0.051, total;dur=1.530".
+const serverTimingTrailer = "server-timing"
+
+// serverTiming accumulates named durations measured while handling a call.
+type serverTiming struct {
+	mu      sync.Mutex
+	names   []string
+	entries map[string]time.Duration
+}
+
+func (t *serverTiming) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.entries[name]; !ok {
+		t.names = append(t.names, name)
+	}
+	t.entries[name] += d
+}
+
+func (t *serverTiming) header(total time.Duration) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parts := make([]string, 0, len(t.names)+1)
+	for _, name := range t.names {
+		parts = append(parts, formatTiming(name, t.entries[name]))
+	}
+	parts = append(parts, formatTiming("total", total))
+	return strings.Join(parts, ", ")
+}
+
+func formatTiming(name string, d time.Duration) string {
+	return fmt.Sprintf("%s;dur=%.3f", name, float64(d)/float64(time.Millisecond))
+}
+
+type serverTimingKey struct{}
+
+// StartTiming starts measuring the named phase of the current call and
+// returns a function that stops it. Repeated phases are summed. It is a
+// no-op unless ServerTimingInterceptor is enabled.
+func StartTiming(ctx context.Context, name string) func() {
+	timing, ok := ctx.Value(serverTimingKey{}).(*serverTiming)
+	if !ok {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		timing.add(name, time.Since(start))
+	}
+}
+
+// ServerTimingInterceptor attaches the phases recorded with StartTiming,
+// plus the total handler time, as a server-timing trailer. When disabled it
+// passes calls straight through and StartTiming costs nothing.
+func ServerTimingInterceptor(enabled bool, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !enabled {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		timing := &serverTiming{entries: make(map[string]time.Duration)}
+		resp, err := handler(context.WithValue(ctx, serverTimingKey{}, timing), req)
+
+		trailer := metadata.Pairs(serverTimingTrailer, timing.header(time.Since(start)))
+		if setErr := grpc.SetTrailer(ctx, trailer); setErr != nil {
+			logger.Warn("Failed to set server timing trailer",
+				zap.String("method", info.FullMethod),
+				zap.Error(setErr),
+			)
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SampleCreationTimes returns the creation times of up to size products
+// picked with RANDOMKEY, so catalog freshness can be estimated without
+// reading the whole catalog. Products may be picked more than once, and
+// non-product keys drawn by RANDOMKEY are skipped, so fewer than size times
+// can be returned.
+func (r *RedisRepository) SampleCreationTimes(ctx context.Context, size int) ([]time.Time, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, size)
+	for i := range cmds {
+		cmds[i] = pipe.RandomKey(ctx)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to sample product keys: %w", err)
+	}
+
+	keys := make([]string, 0, size)
+	for _, cmd := range cmds {
+		if key := cmd.Val(); strings.HasPrefix(key, productsKeyPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	products, err := r.mgetProducts(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make([]time.Time, 0, len(products))
+	for _, product := range products {
+		if !product.CreatedAt.IsZero() {
+			times = append(times, product.CreatedAt)
+		}
+	}
+	return times, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CreateProducts stores products in a single pipeline, assigning IDs and
+// timestamps like CreateProduct, and returns one error per product, nil
+// for those stored. A product that fails to enrich, encode, claim its SKU
+// or write doesn't keep the others from being stored. The stored products
+// are indexed with one batched search call in the background, so they can
+// take a moment to become searchable.
+func (r *RedisRepository) CreateProducts(ctx context.Context, products []*Product) []error {
+	errs := make([]error, len(products))
+	if len(products) == 0 {
+		return errs
+	}
+
+	encoded := make([][]byte, len(products))
+	var pending []int
+	for i, product := range products {
+		if err := r.prepareNewProduct(ctx, product); err != nil {
+			errs[i] = err
+			continue
+		}
+		data, err := json.Marshal(product)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to marshal product: %w", err)
+			continue
+		}
+		encoded[i] = data
+		pending = append(pending, i)
+	}
+
+	candidates := make([]*Product, len(pending))
+	for j, i := range pending {
+		candidates[j] = products[i]
+	}
+	skuErrs := r.reserveSKUs(ctx, candidates)
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StatusCmd, len(products))
+	for j, i := range pending {
+		if skuErrs[j] != nil {
+			errs[i] = skuErrs[j]
+			continue
+		}
+		cmds[i] = pipe.Set(ctx, r.keyFor(products[i].ID), encoded[i], 0)
+	}
+	// Per-command errors are inspected below; Exec only reports the first.
+	if pipe.Len() > 0 {
+		_, _ = pipe.Exec(ctx)
+	}
+
+	stored := make([]*Product, 0, len(products))
+	for i, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		if err := cmd.Err(); err != nil {
+			errs[i] = fmt.Errorf("failed to set product: %w", err)
+			r.releaseSKU(ctx, products[i].SKU, products[i].ID)
+			continue
+		}
+		stored = append(stored, products[i])
+	}
+
+	r.productsStored(ctx, stored, r.indexer)
+	return errs
+}
+
+// UpsertProducts stores products in a single pipeline, overwriting any
+// stored under the same IDs, for imports. Unlike CreateProducts it keeps
+// the products' timestamps, versions and slugs; only a missing ID or
+// creation time is filled in, and products are not enriched. Like
+// CreateProducts it indexes them in the background. SKUs are reserved like
+// on create: if another product, or an earlier one in products, has the
+// SKU of one of them, none are stored and the error wraps ErrDuplicateSKU.
+func (r *RedisRepository) UpsertProducts(ctx context.Context, products []*Product) error {
+	now := time.Now()
+	for _, product := range products {
+		if product.ID == "" {
+			product.ID = newProductID()
+		}
+		if product.CreatedAt.IsZero() {
+			product.CreatedAt = now
+		}
+	}
+
+	// SKUs reserved before a failure are left claimed: their products
+	// don't have them, so the claims are taken over like any stale mapping.
+	var skuErrs []error
+	for i, err := range r.reserveSKUs(ctx, products) {
+		if err != nil {
+			skuErrs = append(skuErrs, fmt.Errorf("product %s: %w", products[i].ID, err))
+		}
+	}
+	if len(skuErrs) > 0 {
+		return errors.Join(skuErrs...)
+	}
+
+	return r.createProducts(ctx, products, r.indexer)
+}
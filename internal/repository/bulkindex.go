@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+)
+
+// bulkIndexer indexes batches of stored products on a bounded pool of
+// goroutines, so that writing the next batch overlaps with indexing the
+// previous ones. Submitting blocks while every worker is busy, which keeps
+// a fast writer from queueing unbounded work.
+type bulkIndexer struct {
+	r   *RedisRepository
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func (r *RedisRepository) newBulkIndexer(concurrency int) *bulkIndexer {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &bulkIndexer{r: r, sem: make(chan struct{}, concurrency)}
+}
+
+// index adds products to the search index and suggestion dictionary in the
+// background. Products are replaced so that overwritten products are
+// reindexed rather than rejected as duplicates. Indexing outlives ctx,
+// which may belong to a call that returns before it finishes.
+func (b *bulkIndexer) index(ctx context.Context, products []*Product) {
+	ctx = context.WithoutCancel(ctx)
+	b.sem <- struct{}{}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer func() { <-b.sem }()
+
+		b.r.indexProducts(ctx, products, redisearch.IndexingOptions{Replace: true})
+		b.r.addSuggestions(ctx, products)
+	}()
+}
+
+// wait blocks until every submitted batch is indexed.
+func (b *bulkIndexer) wait() {
+	b.wg.Wait()
+}
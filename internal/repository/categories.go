@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+	"go.uber.org/zap"
+)
+
+// maxAggregatedCategories bounds the rows returned by the category
+// aggregation; FT.AGGREGATE otherwise returns only 10.
+const maxAggregatedCategories = 10000
+
+// CategoryCount is the number of products in a category.
+type CategoryCount struct {
+	Category string
+	Count    int64
+}
+
+// categoryCache holds the most recent ListCategories result for ttl. An
+// eager cache never expires; it is kept current by RefreshCategoriesEvery
+// and by refreshes triggered when a product introduces a new category.
+type categoryCache struct {
+	ttl   time.Duration
+	eager bool
+
+	// refreshing collapses concurrent refreshes triggered by writes.
+	refreshing atomic.Bool
+
+	mu        sync.Mutex
+	counts    []CategoryCount
+	expiresAt time.Time
+}
+
+func (c *categoryCache) get() ([]CategoryCount, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil || (!c.eager && time.Now().After(c.expiresAt)) {
+		return nil, false
+	}
+	return c.counts, true
+}
+
+func (c *categoryCache) set(counts []CategoryCount) {
+	if c.ttl <= 0 && !c.eager {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts = counts
+	c.expiresAt = time.Now().Add(c.ttl)
+}
+
+// isNew reports whether an eager cache has been loaded and does not list
+// category yet.
+func (c *categoryCache) isNew(category string) bool {
+	if !c.eager {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		return false
+	}
+	for _, count := range c.counts {
+		if count.Category == category {
+			return false
+		}
+	}
+	return true
+}
+
+// ListCategories returns every category with its product count, ordered by
+// category name. Results are cached for the configured TTL since both the
+// aggregation and the fallback scan touch the whole catalog.
+func (r *RedisRepository) ListCategories(ctx context.Context) ([]CategoryCount, error) {
+	if counts, ok := r.categories.get(); ok {
+		return counts, nil
+	}
+
+	counts, err := r.loadCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.categories.set(counts)
+	return counts, nil
+}
+
+// RefreshCategories recomputes the category counts and replaces the cached
+// list.
+func (r *RedisRepository) RefreshCategories(ctx context.Context) error {
+	counts, err := r.loadCategories(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.categories.set(counts)
+	return nil
+}
+
+// RefreshCategoriesEvery refreshes the cached category list every interval
+// until ctx is cancelled.
+func (r *RedisRepository) RefreshCategoriesEvery(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		r.logger.Warn("Periodic category refresh disabled", zap.Duration("interval", interval))
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.RefreshCategories(ctx); err != nil {
+			r.logger.Warn("Failed to refresh categories", zap.Error(err))
+		}
+	}
+}
+
+// noteCategories refreshes an eager category cache in the background when
+// any of products belongs to a category it doesn't list yet.
+func (r *RedisRepository) noteCategories(products ...*Product) {
+	for _, product := range products {
+		if !r.categories.isNew(product.Category) {
+			continue
+		}
+		if !r.categories.refreshing.CompareAndSwap(false, true) {
+			return
+		}
+
+		go func() {
+			defer r.categories.refreshing.Store(false)
+			if err := r.RefreshCategories(context.Background()); err != nil {
+				r.logger.Warn("Failed to refresh categories", zap.Error(err))
+			}
+		}()
+		return
+	}
+}
+
+func (r *RedisRepository) loadCategories(ctx context.Context) ([]CategoryCount, error) {
+	var (
+		counts []CategoryCount
+		err    error
+	)
+	if r.searchEnabled && r.search != nil {
+		counts, err = r.aggregateCategories()
+	} else {
+		counts, err = r.tallyCategories(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Category < counts[j].Category
+	})
+	return counts, nil
+}
+
+func (r *RedisRepository) aggregateCategories() ([]CategoryCount, error) {
+	query := redisearch.NewAggregateQuery().
+		SetQuery(redisearch.NewQuery("*")).
+		Load([]string{"@category"}).
+		GroupBy(*redisearch.NewGroupBy().
+			AddFields("@category").
+			Reduce(*redisearch.NewReducerAlias(redisearch.GroupByReducerCount, []string{}, "count"))).
+		Limit(0, maxAggregatedCategories)
+
+	_, rows, err := r.search.AggregateQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("category aggregation failed: %w", err)
+	}
+
+	counts := make([]CategoryCount, 0, len(rows))
+	for _, row := range rows {
+		category, _ := row["category"].(string)
+		value, _ := row["count"].(string)
+		count, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid category count %q: %w", value, err)
+		}
+		counts = append(counts, CategoryCount{Category: category, Count: count})
+	}
+
+	return counts, nil
+}
+
+func (r *RedisRepository) tallyCategories(ctx context.Context) ([]CategoryCount, error) {
+	tally := make(map[string]int64)
+
+	err := r.StreamAll(ctx, func(product *Product) error {
+		tally[product.Category]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]CategoryCount, 0, len(tally))
+	for category, count := range tally {
+		counts = append(counts, CategoryCount{Category: category, Count: count})
+	}
+	return counts, nil
+}
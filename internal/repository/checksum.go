@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// CatalogChecksum XORs together a SHA-256 digest of every product, so the
+// result does not depend on SCAN order and is computed one batch at a time.
+// Products are re-encoded before hashing so that two stores holding the same
+// data produce the same checksum even if their stored JSON differs in layout.
+func (r *RedisRepository) CatalogChecksum(ctx context.Context) (string, int64, error) {
+	var checksum [sha256.Size]byte
+	var count int64
+
+	err := r.StreamAll(ctx, func(product *Product) error {
+		data, err := json.Marshal(product)
+		if err != nil {
+			return fmt.Errorf("failed to marshal product %s: %w", product.ID, err)
+		}
+
+		digest := sha256.Sum256(data)
+		for i := range checksum {
+			checksum[i] ^= digest[i]
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(checksum[:]), count, nil
+}
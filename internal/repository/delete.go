@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DeleteProductsByCategory removes every product in category, matched like
+// the category filter, together with its search index entry and SKU
+// mapping, and returns how many were removed. Each scan batch is deleted in
+// one pipeline. Name suggestions are removed unless a remaining product
+// shares the name.
+func (r *RedisRepository) DeleteProductsByCategory(ctx context.Context, category string) (int32, error) {
+	tag := categoryTag(category)
+
+	var deleted int32
+	var names []string
+	err := r.scanProductKeys(ctx, func(keys []string) error {
+		products, err := r.fetchProducts(ctx, keys)
+		if err != nil {
+			return err
+		}
+
+		var matched []*Product
+		for _, product := range products {
+			if categoryTag(product.Category) == tag {
+				matched = append(matched, product)
+			}
+		}
+		if len(matched) == 0 {
+			return nil
+		}
+
+		cmds := make([]*redis.IntCmd, len(matched))
+		if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, product := range matched {
+				cmds[i] = pipe.Del(ctx, r.keyFor(product.ID))
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to delete products: %w", err)
+		}
+
+		for i, product := range matched {
+			// A product deleted concurrently isn't counted twice
+			if cmds[i].Val() == 0 {
+				continue
+			}
+			deleted++
+			r.products.invalidate(product.ID)
+			r.unindexProduct(ctx, product.ID)
+			r.releaseSKU(ctx, product.SKU, product.ID)
+			names = append(names, product.Name)
+		}
+		return nil
+	})
+	if deleted > 0 {
+		r.stats.clear()
+		r.refreshCategoriesAfterDelete(ctx)
+		r.removeSuggestions(ctx, names)
+	}
+	return deleted, err
+}
+
+// unindexProduct removes a deleted product from the search index, if there
+// is one. Failures are logged; the product itself is already gone.
+func (r *RedisRepository) unindexProduct(ctx context.Context, id string) {
+	if !r.searchEnabled || r.search == nil {
+		return
+	}
+
+	if err := r.search.DeleteDocument(r.keyFor(id)); err != nil {
+		r.log(ctx).Warn("Failed to remove product from search index", zap.String("id", id), zap.Error(err))
+	}
+}
+
+// refreshCategoriesAfterDelete recomputes a cached category list, which
+// would otherwise keep counting the deleted products.
+func (r *RedisRepository) refreshCategoriesAfterDelete(ctx context.Context) {
+	if r.categories.ttl <= 0 && !r.categories.eager {
+		return
+	}
+	if err := r.RefreshCategories(ctx); err != nil {
+		r.log(ctx).Warn("Failed to refresh categories after delete", zap.Error(err))
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeleteProductsByCategory(t *testing.T) {
+	repo, _ := newTestRepository(t, func(o *Options) {
+		o.StatsCacheTTL = time.Minute
+		o.SuggestionsCacheTTL = time.Minute
+	})
+	ctx := context.Background()
+	for _, product := range []*Product{
+		{ID: "p1", Name: "Laptop", Category: "Electronics", Price: 1000, Currency: "USD"},
+		{ID: "p2", Name: "Lamp", Category: "electronics ", Price: 30, Currency: "USD"},
+		{ID: "p3", Name: "Lamp", Category: "Home", Price: 40, Currency: "USD"},
+	} {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct(%s): %v", product.ID, err)
+		}
+	}
+
+	// Fill the stats and name caches before the delete.
+	if _, err := repo.CatalogStats(ctx); err != nil {
+		t.Fatalf("CatalogStats: %v", err)
+	}
+	if _, err := repo.SuggestProducts(ctx, "La", 5); err != nil {
+		t.Fatalf("SuggestProducts: %v", err)
+	}
+
+	deleted, err := repo.DeleteProductsByCategory(ctx, "ELECTRONICS")
+	if err != nil {
+		t.Fatalf("DeleteProductsByCategory: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted %d products, want 2", deleted)
+	}
+
+	stats, err := repo.CatalogStats(ctx)
+	if err != nil {
+		t.Fatalf("CatalogStats: %v", err)
+	}
+	if len(stats.Categories) != 1 || stats.Categories[0].Category != "Home" {
+		t.Errorf("stats after delete = %+v, want only Home", stats.Categories)
+	}
+
+	// Lamp is still the name of the Home product.
+	suggestions, err := repo.SuggestProducts(ctx, "La", 5)
+	if err != nil {
+		t.Fatalf("SuggestProducts: %v", err)
+	}
+	if want := []string{"Lamp"}; !reflect.DeepEqual(suggestions, want) {
+		t.Errorf("suggestions after delete = %q, want %q", suggestions, want)
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ProductEnricher adjusts a product before CreateProduct or UpdateProduct
+// stores it, e.g. to derive fields or normalize values. Returning an error
+// aborts the write.
+type ProductEnricher interface {
+	Enrich(ctx context.Context, product *Product) error
+}
+
+// Built-in enrichers selectable by name.
+const (
+	EnricherNone = "none"
+	EnricherSlug = "slug"
+)
+
+// NewProductEnricher returns the built-in enricher with the given name.
+func NewProductEnricher(name string) (ProductEnricher, error) {
+	switch name {
+	case "", EnricherNone:
+		return NoopEnricher{}, nil
+	case EnricherSlug:
+		return SlugEnricher{}, nil
+	default:
+		return nil, fmt.Errorf("unknown product enricher %q (expected %q or %q)", name, EnricherNone, EnricherSlug)
+	}
+}
+
+// NoopEnricher leaves products unchanged.
+type NoopEnricher struct{}
+
+func (NoopEnricher) Enrich(context.Context, *Product) error { return nil }
+
+// SlugEnricher sets Slug to a URL-friendly form of the product name, such
+// as "wireless-mouse" for "Wireless Mouse".
+type SlugEnricher struct{}
+
+func (SlugEnricher) Enrich(_ context.Context, product *Product) error {
+	product.Slug = slugify(product.Name)
+	return nil
+}
+
+// slugify lowercases s and joins its runs of letters and digits with
+// hyphens.
+func slugify(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// SetEnricher replaces the enricher applied by CreateProduct and
+// UpdateProduct, e.g. with a deployment-specific implementation. It must be
+// called before the repository serves requests.
+func (r *RedisRepository) SetEnricher(enricher ProductEnricher) {
+	r.enricher = enricher
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+var (
+	fetchMisses      metric.Int64Counter
+	searchDuplicates metric.Int64Counter
+)
+
+func init() {
+	meter := otel.Meter("products-service")
+	var err error
+
+	fetchMisses, err = meter.Int64Counter(
+		"products_fetch_misses_total",
+		metric.WithDescription("Number of keys skipped by batch product fetches, by reason (missing or corrupt)"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	searchDuplicates, err = meter.Int64Counter(
+		"products_search_duplicates_total",
+		metric.WithDescription("Number of repeated documents found on search result pages"),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// mgetProducts fetches the products stored under keys, in order. Keys that
+// no longer exist, for example because they expired between a search and
+// the fetch, and values that can't be decoded are skipped and counted as
+// misses rather than failing the batch.
+func (r *RedisRepository) mgetProducts(ctx context.Context, keys []string) ([]*Product, error) {
+	values, err := r.getValues(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+	if len(values) != len(keys) {
+		return nil, fmt.Errorf("failed to get products: got %d values for %d keys", len(values), len(keys))
+	}
+
+	var missing, corrupt int64
+	products := make([]*Product, 0, len(values))
+	for i, value := range values {
+		var data []byte
+		switch v := value.(type) {
+		case nil:
+			missing++
+			continue
+		case string:
+			data = []byte(v)
+		case []byte:
+			data = v
+		default:
+			corrupt++
+			r.log(ctx).Warn("Unexpected product value type",
+				zap.String("key", keys[i]),
+				zap.String("type", fmt.Sprintf("%T", value)),
+			)
+			continue
+		}
+
+		product, _, err := decodeProduct(data)
+		if err != nil {
+			corrupt++
+			r.log(ctx).Warn("Failed to unmarshal product", zap.String("key", keys[i]), zap.Error(err))
+			continue
+		}
+
+		products = append(products, product)
+	}
+
+	if missing > 0 {
+		fetchMisses.Add(ctx, missing, metric.WithAttributes(attribute.String("reason", "missing")))
+		r.log(ctx).Debug("Skipped missing products in batch fetch", zap.Int64("count", missing))
+	}
+	if corrupt > 0 {
+		fetchMisses.Add(ctx, corrupt, metric.WithAttributes(attribute.String("reason", "corrupt")))
+	}
+
+	return products, nil
+}
+
+// getValues returns the value of each key, or nil for missing keys, like
+// MGET. Keys in a cluster usually live in different slots, which MGET
+// rejects, so there the GETs are pipelined instead.
+func (r *RedisRepository) getValues(ctx context.Context, keys []string) ([]interface{}, error) {
+	if !r.isCluster() {
+		return r.client.MGet(ctx, keys...).Result()
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, cmd := range cmds {
+		if value, err := cmd.Result(); err == nil {
+			values[i] = value
+		}
+	}
+	return values, nil
+}
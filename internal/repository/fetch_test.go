@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFetchProductsMatchesPerKeyGets(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	createTestProducts(t, repo, 25)
+
+	// A shuffled order stands in for the order the search index returns.
+	ids := make([]string, 25)
+	for i := range ids {
+		ids[i] = createTestID(i)
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	keys := make([]string, len(ids))
+	want := make([]*Product, len(ids))
+	for i, id := range ids {
+		keys[i] = repo.keyFor(id)
+		product, err := repo.GetProduct(ctx, id)
+		if err != nil {
+			t.Fatalf("GetProduct(%s): %v", id, err)
+		}
+		want[i] = product
+	}
+
+	for _, tc := range []struct {
+		name        string
+		batchSize   int
+		parallelism int
+	}{
+		{name: "single MGET", batchSize: 0, parallelism: 1},
+		{name: "sequential chunks", batchSize: 4, parallelism: 1},
+		{name: "parallel chunks", batchSize: 3, parallelism: 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo.mgetBatchSize = tc.batchSize
+			repo.mgetParallelism = tc.parallelism
+
+			got, err := repo.fetchProducts(ctx, keys)
+			if err != nil {
+				t.Fatalf("fetchProducts: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("fetched %d products, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i].ID != want[i].ID || got[i].Price != want[i].Price {
+					t.Errorf("product %d = %s (%v), want %s (%v)", i, got[i].ID, got[i].Price, want[i].ID, want[i].Price)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFetchProducts(b *testing.B) {
+	repo, _ := newTestRepository(b)
+	ctx := context.Background()
+	createTestProducts(b, repo, 100)
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = repo.keyFor(createTestID(i))
+	}
+
+	b.Run("MGET", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.fetchProducts(ctx, keys); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("GET per key", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, key := range keys {
+				if err := repo.client.Get(ctx, key).Err(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func TestMGetProductsSkipsMissingAndCorruptValues(t *testing.T) {
+	repo, server := newTestRepository(t)
+	ctx := context.Background()
+	createTestProducts(t, repo, 3)
+
+	// A key deleted between search and fetch, one holding undecodable JSON
+	// and one of the wrong type, interleaved with stored products.
+	server.Set(repo.keyFor("corrupt"), "{not json")
+	server.HSet(repo.keyFor("hash"), "name", "Not a string value")
+	keys := []string{
+		repo.keyFor(createTestID(0)),
+		repo.keyFor("missing"),
+		repo.keyFor(createTestID(1)),
+		repo.keyFor("corrupt"),
+		repo.keyFor("hash"),
+		repo.keyFor(createTestID(2)),
+	}
+
+	products, err := repo.mgetProducts(ctx, keys)
+	if err != nil {
+		t.Fatalf("mgetProducts: %v", err)
+	}
+	want := []string{createTestID(0), createTestID(1), createTestID(2)}
+	if got := productIDs(products); !reflect.DeepEqual(got, want) {
+		t.Errorf("mgetProducts = %q, want %q", got, want)
+	}
+}
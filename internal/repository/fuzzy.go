@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fuzzyMaxDistance is the edit distance tolerated per term. It matches the
+// single % of RediSearch's fuzzy syntax so both list paths agree.
+const fuzzyMaxDistance = 1
+
+// fuzzyMinTermLength skips fuzzy matching for short terms, which would
+// otherwise match most of the catalog.
+const fuzzyMinTermLength = 3
+
+// fuzzySearchQuery wraps every plain term of query in RediSearch's
+// Levenshtein syntax (%term%). Terms containing query syntax are left as
+// they are.
+func fuzzySearchQuery(query string) string {
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		if isFuzzyTerm(term) {
+			terms[i] = "%" + term + "%"
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+func isFuzzyTerm(term string) bool {
+	if len([]rune(term)) < fuzzyMinTermLength {
+		return false
+	}
+	for _, r := range term {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// fuzzyContains reports whether every term of query is within
+// fuzzyMaxDistance edits of some word of text, ignoring case. It is the scan
+// fallback's equivalent of fuzzySearchQuery.
+func fuzzyContains(text, query string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		matched := false
+		for _, word := range words {
+			if word == term || (isFuzzyTerm(term) && editDistance(word, term) <= fuzzyMaxDistance) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Highlight tags wrap matched search terms in highlighted snippets, as
+// RediSearch does with HIGHLIGHT TAGS.
+const (
+	highlightOpenTag  = "<b>"
+	highlightCloseTag = "</b>"
+)
+
+// highlightFields are the text fields highlighted in search results.
+var highlightFields = []string{"name", "description"}
+
+// highlightTerms wraps every case-insensitive occurrence of a term of query
+// in text with the highlight tags. It approximates RediSearch highlighting
+// for listings that don't go through the index, matching substrings rather
+// than stemmed words.
+func highlightTerms(text, query string) string {
+	terms := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) == 0 || text == "" {
+		return text
+	}
+
+	// Mark the matched bytes first so overlapping terms produce one span.
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Lowercasing changed byte offsets; matching on them would split
+		// characters.
+		return text
+	}
+	matched := make([]bool, len(text))
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(term); j++ {
+				matched[j] = true
+			}
+			start += i + len(term)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if matched[i] && (i == 0 || !matched[i-1]) {
+			b.WriteString(highlightOpenTag)
+		}
+		b.WriteByte(text[i])
+		if matched[i] && (i == len(text)-1 || !matched[i+1]) {
+			b.WriteString(highlightCloseTag)
+		}
+	}
+	return b.String()
+}
+
+// highlightProduct fills in the highlighted name and description of a
+// product listed without the index.
+func highlightProduct(product *Product, query string) {
+	product.HighlightedName = highlightTerms(product.Name, query)
+	product.HighlightedDescription = highlightTerms(product.Description, query)
+}
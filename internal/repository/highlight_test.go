@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHighlightTerms(t *testing.T) {
+	for _, tc := range []struct {
+		name, text, query, want string
+	}{
+		{name: "word", text: "Gaming Laptop", query: "laptop", want: "Gaming <b>Laptop</b>"},
+		{name: "substring", text: "Laptops", query: "laptop", want: "<b>Laptop</b>s"},
+		{name: "every occurrence", text: "laptop and laptop bag", query: "LAPTOP", want: "<b>laptop</b> and <b>laptop</b> bag"},
+		{name: "several terms", text: "Wireless mouse", query: "mouse wireless", want: "<b>Wireless</b> <b>mouse</b>"},
+		{name: "overlapping terms make one span", text: "notebook", query: "note book", want: "<b>notebook</b>"},
+		{name: "no match", text: "Desk lamp", query: "laptop", want: "Desk lamp"},
+		{name: "punctuation only", text: "Desk lamp", query: "*", want: "Desk lamp"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := highlightTerms(tc.text, tc.query); got != tc.want {
+				t.Errorf("highlightTerms(%q, %q) = %q, want %q", tc.text, tc.query, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestListProductsHighlightsWithoutIndex(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	for _, product := range []*Product{
+		{ID: "p1", Name: "Gaming Laptop", Description: "A fast laptop", Category: "Computers", Price: 999},
+		{ID: "p2", Name: "Sleeve", Description: "Fits a Laptop", Category: "Bags", Price: 15},
+	} {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	result, err := repo.ListProducts(ctx, ListOptions{SearchQuery: "laptop", Highlight: true})
+	if err != nil {
+		t.Fatalf("ListProducts: %v", err)
+	}
+	want := map[string][2]string{
+		"p1": {"Gaming <b>Laptop</b>", "A fast <b>laptop</b>"},
+		"p2": {"Sleeve", "Fits a <b>Laptop</b>"},
+	}
+	if len(result.Products) != len(want) {
+		t.Fatalf("listed %v, want p1 and p2", productIDs(result.Products))
+	}
+	for _, product := range result.Products {
+		got := [2]string{product.HighlightedName, product.HighlightedDescription}
+		if got != want[product.ID] {
+			t.Errorf("%s highlighted as %q, want %q", product.ID, got, want[product.ID])
+		}
+		if strings.Contains(product.Name, highlightOpenTag) || strings.Contains(product.Description, highlightOpenTag) {
+			t.Errorf("%s stored fields were highlighted: %q, %q", product.ID, product.Name, product.Description)
+		}
+	}
+
+	// Without the option nothing is highlighted.
+	result, err = repo.ListProducts(ctx, ListOptions{SearchQuery: "laptop"})
+	if err != nil {
+		t.Fatalf("ListProducts: %v", err)
+	}
+	for _, product := range result.Products {
+		if product.HighlightedName != "" || product.HighlightedDescription != "" {
+			t.Errorf("%s highlighted without the option: %q, %q", product.ID, product.HighlightedName, product.HighlightedDescription)
+		}
+	}
+}
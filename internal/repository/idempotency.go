@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	idempotencyKeyPrefix = "idempotency:"
+	dedupKeyPrefix       = "dedup:"
+
+	// idempotencyWait bounds how long a retried create waits for a
+	// concurrent create with the same key to store its product.
+	idempotencyWait     = 2 * time.Second
+	idempotencyInterval = 50 * time.Millisecond
+)
+
+// ErrIdempotencyInProgress is returned when a create with the same
+// idempotency key, or the same content, is still in flight.
+var ErrIdempotencyInProgress = errors.New("create with this idempotency key is in progress")
+
+// CreateProductIdempotent creates product unless a create with the same
+// idempotency key succeeded within the key TTL, in which case it returns the
+// product that create stored instead. The key is claimed with SET NX, so of
+// concurrent creates with one key only the first stores a product.
+//
+// Without an idempotency key, creates are deduplicated by content instead
+// when a dedup window is configured: a product with the same name, category,
+// price, currency and SKU as one created within the window returns that
+// product.
+// Otherwise the product is created unconditionally.
+func (r *RedisRepository) CreateProductIdempotent(ctx context.Context, idempotencyKey string, product *Product) (*Product, error) {
+	switch {
+	case idempotencyKey != "":
+		return r.createOnce(ctx, idempotencyKeyPrefix+idempotencyKey, r.idempotencyTTL, product)
+	case r.dedupWindow > 0:
+		return r.createOnce(ctx, dedupKeyPrefix+contentDigest(product), r.dedupWindow, product)
+	}
+
+	if err := r.CreateProduct(ctx, product); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// contentDigest identifies a create request by its normalized name,
+// category, price, currency and SKU.
+func contentDigest(product *Product) string {
+	currency := product.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	normalized := strings.Join([]string{
+		strings.ToLower(strings.Join(strings.Fields(product.Name), " ")),
+		strings.ToLower(strings.TrimSpace(product.Category)),
+		strconv.FormatFloat(product.Price, 'f', -1, 64),
+		currency,
+		product.SKU,
+	}, "\x00")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// createOnce creates product unless key was already claimed within ttl, in
+// which case it returns the product created under the key.
+func (r *RedisRepository) createOnce(ctx context.Context, key string, ttl time.Duration, product *Product) (*Product, error) {
+	if product.ID == "" {
+		product.ID = newProductID()
+	}
+
+	claimed, err := r.client.SetNX(ctx, key, product.ID, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim %s: %w", key, err)
+	}
+
+	if claimed {
+		if err := r.CreateProduct(ctx, product); err != nil {
+			// Release the key so that a retry can create the product.
+			if delErr := r.client.Del(ctx, key).Err(); delErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to release %s: %w", key, delErr))
+			}
+			return nil, err
+		}
+		return product, nil
+	}
+
+	return r.awaitIdempotentProduct(ctx, key)
+}
+
+// awaitIdempotentProduct returns the product created under a claimed key,
+// waiting up to idempotencyWait for a concurrent create to store it.
+func (r *RedisRepository) awaitIdempotentProduct(ctx context.Context, key string) (*Product, error) {
+	deadline := time.Now().Add(idempotencyWait)
+	for {
+		id, err := r.client.Get(ctx, key).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+			// The other create failed and released the key.
+			return nil, ErrIdempotencyInProgress
+		case err != nil:
+			return nil, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+
+		product, err := r.GetProduct(ctx, id)
+		if err == nil {
+			return product, nil
+		}
+		if !errors.Is(err, ErrProductNotFound) {
+			return nil, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrIdempotencyInProgress
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(idempotencyInterval):
+		}
+	}
+}
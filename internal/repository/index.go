@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+	"go.uber.org/zap"
+)
+
+// missingIndexFields returns the fields of want that the live index, with
+// fields have, lacks. They can be added to it with FT.ALTER.
+func missingIndexFields(want, have []redisearch.Field) []redisearch.Field {
+	present := indexFieldNames(have)
+	var missing []redisearch.Field
+	for _, field := range want {
+		if !present[field.Name] {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// indexFieldNames returns the set of the names of fields.
+func indexFieldNames(fields []redisearch.Field) map[string]bool {
+	names := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		names[field.Name] = true
+	}
+	return names
+}
+
+// addIndexFields adds fields to the existing index with FT.ALTER and
+// reindexes the catalog in the background so that products stored before
+// gain them. Fields that can't be added are logged and skipped; the names
+// of those added are returned.
+func (r *RedisRepository) addIndexFields(fields []redisearch.Field) []string {
+	var added []string
+	for _, field := range fields {
+		if err := r.search.AddField(field); err != nil {
+			r.logger.Warn("Failed to add field to the search index", zap.String("field", field.Name), zap.Error(err))
+			continue
+		}
+		added = append(added, field.Name)
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	r.logger.Info("Added fields to the search index", zap.Strings("fields", added))
+	r.repopulateIndex()
+	return added
+}
+
+// repopulateIndex reindexes every stored product in the background after
+// the index schema changed. Until it finishes, searches on the changed
+// fields only see the products reindexed so far.
+func (r *RedisRepository) repopulateIndex() {
+	go func() {
+		err := r.Reindex(context.Background(), true, func(ReindexProgress) error { return nil })
+		if err != nil {
+			r.logger.Error("Failed to repopulate search index", zap.Error(err))
+			return
+		}
+		r.logger.Info("Repopulated search index")
+	}()
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+)
+
+func TestMissingIndexFields(t *testing.T) {
+	want := []redisearch.Field{
+		redisearch.NewSortableTextField(SortByName, 2),
+		redisearch.NewSortableNumericField(SortByPrice),
+		redisearch.NewSortableNumericField(SortByCreatedAt),
+		redisearch.NewSortableNumericField(SortByUpdatedAt),
+	}
+
+	for _, tc := range []struct {
+		name string
+		have []redisearch.Field
+		want []string
+	}{
+		{
+			name: "up to date",
+			have: want,
+		},
+		{
+			name: "original schema",
+			have: []redisearch.Field{
+				redisearch.NewTextField(SortByName),
+				redisearch.NewNumericField(SortByPrice),
+			},
+			want: []string{SortByCreatedAt, SortByUpdatedAt},
+		},
+		{
+			name: "empty",
+			want: []string{SortByName, SortByPrice, SortByCreatedAt, SortByUpdatedAt},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, field := range missingIndexFields(want, tc.have) {
+				got = append(got, field.Name)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("missingIndexFields = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTagCategoryIndex(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		fields []redisearch.Field
+		want   bool
+	}{
+		{
+			name:   "text category",
+			fields: []redisearch.Field{redisearch.NewTextField("name"), redisearch.NewTextField("category")},
+		},
+		{
+			name: "text category with tag copy",
+			fields: []redisearch.Field{
+				redisearch.NewTextField("category"),
+				redisearch.NewTagField(categoryTagField),
+			},
+		},
+		{
+			name:   "tag category",
+			fields: []redisearch.Field{redisearch.NewTextField("name"), redisearch.NewTagField("category")},
+			want:   true,
+		},
+		{
+			name:   "no category",
+			fields: []redisearch.Field{redisearch.NewTextField("name")},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tagCategoryIndex(tc.fields); got != tc.want {
+				t.Errorf("tagCategoryIndex = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIndexSortField(t *testing.T) {
+	// An index created before created_at and updated_at were added
+	repo := &RedisRepository{indexFields: map[string]bool{
+		SortByName:  true,
+		SortByPrice: true,
+		SortByStock: true,
+	}}
+
+	for _, tc := range []struct {
+		sortBy string
+		want   string
+		ok     bool
+	}{
+		{sortBy: "", want: SortByPrice, ok: true},
+		{sortBy: SortByName, want: SortByName, ok: true},
+		{sortBy: SortByStock, want: SortByStock, ok: true},
+		{sortBy: SortByCreatedAt},
+		{sortBy: "description"},
+	} {
+		field, err := repo.indexSortField(tc.sortBy)
+		if !tc.ok {
+			if !errors.Is(err, ErrUnsortableField) {
+				t.Errorf("indexSortField(%q) error = %v, want ErrUnsortableField", tc.sortBy, err)
+			}
+			continue
+		}
+		if err != nil || field.name != tc.want {
+			t.Errorf("indexSortField(%q) = %q, %v, want %q", tc.sortBy, field.name, err, tc.want)
+		}
+	}
+
+	// Without FT.INFO every known field is accepted
+	repo.indexFields = nil
+	if _, err := repo.indexSortField(SortByCreatedAt); err != nil {
+		t.Errorf("indexSortField(%q) with an unknown schema: %v", SortByCreatedAt, err)
+	}
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// memoryIndex is an in-memory inverted index over product names,
+// descriptions and categories that lets the fallback list path avoid a full
+// keyspace scan when RediSearch is unavailable. It only narrows the set of
+// candidate products; callers still apply matchesFilters to the fetched
+// products, so stale entries (e.g. for expired keys) are harmless.
+//
+// Terms are split on whitespace only, so every whitespace-free substring of
+// a name or description lies within a single term and substring searches
+// can be answered from the term dictionary.
+type memoryIndex struct {
+	maxProducts int
+
+	mu         sync.RWMutex
+	products   map[string]struct{}
+	terms      map[string]map[string]struct{}
+	categories map[string]map[string]struct{}
+	// full is set once maxProducts is exceeded; the index then stops
+	// accepting products and declines every query.
+	full bool
+}
+
+func newMemoryIndex(maxProducts int) *memoryIndex {
+	return &memoryIndex{
+		maxProducts: maxProducts,
+		products:    make(map[string]struct{}),
+		terms:       make(map[string]map[string]struct{}),
+		categories:  make(map[string]map[string]struct{}),
+	}
+}
+
+// buildMemoryIndex indexes every stored product. The index is dropped if the
+// catalog is larger than maxProducts.
+func (r *RedisRepository) buildMemoryIndex(ctx context.Context, maxProducts int) {
+	index := newMemoryIndex(maxProducts)
+
+	err := r.StreamAll(ctx, func(product *Product) error {
+		index.add(product)
+		if index.isFull() {
+			return errStopScan
+		}
+		return nil
+	})
+	switch {
+	case index.isFull():
+		r.logger.Warn("Catalog exceeds in-memory index bound; fallback searches will scan Redis",
+			zap.Int("max_products", maxProducts),
+		)
+	case err != nil:
+		r.logger.Warn("Failed to build in-memory index", zap.Error(err))
+	default:
+		r.memIndex = index
+		r.logger.Info("Built in-memory product index", zap.Int("count", index.size()))
+	}
+}
+
+func (m *memoryIndex) add(product *Product) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.full {
+		return
+	}
+	if _, ok := m.products[product.ID]; !ok && len(m.products) >= m.maxProducts {
+		m.full = true
+		m.products, m.terms, m.categories = nil, nil, nil
+		return
+	}
+	m.products[product.ID] = struct{}{}
+
+	for _, term := range strings.Fields(strings.ToLower(product.Name + " " + product.Description)) {
+		addPosting(m.terms, term, product.ID)
+	}
+	addPosting(m.categories, categoryTag(product.Category), product.ID)
+}
+
+func addPosting(postings map[string]map[string]struct{}, key, id string) {
+	ids, ok := postings[key]
+	if !ok {
+		ids = make(map[string]struct{})
+		postings[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (m *memoryIndex) isFull() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.full
+}
+
+func (m *memoryIndex) size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.products)
+}
+
+// candidates returns the IDs of products that may match the category and
+// search filters of opts. ok is false when the index can't narrow the
+// query (no category or search term, fuzzy matching, or a full index) and
+// the caller should scan instead.
+func (m *memoryIndex) candidates(opts ListOptions) (ids []string, ok bool) {
+	if m == nil || opts.Fuzzy || (opts.Category == "" && opts.SearchQuery == "") {
+		return nil, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.full {
+		return nil, false
+	}
+
+	var matched map[string]struct{}
+	if opts.Category != "" {
+		matched = copyIDs(m.categories[categoryTag(opts.Category)])
+	}
+
+	for _, word := range strings.Fields(strings.ToLower(opts.SearchQuery)) {
+		union := make(map[string]struct{})
+		for term, termIDs := range m.terms {
+			if !strings.Contains(term, word) {
+				continue
+			}
+			for id := range termIDs {
+				union[id] = struct{}{}
+			}
+		}
+		matched = intersectIDs(matched, union)
+	}
+
+	ids = make([]string, 0, len(matched))
+	for id := range matched {
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+func copyIDs(ids map[string]struct{}) map[string]struct{} {
+	out := make(map[string]struct{}, len(ids))
+	for id := range ids {
+		out[id] = struct{}{}
+	}
+	return out
+}
+
+// intersectIDs returns the IDs in both sets, treating a nil a as the
+// universe.
+func intersectIDs(a, b map[string]struct{}) map[string]struct{} {
+	if a == nil {
+		return b
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			delete(a, id)
+		}
+	}
+	return a
+}
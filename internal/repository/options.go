@@ -0,0 +1,95 @@
+package repository
+
+import "time"
+
+// Redis connection modes.
+const (
+	RedisModeSingle   = "single"
+	RedisModeCluster  = "cluster"
+	RedisModeSentinel = "sentinel"
+)
+
+// Options configure NewRedisRepository. Zero values disable the optional
+// caches and features they control.
+type Options struct {
+	// RedisMode is single, cluster or sentinel. RedisAddrs lists the node
+	// (single), seed nodes (cluster) or Sentinels (sentinel);
+	// RedisMasterName names the Sentinel master.
+	RedisMode       string
+	RedisAddrs      []string
+	RedisMasterName string
+	// ClientName shows up in CLIENT LIST so connections can be attributed.
+	ClientName string
+
+	// PoolSize is the connection pool size per node; zero keeps the
+	// go-redis default of 10 per CPU. MaxRetries of -1 disables retries.
+	PoolSize     int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	MaxRetries   int
+
+	// SearchAddr is the RediSearch endpoint when it is not served by Redis
+	// itself. Empty uses the Redis node.
+	SearchAddr string
+
+	// NotifyExpirations enables expired-key notifications on startup for
+	// WatchExpirations.
+	NotifyExpirations bool
+
+	// MGetBatchSize splits batch fetches into MGETs of at most this many
+	// keys, at most MGetParallelism of them in flight.
+	MGetBatchSize   int
+	MGetParallelism int
+
+	CategoriesCacheTTL time.Duration
+	// CategoriesEager loads the category list at startup and keeps it in
+	// memory instead of expiring it after CategoriesCacheTTL.
+	CategoriesEager     bool
+	StatsCacheTTL       time.Duration
+	SuggestionsCacheTTL time.Duration
+
+	// ProductCacheSize enables an LRU of this many products in front of
+	// GetProduct.
+	ProductCacheSize int
+	ProductCacheTTL  time.Duration
+
+	// MemoryIndexEnabled builds an in-memory index for filtering when
+	// RediSearch is unavailable, unless the catalog exceeds
+	// MemoryIndexMaxProducts.
+	MemoryIndexEnabled     bool
+	MemoryIndexMaxProducts int
+
+	// SeedEnabled tops the catalog up to SeedTargetCount products on
+	// startup. SeedUpsertBase also overwrites changed base seed products.
+	SeedEnabled     bool
+	SeedTargetCount int
+	SeedUpsertBase  bool
+
+	// SchemaRewrite stores products upgraded on read back to Redis.
+	SchemaRewrite bool
+
+	// ReindexRate throttles Reindex to this many products per second; zero
+	// means unthrottled.
+	ReindexRate float64
+	// RecreateIndex drops and rebuilds a search index with an outdated
+	// schema.
+	RecreateIndex bool
+	// DedupResults drops repeated documents from search result pages.
+	DedupResults bool
+	// ListAllWithSearch serves unfiltered listings from the search index
+	// rather than a keyspace scan.
+	ListAllWithSearch bool
+	// IndexConcurrency bounds how many bulk-written batches are indexed at
+	// once.
+	IndexConcurrency int
+
+	// Enricher names the built-in ProductEnricher: none or slug.
+	Enricher string
+
+	// IdempotencyKeyTTL is how long an idempotency key maps to the product
+	// created with it. CreateDedupWindow is how long creates without a key
+	// return an earlier product with the same content; zero disables it.
+	IdempotencyKeyTTL time.Duration
+	CreateDedupWindow time.Duration
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// pageToken is the decoded form of the opaque ListProducts page token.
+//
+// Search results sorted on a numeric index field continue from the last seen sort
+// value (keyset pagination) so deep pages don't pay for large offsets: the
+// next page queries values from After onwards and skips the Skip entries
+// that tie with After and were already returned. Fallback listings in
+// keyspace order continue likewise from a SCAN cursor: the next page scans
+// from Cursor and skips the Skip matches of that batch already returned.
+// Text sorts and sorted fallback listings, which sort in memory, continue
+// from Offset instead.
+type pageToken struct {
+	Offset int     `json:"o,omitempty"`
+	Keyset bool    `json:"k,omitempty"`
+	After  float64 `json:"a,omitempty"`
+	Skip   int     `json:"s,omitempty"`
+	Field  string  `json:"f,omitempty"`
+	Desc   bool    `json:"d,omitempty"`
+	Scan   bool    `json:"c,omitempty"`
+	Cursor uint64  `json:"u,omitempty"`
+	// Total is the match count from the first page, reported again on later
+	// pages because keyset queries only count the remaining matches.
+	Total int `json:"t"`
+}
+
+func encodePageToken(token pageToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(value string) (*pageToken, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+
+	var token pageToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+	if token.Offset < 0 || token.Skip < 0 {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &token, nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
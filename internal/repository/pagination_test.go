@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestListProductsPageTokensWalkScanCursor(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	const n = 250
+	createTestProducts(t, repo, n)
+
+	seen := make(map[string]bool, n)
+	opts := ListOptions{PageSize: 7}
+	for pages := 0; ; pages++ {
+		if pages > n {
+			t.Fatal("page tokens did not end")
+		}
+		result, err := repo.ListProducts(ctx, opts)
+		if err != nil {
+			t.Fatalf("ListProducts page %d: %v", pages+1, err)
+		}
+		if result.Total != n {
+			t.Errorf("page %d total = %d, want %d", pages+1, result.Total, n)
+		}
+		for _, id := range productIDs(result.Products) {
+			if seen[id] {
+				t.Errorf("page %d repeats %s", pages+1, id)
+			}
+			seen[id] = true
+		}
+		if result.NextPageToken == "" {
+			break
+		}
+		opts.PageToken = result.NextPageToken
+	}
+	for i := 0; i < n; i++ {
+		if id := createTestID(i); !seen[id] {
+			t.Errorf("page tokens skipped %s", id)
+		}
+	}
+
+	// Page numbers page through the same order as the tokens.
+	first, err := repo.ListProducts(ctx, ListOptions{PageSize: 7})
+	if err != nil {
+		t.Fatalf("ListProducts: %v", err)
+	}
+	second, err := repo.ListProducts(ctx, ListOptions{PageSize: 7, PageToken: first.NextPageToken})
+	if err != nil {
+		t.Fatalf("ListProducts by token: %v", err)
+	}
+	numbered, err := repo.ListProducts(ctx, ListOptions{Page: 2, PageSize: 7})
+	if err != nil {
+		t.Fatalf("ListProducts page 2: %v", err)
+	}
+	if got, want := productIDs(numbered.Products), productIDs(second.Products); !slices.Equal(got, want) {
+		t.Errorf("page 2 = %v, want %v like its token", got, want)
+	}
+
+	// A token from a keyspace-order listing doesn't continue a sorted one.
+	if _, err := repo.ListProducts(ctx, ListOptions{PageSize: 7, SortBy: SortByPrice, PageToken: first.NextPageToken}); !errors.Is(err, ErrInvalidPageToken) {
+		t.Errorf("sorted ListProducts with a scan token = %v, want ErrInvalidPageToken", err)
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/currency"
+)
+
+// DefaultCurrency is the currency of products created without one and of
+// products stored before currencies were introduced.
+const DefaultCurrency = "USD"
+
+// currencyField is the search index TAG field holding the currency of each
+// product, which catalog stats are grouped by.
+const currencyField = "currency"
+
+// ErrPricePrecision is returned for a price with more decimals than the
+// minor unit of its currency, which storing it would round away.
+var ErrPricePrecision = errors.New("price is more precise than its currency allows")
+
+// ParseCurrency validates an ISO 4217 currency code, in any case, and
+// returns it in upper case.
+func ParseCurrency(code string) (string, error) {
+	unit, err := currency.ParseISO(strings.TrimSpace(code))
+	if err != nil {
+		return "", fmt.Errorf("%q is not an ISO 4217 currency code", code)
+	}
+	return unit.String(), nil
+}
+
+// minorUnitDigits returns the number of decimals of the currency's minor
+// unit, e.g. 2 for USD and 0 for JPY. Unknown currencies are treated as
+// having cents.
+func minorUnitDigits(code string) int {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return 2
+	}
+	digits, _ := currency.Standard.Rounding(unit)
+	return digits
+}
+
+// minorUnitScale returns 10 to the number of decimals of the currency's
+// minor unit, e.g. 100 for USD and 1 for JPY.
+func minorUnitScale(code string) float64 {
+	return math.Pow10(minorUnitDigits(code))
+}
+
+// CheckPricePrecision returns ErrPricePrecision if price, written out in
+// the fewest decimals that represent it, has more decimals than the minor
+// unit of the currency: 19.99 is a valid USD price but not a JPY one.
+func CheckPricePrecision(price float64, code string) error {
+	formatted := strconv.FormatFloat(price, 'f', -1, 64)
+	_, decimals, _ := strings.Cut(formatted, ".")
+	if digits := minorUnitDigits(code); len(decimals) > digits {
+		return fmt.Errorf("%w: %s %s has more than %d decimals", ErrPricePrecision, formatted, code, digits)
+	}
+	return nil
+}
+
+// toMinorUnits converts a price to the nearest whole number of the
+// currency's minor units.
+func toMinorUnits(price float64, code string) int64 {
+	return int64(math.Round(price * minorUnitScale(code)))
+}
+
+func fromMinorUnits(minor int64, code string) float64 {
+	return float64(minor) / minorUnitScale(code)
+}
+
+// normalizePrice makes PriceMinor, the stored amount, authoritative: it is
+// set from Price, defaulting the currency, and Price is recomputed from it,
+// so 19.99 stays exactly the float 19.99 however often it is stored. Writes
+// reject over-precise prices with CheckPricePrecision first; the rounding
+// only applies to imported and legacy products.
+func normalizePrice(product *Product) {
+	if product.Currency == "" {
+		product.Currency = DefaultCurrency
+	}
+	product.PriceMinor = toMinorUnits(product.Price, product.Currency)
+	product.Price = fromMinorUnits(product.PriceMinor, product.Currency)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCurrency(t *testing.T) {
+	for _, tc := range []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{code: "USD", want: "USD"},
+		{code: " eur ", want: "EUR"},
+		{code: "jpy", want: "JPY"},
+		{code: "XYZ", wantErr: true},
+		{code: "US", wantErr: true},
+		{code: "", wantErr: true},
+	} {
+		got, err := ParseCurrency(tc.code)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseCurrency(%q) = %q, %v; want %q, error %t", tc.code, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestCheckPricePrecision(t *testing.T) {
+	for _, tc := range []struct {
+		price    float64
+		currency string
+		wantErr  bool
+	}{
+		{price: 19.99, currency: "USD"},
+		{price: 0.30000000000000004, currency: "USD", wantErr: true},
+		{price: 19.999, currency: "USD", wantErr: true},
+		{price: 20, currency: "JPY"},
+		{price: 19.99, currency: "JPY", wantErr: true},
+		{price: 1.234, currency: "BHD"},
+		{price: 1.2345, currency: "BHD", wantErr: true},
+		{price: 999999.99, currency: "EUR"},
+	} {
+		err := CheckPricePrecision(tc.price, tc.currency)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("CheckPricePrecision(%v, %s) = %v, want error %t", tc.price, tc.currency, err, tc.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrPricePrecision) {
+			t.Errorf("CheckPricePrecision(%v, %s) = %v, want ErrPricePrecision", tc.price, tc.currency, err)
+		}
+	}
+}
+
+func TestPriceRoundTripsInMinorUnits(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	if err := repo.CreateProduct(ctx, &Product{ID: "p1", Name: "Mouse", Price: 19.99, Currency: "USD"}); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	// Store the product again to check that repeated writes don't drift.
+	for i := 0; i < 3; i++ {
+		product, err := repo.GetProduct(ctx, "p1")
+		if err != nil {
+			t.Fatalf("GetProduct: %v", err)
+		}
+		if product.PriceMinor != 1999 || product.Price != 19.99 {
+			t.Fatalf("write %d: price = %v (%d minor units), want 19.99 (1999)", i, product.Price, product.PriceMinor)
+		}
+		if _, err := repo.UpdateProduct(ctx, product, 0); err != nil {
+			t.Fatalf("UpdateProduct: %v", err)
+		}
+	}
+
+	err := repo.CreateProduct(ctx, &Product{ID: "p2", Name: "Tea", Price: 19.99, Currency: "JPY"})
+	if !errors.Is(err, ErrPricePrecision) {
+		t.Errorf("CreateProduct(19.99 JPY) = %v, want ErrPricePrecision", err)
+	}
+}
+
+func TestContentDigestDistinguishesCurrencies(t *testing.T) {
+	repo, _ := newTestRepository(t, func(o *Options) { o.CreateDedupWindow = time.Minute })
+	ctx := context.Background()
+
+	dollars, err := repo.CreateProductIdempotent(ctx, "", &Product{Name: "Cable", Price: 5})
+	if err != nil {
+		t.Fatalf("create in the default currency: %v", err)
+	}
+	again, err := repo.CreateProductIdempotent(ctx, "", &Product{Name: "Cable", Price: 5, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("create in USD: %v", err)
+	}
+	euros, err := repo.CreateProductIdempotent(ctx, "", &Product{Name: "Cable", Price: 5, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("create in EUR: %v", err)
+	}
+
+	if again.ID != dollars.ID {
+		t.Errorf("USD create = %s, want the default-currency product %s", again.ID, dollars.ID)
+	}
+	if euros.ID == dollars.ID {
+		t.Errorf("EUR create was deduplicated to the USD product %s", dollars.ID)
+	}
+}
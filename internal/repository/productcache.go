@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	productCacheHits   metric.Int64Counter
+	productCacheMisses metric.Int64Counter
+)
+
+func init() {
+	meter := otel.Meter("products-service")
+	var err error
+
+	productCacheHits, err = meter.Int64Counter(
+		"products_cache_hits_total",
+		metric.WithDescription("Number of GetProduct calls served from the in-memory cache"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	productCacheMisses, err = meter.Int64Counter(
+		"products_cache_misses_total",
+		metric.WithDescription("Number of GetProduct calls that had to read from Redis"),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// productCache is a fixed-size LRU of products keyed by ID. Entries older
+// than ttl are treated as misses. A nil cache is valid and caches nothing.
+type productCache struct {
+	maxEntries int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	// generation counts invalidations. A fill that read Redis before an
+	// invalidation may hold the value it invalidated, so add drops fills
+	// started in an earlier generation.
+	generation uint64
+}
+
+type productCacheEntry struct {
+	id        string
+	product   Product
+	cachedAt  time.Time
+	expiresAt time.Time
+}
+
+// newProductCache returns nil when maxEntries is not positive, disabling
+// caching.
+func newProductCache(maxEntries int, ttl time.Duration) *productCache {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &productCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element, maxEntries),
+	}
+}
+
+// get returns a copy of the cached product so callers can't mutate the
+// cached entry. Entries cached more than maxStaleness ago are misses but are
+// kept for less demanding readers; a negative maxStaleness accepts any entry
+// within the TTL.
+func (c *productCache) get(ctx context.Context, id string, maxStaleness time.Duration) (*Product, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	elem, ok := c.entries[id]
+	if ok && c.ttl > 0 && now.After(elem.Value.(*productCacheEntry).expiresAt) {
+		c.removeElement(elem)
+		ok = false
+	}
+	if ok && maxStaleness >= 0 && now.Sub(elem.Value.(*productCacheEntry).cachedAt) > maxStaleness {
+		ok = false
+	}
+	if !ok {
+		productCacheMisses.Add(ctx, 1)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	productCacheHits.Add(ctx, 1)
+	product := elem.Value.(*productCacheEntry).product
+	return &product, true
+}
+
+// currentGeneration is passed to add by readers filling the cache; take
+// it before reading the product from Redis.
+func (c *productCache) currentGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// add caches product unless the cache was invalidated since generation was
+// taken, in which case product may be stale and is dropped.
+func (c *productCache) add(product *Product, generation uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := time.Now()
+	entry := &productCacheEntry{
+		id:        product.ID,
+		product:   *product,
+		cachedAt:  now,
+		expiresAt: now.Add(c.ttl),
+	}
+	if elem, ok := c.entries[product.ID]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[product.ID] = c.order.PushFront(entry)
+	if c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// invalidate drops the cached entry for id, if any. Call it after every
+// write to the product.
+func (c *productCache) invalidate(id string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.entries[id]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *productCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*productCacheEntry).id)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProductCacheHitMissAndInvalidation(t *testing.T) {
+	repo, server := newTestRepository(t, func(o *Options) {
+		o.ProductCacheSize = 10
+		o.ProductCacheTTL = time.Minute
+	})
+	ctx := context.Background()
+	createTestProducts(t, repo, 1)
+	id := createTestID(0)
+
+	if _, source, err := repo.GetProductWithin(ctx, id, -1); err != nil || source != ReadSourcePrimary {
+		t.Fatalf("first read = %v, %v; want a miss served from Redis", source, err)
+	}
+	if _, source, err := repo.GetProductWithin(ctx, id, -1); err != nil || source != ReadSourceCache {
+		t.Fatalf("second read = %v, %v; want a cache hit", source, err)
+	}
+
+	// Changed behind the repository's back, Redis isn't consulted on a hit.
+	server.Del(repo.keyFor(id))
+	if _, err := repo.GetProduct(ctx, id); err != nil {
+		t.Fatalf("cached read after external delete: %v", err)
+	}
+
+	createTestProducts(t, repo, 1)
+	updated := &Product{ID: id, Name: "Renamed", Category: "Test", Price: 5, Stock: 10}
+	if _, err := repo.UpdateProduct(ctx, updated, 0); err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+	product, source, err := repo.GetProductWithin(ctx, id, -1)
+	if err != nil {
+		t.Fatalf("read after update: %v", err)
+	}
+	if source != ReadSourcePrimary || product.Name != "Renamed" {
+		t.Errorf("read after update = %q from %v, want %q from Redis", product.Name, source, "Renamed")
+	}
+}
+
+func TestProductCacheDropsFillsStartedBeforeInvalidation(t *testing.T) {
+	cache := newProductCache(10, time.Minute)
+	ctx := context.Background()
+
+	// A reader takes the generation and loads the old value; a writer then
+	// stores a new one and invalidates before the reader fills the cache.
+	generation := cache.currentGeneration()
+	stale := &Product{ID: "p1", Name: "Old"}
+	cache.invalidate("p1")
+	cache.add(stale, generation)
+
+	if product, ok := cache.get(ctx, "p1", -1); ok {
+		t.Fatalf("cache holds %q filled before the invalidation", product.Name)
+	}
+
+	cache.add(&Product{ID: "p1", Name: "New"}, cache.currentGeneration())
+	if product, ok := cache.get(ctx, "p1", -1); !ok || product.Name != "New" {
+		t.Errorf("get after a current fill = %v, %v; want New", product, ok)
+	}
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestBuildSearchQuery(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts ListOptions
+		want string
+	}{
+		{name: "no filters", want: "*"},
+		{
+			name: "category ignores case",
+			opts: ListOptions{SearchQuery: "laptop", Category: " Electronics "},
+			want: "laptop @category_tag:{electronics}",
+		},
+		{
+			name: "multi-word category",
+			opts: ListOptions{SearchQuery: "chair", Category: "Home & Garden"},
+			want: `chair @category_tag:{home\ \&\ garden}`,
+		},
+		{
+			name: "category with a comma",
+			opts: ListOptions{SearchQuery: "chair", Category: "Home, Garden"},
+			want: `chair @category_tag:{home\,\ garden}`,
+		},
+		{
+			name: "category with tag syntax",
+			opts: ListOptions{SearchQuery: "blocks", Category: "{A|B}"},
+			want: `blocks @category_tag:{\{a\|b\}}`,
+		},
+		{
+			name: "several tags",
+			opts: ListOptions{Tags: []string{"Wireless", "USB C"}},
+			want: `@tags:{wireless} @tags:{usb\ c}`,
+		},
+		{
+			name: "minimum stock",
+			opts: ListOptions{Category: "Electronics", MinStock: 1},
+			want: "@category_tag:{electronics} @stock:[1 +inf]",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := buildSearchQuery(tc.opts); got != tc.want {
+				t.Errorf("buildSearchQuery = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestListProductsCategoryIgnoresCase(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	for _, product := range []*Product{
+		{ID: "laptop", Name: "Laptop", Category: "Electronics", Price: 999},
+		{ID: "chair", Name: "Chair", Category: "Furniture", Price: 99},
+	} {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	for _, category := range []string{"Electronics", "electronics", " ELECTRONICS "} {
+		result, err := repo.ListProducts(ctx, ListOptions{Category: category})
+		if err != nil {
+			t.Fatalf("ListProducts(%q): %v", category, err)
+		}
+		if len(result.Products) != 1 || result.Products[0].ID != "laptop" {
+			t.Errorf("ListProducts(%q) = %v, want only laptop", category, productIDs(result.Products))
+			continue
+		}
+		if result.Products[0].Category != "Electronics" {
+			t.Errorf("category = %q, want the original case", result.Products[0].Category)
+		}
+	}
+}
+
+func TestListProductsAdversarialCategories(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	categories := map[string]string{
+		"comma":  "Home, Garden",
+		"home":   "Home",
+		"garden": "Garden",
+		"pipe":   "A|B",
+		"a":      "A",
+		"braces": "{Toys}",
+		"quote":  `Tools "Pro"`,
+	}
+	for id, category := range categories {
+		if err := repo.CreateProduct(ctx, &Product{ID: id, Name: id, Category: category, Price: 1}); err != nil {
+			t.Fatalf("CreateProduct(%q): %v", category, err)
+		}
+	}
+
+	for id, category := range categories {
+		result, err := repo.ListProducts(ctx, ListOptions{Category: category})
+		if err != nil {
+			t.Errorf("ListProducts(%q): %v", category, err)
+			continue
+		}
+		if got := productIDs(result.Products); !slices.Equal(got, []string{id}) || result.Total != 1 {
+			t.Errorf("ListProducts(%q) = %v of %d, want only %s", category, got, result.Total, id)
+		}
+	}
+}
+
+func TestListProductsCategoryTotalsAgreeAcrossPaths(t *testing.T) {
+	repo, redis := newTestRepository(t)
+	ctx := context.Background()
+
+	categories := []string{"Electronics", "Home & Garden", "Books"}
+	want := make(map[string][]string)
+	var products []*Product
+	for i := 0; i < 23; i++ {
+		product := &Product{
+			ID:       createTestID(i),
+			Name:     fmt.Sprintf("Product %d", i),
+			Category: categories[i%len(categories)],
+			// Some prices tie, so keyset tokens have to skip within a price.
+			Price: float64(i/2 + 1),
+		}
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+		products = append(products, product)
+		want[product.Category] = append(want[product.Category], product.ID)
+	}
+
+	// walk pages through the category with page tokens and returns the IDs
+	// listed and the total of every page.
+	walk := func(category string) ([]string, []int32) {
+		var ids []string
+		var totals []int32
+		opts := ListOptions{Category: category, PageSize: 3}
+		for range products {
+			result, err := repo.ListProducts(ctx, opts)
+			if err != nil {
+				t.Fatalf("ListProducts(%q): %v", category, err)
+			}
+			ids = append(ids, productIDs(result.Products)...)
+			totals = append(totals, result.Total)
+			if result.NextPageToken == "" {
+				break
+			}
+			opts.PageToken = result.NextPageToken
+		}
+		slices.Sort(ids)
+		return ids, totals
+	}
+	check := func(path, category string) {
+		t.Helper()
+		ids, totals := walk(category)
+		expected := slices.Clone(want[category])
+		slices.Sort(expected)
+		if !slices.Equal(ids, expected) {
+			t.Errorf("%s path listed %v for %q, want %v", path, ids, category, expected)
+		}
+		for page, total := range totals {
+			if int(total) != len(expected) {
+				t.Errorf("%s path page %d total for %q = %d, want %d", path, page+1, category, total, len(expected))
+			}
+		}
+	}
+
+	for _, category := range categories {
+		check("scan", category)
+	}
+	enableFakeSearch(t, repo, redis, products)
+	for _, category := range categories {
+		check("search", category)
+	}
+}
+
+func TestListProductsRelevanceOrder(t *testing.T) {
+	repo, redis := newTestRepository(t)
+	ctx := context.Background()
+
+	// The weak match is the cheapest, so price order would put it first.
+	products := []*Product{
+		{ID: "weak", Name: "Sleeve", Description: "Fits a laptop", Category: "Bags", Price: 15},
+		{ID: "strong", Name: "Laptop", Description: "A laptop for work", Category: "Computers", Price: 999},
+		{ID: "other", Name: "Mouse", Description: "Wireless", Category: "Computers", Price: 20},
+	}
+	for _, product := range products {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	// Without the index there are no scores, so relevance falls back to
+	// price order.
+	result, err := repo.ListProducts(ctx, ListOptions{SearchQuery: "laptop", SortBy: SortByRelevance})
+	if err != nil {
+		t.Fatalf("scan ListProducts: %v", err)
+	}
+	if got := productIDs(result.Products); !slices.Equal(got, []string{"weak", "strong"}) {
+		t.Errorf("scan path listed %v, want price order [weak strong]", got)
+	}
+	for _, product := range result.Products {
+		if product.Score != NoScore {
+			t.Errorf("scan path scored %s %g, want NoScore", product.ID, product.Score)
+		}
+	}
+
+	enableFakeSearch(t, repo, redis, products)
+	result, err = repo.ListProducts(ctx, ListOptions{SearchQuery: "laptop", SortBy: SortByRelevance})
+	if err != nil {
+		t.Fatalf("search ListProducts: %v", err)
+	}
+	if got := productIDs(result.Products); !slices.Equal(got, []string{"strong", "weak"}) {
+		t.Fatalf("relevance order = %v, want the name match first", got)
+	}
+	if strong, weak := result.Products[0].Score, result.Products[1].Score; strong <= weak || weak <= 0 {
+		t.Errorf("scores = %g and %g, want the name match scored higher", strong, weak)
+	}
+
+	// The default order stays by price.
+	result, err = repo.ListProducts(ctx, ListOptions{SearchQuery: "laptop"})
+	if err != nil {
+		t.Fatalf("search ListProducts: %v", err)
+	}
+	if got := productIDs(result.Products); !slices.Equal(got, []string{"weak", "strong"}) {
+		t.Errorf("default order = %v, want price order [weak strong]", got)
+	}
+}
+
+func TestListProductsMultiWordCategory(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	for _, product := range []*Product{
+		{ID: "rake", Name: "Rake", Category: "Home & Garden", Price: 20},
+		{ID: "shovel", Name: "Shovel", Category: "home & garden", Price: 30},
+		{ID: "lamp", Name: "Lamp", Category: "Home", Price: 40},
+		{ID: "hose", Name: "Hose", Category: "Garden", Price: 50},
+	} {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	result, err := repo.ListProducts(ctx, ListOptions{Category: "Home & Garden"})
+	if err != nil {
+		t.Fatalf("ListProducts: %v", err)
+	}
+	if got := productIDs(result.Products); !slices.Equal(got, []string{"rake", "shovel"}) {
+		t.Errorf("ListProducts = %v, want [rake shovel]", got)
+	}
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newRedisClient builds the client for the configured Redis mode. Cluster
+// mode returns a *redis.ClusterClient; single and Sentinel modes both return
+// a *redis.Client, the latter following failovers of the named master.
+func newRedisClient(options Options) (redis.UniversalClient, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:      options.RedisAddrs,
+		MasterName: options.RedisMasterName,
+		ClientName: options.ClientName,
+
+		PoolSize:     options.PoolSize,
+		DialTimeout:  options.DialTimeout,
+		ReadTimeout:  options.ReadTimeout,
+		WriteTimeout: options.WriteTimeout,
+		MaxRetries:   options.MaxRetries,
+	}
+
+	switch options.RedisMode {
+	case RedisModeSingle:
+		return redis.NewClient(opts.Simple()), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	case RedisModeSentinel:
+		if options.RedisMasterName == "" {
+			return nil, fmt.Errorf("redis sentinel mode requires a master name")
+		}
+		return redis.NewFailoverClient(opts.Failover()), nil
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", options.RedisMode)
+	}
+}
+
+// effectivePoolSize is the per-node pool size go-redis uses for poolSize,
+// which defaults to 10 connections per CPU when unset.
+func effectivePoolSize(poolSize int) int {
+	if poolSize > 0 {
+		return poolSize
+	}
+	return 10 * runtime.GOMAXPROCS(0)
+}
+
+// searchAddr returns the address the RediSearch client connects to:
+// SearchAddr when set, otherwise the Redis node. The search client does its
+// own connection handling, so in Sentinel mode the current master is looked
+// up once at startup.
+func searchAddr(ctx context.Context, options Options) (string, error) {
+	if options.SearchAddr != "" {
+		return options.SearchAddr, nil
+	}
+	if options.RedisMode != RedisModeSentinel {
+		return options.RedisAddrs[0], nil
+	}
+
+	sentinel := redis.NewSentinelClient(&redis.Options{Addr: options.RedisAddrs[0]})
+	defer sentinel.Close()
+
+	master, err := sentinel.GetMasterAddrByName(ctx, options.RedisMasterName).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve redis master %q: %w", options.RedisMasterName, err)
+	}
+	return net.JoinHostPort(master[0], master[1]), nil
+}
+
+// forEachNode runs fn against every node holding product keys: each master
+// in cluster mode, otherwise the single client. Calls are serialized so fn
+// need not be safe for concurrent use.
+func (r *RedisRepository) forEachNode(ctx context.Context, fn func(ctx context.Context, client *redis.Client) error) error {
+	switch client := r.client.(type) {
+	case *redis.ClusterClient:
+		var mu sync.Mutex
+		return client.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(ctx, node)
+		})
+	case *redis.Client:
+		return fn(ctx, client)
+	default:
+		return fmt.Errorf("unsupported redis client %T", r.client)
+	}
+}
+
+// isCluster reports whether keys are spread over several cluster nodes, in
+// which case multi-key commands such as MGET and per-node SCAN cursors
+// cannot be used across the whole keyspace.
+func (r *RedisRepository) isCluster() bool {
+	_, ok := r.client.(*redis.ClusterClient)
+	return ok
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewRedisClientModes(t *testing.T) {
+	for _, tc := range []struct {
+		mode       string
+		masterName string
+		want       string
+		wantErr    bool
+	}{
+		{mode: RedisModeSingle, want: "*redis.Client"},
+		{mode: RedisModeCluster, want: "*redis.ClusterClient"},
+		{mode: RedisModeSentinel, masterName: "mymaster", want: "*redis.Client"},
+		{mode: RedisModeSentinel, wantErr: true},
+		{mode: "ring", wantErr: true},
+	} {
+		client, err := newRedisClient(Options{
+			RedisMode:       tc.mode,
+			RedisAddrs:      []string{"localhost:6379", "localhost:6380"},
+			RedisMasterName: tc.masterName,
+		})
+		if tc.wantErr {
+			if err == nil {
+				client.Close()
+				t.Errorf("newRedisClient(%s, master %q) succeeded, want an error", tc.mode, tc.masterName)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newRedisClient(%s): %v", tc.mode, err)
+			continue
+		}
+
+		var got string
+		switch client.(type) {
+		case *redis.Client:
+			got = "*redis.Client"
+		case *redis.ClusterClient:
+			got = "*redis.ClusterClient"
+		default:
+			got = "other"
+		}
+		if got != tc.want {
+			t.Errorf("newRedisClient(%s) built a %T, want %s", tc.mode, client, tc.want)
+		}
+		client.Close()
+	}
+}
+
+func TestNewRedisClientPoolOptions(t *testing.T) {
+	client, err := newRedisClient(Options{
+		RedisMode:    RedisModeSingle,
+		RedisAddrs:   []string{"localhost:6379"},
+		PoolSize:     42,
+		DialTimeout:  2 * time.Second,
+		ReadTimeout:  750 * time.Millisecond,
+		WriteTimeout: 900 * time.Millisecond,
+		MaxRetries:   7,
+	})
+	if err != nil {
+		t.Fatalf("newRedisClient: %v", err)
+	}
+	defer client.Close()
+
+	opts := client.(*redis.Client).Options()
+	if opts.PoolSize != 42 || opts.DialTimeout != 2*time.Second || opts.ReadTimeout != 750*time.Millisecond ||
+		opts.WriteTimeout != 900*time.Millisecond || opts.MaxRetries != 7 {
+		t.Errorf("redis options = pool %d, dial %v, read %v, write %v, retries %d; want 42, 2s, 750ms, 900ms, 7",
+			opts.PoolSize, opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout, opts.MaxRetries)
+	}
+}
+
+// watchExpirations runs WatchExpirations in the background, sending the
+// IDs it reports on the returned channel once every node is subscribed to.
+func watchExpirations(t *testing.T, repo *RedisRepository, nodes ...*miniredis.Miniredis) <-chan string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ids := make(chan string, 10)
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	go func() {
+		defer close(done)
+		repo.WatchExpirations(ctx, func(id string) error {
+			ids <- id
+			return nil
+		})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, node := range nodes {
+		for node.PubSubNumPat() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("WatchExpirations did not subscribe to every node")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return ids
+}
+
+// receiveIDs waits for n IDs from ids and returns them sorted.
+func receiveIDs(t *testing.T, ids <-chan string, n int) []string {
+	t.Helper()
+
+	var got []string
+	for range n {
+		select {
+		case id := <-ids:
+			got = append(got, id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %v, want %d IDs", got, n)
+		}
+	}
+	slices.Sort(got)
+	return got
+}
+
+func TestWatchExpirations(t *testing.T) {
+	repo, redis := newTestRepository(t)
+	ids := watchExpirations(t, repo, redis)
+
+	redis.Publish("__keyevent@0__:expired", "session:abc")
+	redis.Publish("__keyevent@0__:expired", productsKeyPrefix+"p1")
+	if got := receiveIDs(t, ids, 1); !slices.Equal(got, []string{"p1"}) {
+		t.Errorf("reported %v, want [p1]", got)
+	}
+}
+
+func TestWatchExpirationsEveryClusterMaster(t *testing.T) {
+	repo, _ := newTestRepository(t)
+
+	// Two masters splitting the slots between them.
+	first, second := miniredis.RunT(t), miniredis.RunT(t)
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: first.Addr()}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: second.Addr()}}},
+			}, nil
+		},
+	})
+	t.Cleanup(func() { cluster.Close() })
+	repo.client = cluster
+
+	ids := watchExpirations(t, repo, first, second)
+	first.Publish("__keyevent@0__:expired", productsKeyPrefix+"p1")
+	second.Publish("__keyevent@0__:expired", productsKeyPrefix+"p2")
+	if got := receiveIDs(t, ids, 2); !slices.Equal(got, []string{"p1", "p2"}) {
+		t.Errorf("reported %v, want the expirations of both masters", got)
+	}
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// reindexStateKey persists the SCAN cursor and processed count of a running
+// reindex so an interrupted run can resume instead of starting over.
+const reindexStateKey = "products:reindex:state"
+
+var ErrSearchUnavailable = errors.New("search index is not available")
+
+// ReindexProgress is reported after every reindexed batch.
+type ReindexProgress struct {
+	Processed int64
+	Total     int64
+	Done      bool
+}
+
+// Percent returns the share of products processed so far.
+func (p ReindexProgress) Percent() float64 {
+	if p.Total == 0 {
+		return 100
+	}
+	percent := float64(p.Processed) / float64(p.Total) * 100
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// Reindex walks the keyspace one SCAN batch at a time and re-adds every
+// product to the search index, throttled to the configured items per second.
+// The cursor is saved after each batch; a batch interrupted midway is simply
+// indexed again on resume, which is harmless since documents are replaced.
+// SCAN cursors are per node, so in cluster mode every reindex starts over.
+func (r *RedisRepository) Reindex(ctx context.Context, restart bool, fn func(ReindexProgress) error) error {
+	if !r.searchEnabled || r.search == nil {
+		return ErrSearchUnavailable
+	}
+
+	var limiter *rate.Limiter
+	if r.reindexRate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(r.reindexRate), 1)
+	}
+
+	if r.isCluster() {
+		return r.reindexCluster(ctx, limiter, fn)
+	}
+
+	if restart {
+		if err := r.client.Del(ctx, reindexStateKey).Err(); err != nil {
+			return fmt.Errorf("failed to reset reindex progress: %w", err)
+		}
+	}
+
+	cursor, processed, err := r.loadReindexState(ctx)
+	if err != nil {
+		return err
+	}
+	if cursor != 0 {
+		r.log(ctx).Info("Resuming reindex", zap.Uint64("cursor", cursor), zap.Int64("processed", processed))
+	}
+
+	total, err := r.countProducts(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	pattern := productsKeyPrefix + "*"
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		keys, nextCursor, err := r.client.Scan(ctx, cursor, pattern, int64(seedScanBatchSize)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan product keys: %w", err)
+		}
+
+		count, err := r.reindexBatch(ctx, keys, limiter)
+		if err != nil {
+			return err
+		}
+
+		processed += int64(count)
+		cursor = nextCursor
+
+		if cursor == 0 {
+			if err := r.client.Del(ctx, reindexStateKey).Err(); err != nil {
+				r.log(ctx).Warn("Failed to clear reindex progress", zap.Error(err))
+			}
+			return fn(ReindexProgress{Processed: processed, Total: int64(total), Done: true})
+		}
+
+		if err := r.client.HSet(ctx, reindexStateKey, "cursor", cursor, "processed", processed).Err(); err != nil {
+			return fmt.Errorf("failed to save reindex progress: %w", err)
+		}
+
+		if err := fn(ReindexProgress{Processed: processed, Total: int64(total)}); err != nil {
+			return err
+		}
+	}
+}
+
+// reindexCluster reindexes every master's keys without saving progress.
+func (r *RedisRepository) reindexCluster(ctx context.Context, limiter *rate.Limiter, fn func(ReindexProgress) error) error {
+	total, err := r.countProducts(ctx, 0)
+	if err != nil {
+		return err
+	}
+
+	var processed int64
+	err = r.scanProductKeys(ctx, func(keys []string) error {
+		count, err := r.reindexBatch(ctx, keys, limiter)
+		if err != nil {
+			return err
+		}
+		processed += int64(count)
+		return fn(ReindexProgress{Processed: processed, Total: int64(total)})
+	})
+	if err != nil {
+		return err
+	}
+
+	return fn(ReindexProgress{Processed: processed, Total: int64(total), Done: true})
+}
+
+// reindexBatch re-adds the products stored under keys to the search index
+// and returns how many were found.
+func (r *RedisRepository) reindexBatch(ctx context.Context, keys []string, limiter *rate.Limiter) (int, error) {
+	products, err := r.fetchProducts(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	docs := make([]redisearch.Document, 0, len(products))
+	for _, product := range products {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return 0, err
+			}
+		}
+		docs = append(docs, r.productDocument(product))
+	}
+
+	if len(docs) > 0 {
+		if err := r.search.IndexOptions(redisearch.IndexingOptions{Replace: true}, docs...); err != nil {
+			r.log(ctx).Warn("Failed to reindex some products", zap.Error(err))
+		}
+	}
+	r.addSuggestions(ctx, products)
+
+	return len(products), nil
+}
+
+func (r *RedisRepository) loadReindexState(ctx context.Context) (uint64, int64, error) {
+	state, err := r.client.HGetAll(ctx, reindexStateKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, 0, fmt.Errorf("failed to load reindex progress: %w", err)
+	}
+	if len(state) == 0 {
+		return 0, 0, nil
+	}
+
+	cursor, err := strconv.ParseUint(state["cursor"], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid reindex cursor %q: %w", state["cursor"], err)
+	}
+	processed, err := strconv.ParseInt(state["processed"], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid reindex progress %q: %w", state["processed"], err)
+	}
+	return cursor, processed, nil
+}
@@ -5,11 +5,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/RediSearch/redisearch-go/v2/redisearch"
 	"github.com/brianvoe/gofakeit/v7"
+	"github.com/chirik/products/internal/observability"
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
@@ -22,28 +28,300 @@ type Product struct {
 	Category    string    `json:"category"`
 	Stock       int32     `json:"stock"`
 	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Slug is a URL-friendly name, set by the slug enricher.
+	Slug string `json:"slug,omitempty"`
+	// SKU is the optional stock keeping unit. No two products share one;
+	// see skuKeyPrefix.
+	SKU string `json:"sku,omitempty"`
+
+	// Tags are free-form labels; unlike the category a product can have
+	// several. Attributes are free-form key/value details such as a color.
+	Tags       []string          `json:"tags,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Currency is the ISO 4217 code of Price. PriceMinor is the stored
+	// amount in the currency's minor units, such as cents: Price is derived
+	// from it when a product is read and sets it when a product is written,
+	// so prices don't drift.
+	Currency   string `json:"currency"`
+	PriceMinor int64  `json:"price_minor"`
+
+	// SchemaVersion is the stored layout of the record; see
+	// CurrentSchemaVersion. Products returned by the repository are always
+	// upgraded to the current version.
+	SchemaVersion int `json:"schema_version"`
+
+	// Version counts writes to the product, starting at 1, so updates can
+	// detect that it changed since it was read.
+	Version int64 `json:"version"`
+
+	// Score is the search relevance score populated by ListProducts when
+	// ListOptions.IncludeScore is set or results are sorted by relevance.
+	// It is never stored.
+	Score float64 `json:"-"`
+
+	// HighlightedName and HighlightedDescription are the name and
+	// description with matched search terms wrapped in <b> tags, populated
+	// by ListProducts when ListOptions.Highlight is set. They are never
+	// stored.
+	HighlightedName        string `json:"-"`
+	HighlightedDescription string `json:"-"`
 }
 
+// NoScore is the Score reported when relevance scores are unavailable
+// because the search index is not being used.
+const NoScore = -1
+
+var ErrProductNotFound = errors.New("product not found")
+
 type Repository interface {
 	CreateProduct(ctx context.Context, product *Product) error
+	// CreateProductIdempotent creates product, or returns the product
+	// already created with the same idempotency key or, without a key and
+	// with deduplication enabled, the same content.
+	CreateProductIdempotent(ctx context.Context, idempotencyKey string, product *Product) (*Product, error)
+	// CreateProducts creates products with one pipelined write and returns
+	// an error per product, nil for each one created, so that one failure
+	// doesn't abort the rest.
+	CreateProducts(ctx context.Context, products []*Product) []error
 	GetProduct(ctx context.Context, id string) (*Product, error)
-	ListProducts(ctx context.Context, page, pageSize int32, category, searchQuery string) ([]*Product, int32, error)
+	// GetProductBySKU returns the product with the given SKU.
+	GetProductBySKU(ctx context.Context, sku string) (*Product, error)
+	// GetProductWithin is GetProduct accepting a cached copy only if it was
+	// cached at most maxStaleness ago; a negative maxStaleness accepts any
+	// cached copy. It reports where the product was read from.
+	GetProductWithin(ctx context.Context, id string, maxStaleness time.Duration) (*Product, ReadSource, error)
+	// ListProducts returns one page of matching products together with the
+	// total number of matches. The total counts every match across all pages,
+	// while Products holds only the products actually fetched for this page,
+	// which may be fewer than the page size (or than the index reported) if
+	// keys disappeared between the search and the fetch.
+	ListProducts(ctx context.Context, opts ListOptions) (*ListResult, error)
+	// ListModifiedSince pages through the products updated at or after
+	// since, oldest change first, for incremental syncs.
+	ListModifiedSince(ctx context.Context, since time.Time, pageSize int32, pageToken string) (*ListResult, error)
+	// IncrementStock atomically adds quantity to the product's stock and
+	// returns the updated product.
+	IncrementStock(ctx context.Context, id string, quantity int32) (*Product, error)
+	// UpdateProduct overwrites a product's editable fields, optionally only
+	// if it is still at expectedVersion, and returns the updated product.
+	UpdateProduct(ctx context.Context, product *Product, expectedVersion int64) (*Product, error)
+	// DecrementStock atomically removes quantity from the product's stock
+	// and returns the updated product, failing with ErrInsufficientStock
+	// rather than letting the stock go negative.
+	DecrementStock(ctx context.Context, id string, quantity int32) (*Product, error)
+	// DeleteProductsByCategory removes every product in category and
+	// returns how many were removed.
+	DeleteProductsByCategory(ctx context.Context, category string) (int32, error)
+	StreamProducts(ctx context.Context, opts ListOptions, fn func(*Product) error) error
+	// StreamAll invokes fn for every stored product, holding at most one
+	// scan batch in memory. It stops when ctx is done or fn returns an error.
+	StreamAll(ctx context.Context, fn func(*Product) error) error
+	ListCategories(ctx context.Context) ([]CategoryCount, error)
+	// CatalogStats returns product count, price and stock totals for the
+	// whole catalog and per category.
+	CatalogStats(ctx context.Context) (*CatalogStats, error)
+	// SuggestProducts returns up to limit product names completing prefix.
+	SuggestProducts(ctx context.Context, prefix string, limit int) ([]string, error)
+	// Reindex rebuilds the search index from the stored products, resuming
+	// from persisted progress unless restart is set.
+	Reindex(ctx context.Context, restart bool, fn func(ReindexProgress) error) error
+	// CatalogChecksum returns an order-independent digest of every stored
+	// product and the number of products it covers.
+	CatalogChecksum(ctx context.Context) (string, int64, error)
+	WatchExpirations(ctx context.Context, fn func(id string) error) error
 	Close() error
 }
 
+// ListOptions describes the page, filters and ordering applied by
+// ListProducts. A zero MaxPrice means there is no upper price bound and an
+// empty SortBy sorts by price.
+type ListOptions struct {
+	Page        int32
+	PageSize    int32
+	Category    string
+	SearchQuery string
+	MinPrice    float64
+	MaxPrice    float64
+	SortBy      string
+	SortDesc    bool
+
+	// Fuzzy tolerates one typo per search term (of three or more characters)
+	// at the cost of more matches and slower queries.
+	Fuzzy bool
+
+	// IncludeScore requests relevance scores in Product.Score.
+	IncludeScore bool
+
+	// Highlight requests the matches of SearchQuery in
+	// Product.HighlightedName and Product.HighlightedDescription.
+	Highlight bool
+
+	// PageToken continues from a previous ListResult.NextPageToken and takes
+	// precedence over Page.
+	PageToken string
+
+	// ModifiedSince, when set, restricts the listing to products updated at
+	// or after it, to the second.
+	ModifiedSince time.Time
+
+	// MinStock, when positive, restricts the listing to products with at
+	// least that much stock; 1 hides out-of-stock products.
+	MinStock int32
+
+	// Tags restricts the listing to products carrying every one of them,
+	// matched like Category.
+	Tags []string
+}
+
+// ListResult is one page of ListProducts results. NextPageToken is empty on
+// the last page.
+type ListResult struct {
+	Products      []*Product
+	Total         int32
+	NextPageToken string
+}
+
+const (
+	SortByPrice     = "price"
+	SortByName      = "name"
+	SortByStock     = "stock"
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+
+	// SortByRelevance orders search results best match first by their
+	// RediSearch score. It is not an index field; listings that don't go
+	// through the index fall back to the default price order.
+	SortByRelevance = "relevance"
+)
+
+// sortableIndexField is an index field created SORTABLE, which RediSearch
+// requires for SORTBY. weight scales the relevance of matches in a text
+// field.
+type sortableIndexField struct {
+	name    string
+	numeric bool
+	weight  float64
+}
+
+// sortableIndexFields are the only fields ListProducts sorts on. createIndex
+// builds them from this list so the validation can't drift from the schema,
+// though an index created by an earlier version may still lack some; see
+// indexSortField.
+var sortableIndexFields = []sortableIndexField{
+	// A term in the name says more about a product than one in its
+	// description.
+	{name: SortByName, weight: 2},
+	{name: SortByPrice, numeric: true},
+	{name: SortByStock, numeric: true},
+	{name: SortByCreatedAt, numeric: true},
+	{name: SortByUpdatedAt, numeric: true},
+}
+
+var ErrUnsortableField = errors.New("field is not sortable")
+
+func lookupSortableField(field string) (sortableIndexField, bool) {
+	field = sortField(field)
+	for _, f := range sortableIndexFields {
+		if f.name == field {
+			return f, true
+		}
+	}
+	return sortableIndexField{}, false
+}
+
+// ValidSortField reports whether ListProducts can sort on the given field,
+// i.e. whether it maps to a sortable index field or is SortByRelevance. An
+// empty field selects the default price sort.
+func ValidSortField(field string) bool {
+	_, ok := lookupSortableField(field)
+	return ok || field == SortByRelevance
+}
+
+// indexSortField returns the sortable field for sortBy, failing with
+// ErrUnsortableField unless the live search index has it.
+func (r *RedisRepository) indexSortField(sortBy string) (sortableIndexField, error) {
+	field, ok := lookupSortableField(sortBy)
+	if !ok {
+		return sortableIndexField{}, fmt.Errorf("%w: %s", ErrUnsortableField, sortBy)
+	}
+	if r.indexFields != nil && !r.indexFields[field.name] {
+		return sortableIndexField{}, fmt.Errorf("%w: %s is not in the search index", ErrUnsortableField, field.name)
+	}
+	return field, nil
+}
+
 type RedisRepository struct {
-	client        *redis.Client
+	client        redis.UniversalClient
 	search        *redisearch.Client
+	suggester     *redisearch.Autocompleter
 	logger        *zap.Logger
 	indexName     string
 	searchEnabled bool
+
+	mgetBatchSize   int
+	mgetParallelism int
+
+	categories *categoryCache
+	stats      *statsCache
+	names      *nameCache
+	products   *productCache
+	memIndex   *memoryIndex
+
+	seedTarget     int
+	seedUpsertBase bool
+
+	// rewriteMigrations stores products upgraded by GetProduct back to
+	// Redis, instead of upgrading them again on every read.
+	rewriteMigrations bool
+
+	reindexRate float64
+
+	// idempotencyTTL is how long an idempotency key maps to the product
+	// created with it.
+	idempotencyTTL time.Duration
+	// enricher adjusts products before CreateProduct and UpdateProduct
+	// store them.
+	enricher ProductEnricher
+
+	// indexConcurrency bounds how many bulk-written batches are indexed at
+	// once while later batches are written.
+	indexConcurrency int
+	// indexer indexes the batches stored by CreateProducts and
+	// UpsertProducts in the background, so that callers writing batch after batch overlap writing
+	// with indexing. Close waits for it.
+	indexer *bulkIndexer
+
+	// recreateIndex drops and rebuilds a search index whose schema predates
+	// the current one.
+	recreateIndex bool
+
+	// dedupResults drops repeated documents from search result pages.
+	dedupResults bool
+
+	// listAllWithSearch pages through unfiltered listings with a wildcard
+	// search rather than a keyspace scan.
+	listAllWithSearch bool
+
+	// dedupWindow is how long creates without an idempotency key return an
+	// earlier product with the same content. Zero disables deduplication.
+	dedupWindow time.Duration
+
+	// indexFields are the fields of the live search index, as read from
+	// FT.INFO at startup, so that sorts on fields it lacks are rejected
+	// before querying. It is nil when the schema is unknown.
+	indexFields map[string]bool
 }
 
 const (
-	productsKeyPrefix  = "product:"
-	defaultIndexName   = "products-index"
-	targetSeedProducts = 100000
-	seedScanBatchSize  = 1000
+	productsKeyPrefix = "product:"
+	defaultIndexName  = "products-index"
+	seedScanBatchSize = 1000
+	// seedWriteBatchSize is how many seeded products are stored and indexed
+	// per round trip.
+	seedWriteBatchSize = 1000
+
+	expiredEventsPattern = "__keyevent@*__:expired"
 )
 
 var seedProducts = []*Product{
@@ -101,28 +379,75 @@ var seedCategories = []string{
 	"Books",
 }
 
-func NewRedisRepository(addr string, logger *zap.Logger) (*RedisRepository, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr: addr,
-	})
+func NewRedisRepository(opts Options, logger *zap.Logger) (*RedisRepository, error) {
+	enricher, err := NewProductEnricher(opts.Enricher)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newRedisClient(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	// Test connection
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
 	}
+	logger.Info("Connected to Redis",
+		zap.String("mode", opts.RedisMode),
+		zap.Strings("addrs", opts.RedisAddrs),
+		zap.Int("pool_size", effectivePoolSize(opts.PoolSize)),
+		zap.Duration("dial_timeout", opts.DialTimeout),
+		zap.Duration("read_timeout", opts.ReadTimeout),
+		zap.Duration("write_timeout", opts.WriteTimeout),
+		zap.Int("max_retries", opts.MaxRetries),
+	)
 
 	repo := &RedisRepository{
-		client:    client,
-		logger:    logger,
-		indexName: defaultIndexName,
+		client:          client,
+		logger:          logger,
+		indexName:       defaultIndexName,
+		mgetBatchSize:   opts.MGetBatchSize,
+		mgetParallelism: opts.MGetParallelism,
+		categories:      &categoryCache{ttl: opts.CategoriesCacheTTL, eager: opts.CategoriesEager},
+		stats:           &statsCache{ttl: opts.StatsCacheTTL},
+		names:           &nameCache{ttl: opts.SuggestionsCacheTTL},
+		products:        newProductCache(opts.ProductCacheSize, opts.ProductCacheTTL),
+		seedTarget:      opts.SeedTargetCount,
+		seedUpsertBase:  opts.SeedUpsertBase,
+
+		rewriteMigrations: opts.SchemaRewrite,
+		reindexRate:       opts.ReindexRate,
+		idempotencyTTL:    opts.IdempotencyKeyTTL,
+		dedupWindow:       opts.CreateDedupWindow,
+		recreateIndex:     opts.RecreateIndex,
+		dedupResults:      opts.DedupResults,
+		listAllWithSearch: opts.ListAllWithSearch,
+		indexConcurrency:  opts.IndexConcurrency,
+		enricher:          enricher,
+	}
+	repo.indexer = repo.newBulkIndexer(opts.IndexConcurrency)
+
+	if opts.NotifyExpirations {
+		err := repo.forEachNode(ctx, func(ctx context.Context, node *redis.Client) error {
+			return node.ConfigSet(ctx, "notify-keyspace-events", "Ex").Err()
+		})
+		if err != nil {
+			logger.Warn("Failed to enable keyspace expiry notifications", zap.Error(err))
+		}
 	}
 
-	if err := repo.detectRediSearch(ctx); err != nil {
+	if err := repo.detectRediSearch(ctx, opts); err != nil {
 		logger.Warn("RediSearch module not available; search features disabled", zap.Error(err))
+	} else if addr, err := searchAddr(ctx, opts); err != nil {
+		logger.Warn("Failed to resolve RediSearch address; search features disabled", zap.Error(err))
 	} else {
 		repo.searchEnabled = true
 		repo.search = redisearch.NewClient(addr, repo.indexName)
+		repo.suggester = redisearch.NewAutocompleter(addr, suggestionsKey)
+		logger.Info("Connected to RediSearch", zap.String("addr", addr))
 	}
 
 	// Create search index if it doesn't exist
@@ -131,178 +456,301 @@ func NewRedisRepository(addr string, logger *zap.Logger) (*RedisRepository, erro
 	}
 
 	// Seed initial data if needed
-	if err := repo.seedData(ctx); err != nil {
-		logger.Warn("Failed to seed data", zap.Error(err))
+	if opts.SeedEnabled {
+		if err := repo.seedData(ctx); err != nil {
+			logger.Warn("Failed to seed data", zap.Error(err))
+		}
+
+		if err := repo.verifySeedData(ctx); err != nil {
+			logger.Warn("Product data verification failed", zap.Error(err))
+		}
+	} else {
+		logger.Info("Product seeding disabled")
 	}
 
-	if err := repo.verifySeedData(ctx); err != nil {
-		logger.Warn("Product data verification failed", zap.Error(err))
+	if opts.CategoriesEager {
+		if err := repo.RefreshCategories(ctx); err != nil {
+			logger.Warn("Failed to preload categories", zap.Error(err))
+		}
+	}
+
+	if opts.MemoryIndexEnabled && !repo.searchEnabled {
+		repo.buildMemoryIndex(ctx, opts.MemoryIndexMaxProducts)
 	}
 
 	return repo, nil
 }
 
+// categoryTagSeparator separates the values of the category TAG field. It
+// is a control character rather than the default comma so that categories
+// such as "Home, Garden" stay a single tag.
+const categoryTagSeparator = '\x1f'
+
+// categoryTagField is the TAG index field category filters match. The
+// category is indexed as TEXT as well, so that its words stay searchable,
+// and a separate field name lets indexes that predate the TAG field gain it
+// with FT.ALTER.
+const categoryTagField = "category_tag"
+
 func (r *RedisRepository) createIndex(ctx context.Context) error {
 	if !r.searchEnabled || r.search == nil {
 		return nil
 	}
 
 	schema := redisearch.NewSchema(redisearch.DefaultOptions).
-		AddField(redisearch.NewTextField("name")).
 		AddField(redisearch.NewTextField("description")).
 		AddField(redisearch.NewTextField("category")).
-		AddField(redisearch.NewNumericField("price")).
-		AddField(redisearch.NewNumericField("stock"))
+		AddField(redisearch.NewTagFieldOptions(categoryTagField, redisearch.TagFieldOptions{
+			Separator: categoryTagSeparator,
+		})).
+		AddField(redisearch.NewTagFieldOptions(tagsField, redisearch.TagFieldOptions{
+			Separator: categoryTagSeparator,
+		})).
+		AddField(redisearch.NewTagField(currencyField))
+	for _, field := range sortableIndexFields {
+		if field.numeric {
+			schema.AddField(redisearch.NewSortableNumericField(field.name))
+		} else {
+			schema.AddField(redisearch.NewSortableTextField(field.name, float32(field.weight)))
+		}
+	}
+
+	err := r.search.CreateIndex(schema)
+	if err == nil {
+		r.indexFields = indexFieldNames(schema.Fields)
+		return nil
+	}
+	// Index might already exist, which is fine
+	r.logger.Debug("Index creation returned error (might already exist)", zap.Error(err))
+
+	info, err := r.search.Info()
+	if err != nil {
+		return fmt.Errorf("failed to read search index info: %w", err)
+	}
+
+	tagCategory := tagCategoryIndex(info.Schema.Fields)
+	if tagCategory && r.recreateIndex {
+		if err := r.recreateSearchIndex(schema); err != nil {
+			return err
+		}
+		r.indexFields = indexFieldNames(schema.Fields)
+		return nil
+	}
+
+	// Indexes created before a field was introduced, such as the sortable
+	// created_at and updated_at or the category TAG field, lack it until it
+	// is added.
+	r.indexFields = indexFieldNames(info.Schema.Fields)
+	if missing := missingIndexFields(schema.Fields, info.Schema.Fields); len(missing) > 0 {
+		for _, field := range r.addIndexFields(missing) {
+			r.indexFields[field] = true
+		}
+	}
+	if tagCategory {
+		r.logger.Warn("Search index has category as a TAG field only; category words won't match search queries until it is recreated with SEARCH_INDEX_RECREATE=true")
+	}
+	return nil
+}
 
+// tagCategoryIndex reports whether an index with fields has category as a
+// TAG rather than a TEXT field, as indexes created before categoryTagField
+// was introduced do.
+func tagCategoryIndex(fields []redisearch.Field) bool {
+	for _, field := range fields {
+		if field.Name == "category" {
+			return field.Type == redisearch.TagField
+		}
+	}
+	return false
+}
+
+// recreateSearchIndex drops the index, keeping the stored products, creates
+// it again with schema and repopulates it in the background.
+func (r *RedisRepository) recreateSearchIndex(schema *redisearch.Schema) error {
+	r.logger.Info("Recreating search index to migrate its schema")
+	if err := r.search.DropIndex(false); err != nil {
+		return fmt.Errorf("failed to drop search index: %w", err)
+	}
 	if err := r.search.CreateIndex(schema); err != nil {
-		// Index might already exist, which is fine
-		r.logger.Debug("Index creation returned error (might already exist)", zap.Error(err))
+		return fmt.Errorf("failed to recreate search index: %w", err)
 	}
+
+	r.repopulateIndex()
 	return nil
 }
 
+// seedData tops the catalog up to seedTarget products. Memory use is bounded
+// by the write batch size: the existing catalog is only counted, and products
+// are inserted with SET NX so that nothing already stored is overwritten.
 func (r *RedisRepository) seedData(ctx context.Context) error {
-	existing, err := r.collectExistingProductIDs(ctx)
+	if r.seedUpsertBase {
+		if err := r.upsertBaseSeeds(ctx); err != nil {
+			return err
+		}
+	}
+
+	count, err := r.countProducts(ctx, r.seedTarget)
 	if err != nil {
 		return err
 	}
 
-	if len(existing) >= targetSeedProducts {
-		r.logger.Info("Product catalog already seeded", zap.Int("count", len(existing)))
+	if count >= r.seedTarget {
+		r.logger.Info("Product catalog already seeded", zap.Int("count", count))
 		return nil
 	}
 
-	for _, product := range seedProducts {
-		if _, ok := existing[product.ID]; ok {
-			continue
-		}
+	base := make([]*Product, len(seedProducts))
+	for i, product := range seedProducts {
 		seed := *product
 		if seed.CreatedAt.IsZero() {
 			seed.CreatedAt = time.Now()
 		}
-		if err := r.CreateProduct(ctx, &seed); err != nil {
-			return fmt.Errorf("failed to seed base product %s: %w", product.ID, err)
-		}
-		existing[seed.ID] = struct{}{}
+		base[i] = &seed
 	}
+	indexer := r.newBulkIndexer(r.indexConcurrency)
+	defer indexer.wait()
 
-	if len(existing) >= targetSeedProducts {
-		r.logger.Info("Ensured product seed data present", zap.Int("count", len(existing)))
+	inserted, err := r.insertProducts(ctx, base, indexer)
+	if err != nil {
+		return fmt.Errorf("failed to seed base products: %w", err)
+	}
+	count += inserted
+
+	if count >= r.seedTarget {
+		r.logger.Info("Ensured product seed data present", zap.Int("count", count))
 		return nil
 	}
 
 	gofakeit.Seed(time.Now().UnixNano())
 
-	for len(existing) < targetSeedProducts {
-		id := fmt.Sprintf("seed-%s", strings.ReplaceAll(gofakeit.UUID(), "-", ""))
-		if _, ok := existing[id]; ok {
-			continue
-		}
-
-		product := &Product{
-			ID:          id,
-			Name:        gofakeit.ProductName(),
-			Description: gofakeit.ProductDescription(),
-			Price:       gofakeit.Price(5.0, 5000.0),
-			Category:    gofakeit.RandomString(seedCategories),
-			Stock:       int32(gofakeit.Number(0, 1000)),
-			CreatedAt:   time.Now(),
+	for count < r.seedTarget {
+		// A fresh slice per batch, since earlier batches may still be
+		// being indexed.
+		batch := make([]*Product, 0, seedWriteBatchSize)
+		for len(batch) < min(seedWriteBatchSize, r.seedTarget-count) {
+			batch = append(batch, &Product{
+				ID:          fmt.Sprintf("seed-%s", strings.ReplaceAll(gofakeit.UUID(), "-", "")),
+				Name:        gofakeit.ProductName(),
+				Description: gofakeit.ProductDescription(),
+				Price:       gofakeit.Price(5.0, 5000.0),
+				Category:    gofakeit.RandomString(seedCategories),
+				Stock:       int32(gofakeit.Number(0, 1000)),
+				CreatedAt:   time.Now(),
+			})
 		}
 
-		if err := r.CreateProduct(ctx, product); err != nil {
-			return fmt.Errorf("failed to seed product %s: %w", product.ID, err)
+		inserted, err := r.insertProducts(ctx, batch, indexer)
+		if err != nil {
+			return fmt.Errorf("failed to seed products: %w", err)
 		}
 
-		existing[id] = struct{}{}
-
-		if len(existing)%10000 == 0 {
-			r.logger.Info("Seeding products", zap.Int("count", len(existing)))
+		previous := count
+		count += inserted
+		if count/10000 > previous/10000 {
+			r.logger.Info("Seeding products", zap.Int("count", count))
 		}
 	}
 
-	r.logger.Info("Ensured product seed data present", zap.Int("count", len(existing)))
+	r.logger.Info("Ensured product seed data present", zap.Int("count", count))
 	return nil
 }
 
-func (r *RedisRepository) collectExistingProductIDs(ctx context.Context) (map[string]struct{}, error) {
-	existing := make(map[string]struct{}, targetSeedProducts)
-	var cursor uint64
-	pattern := productsKeyPrefix + "*"
+// upsertBaseSeeds overwrites stored base seed products whose content no
+// longer matches seedProducts, so edits to the hardcoded seeds reach
+// catalogs seeded by an earlier version. The stored creation time is kept.
+func (r *RedisRepository) upsertBaseSeeds(ctx context.Context) error {
+	keys := make([]string, len(seedProducts))
+	for i, product := range seedProducts {
+		keys[i] = r.keyFor(product.ID)
+	}
 
-	for {
-		keys, nextCursor, err := r.client.Scan(ctx, cursor, pattern, int64(seedScanBatchSize)).Result()
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan product keys: %w", err)
-		}
+	stored, err := r.mgetProducts(ctx, keys)
+	if err != nil {
+		return fmt.Errorf("failed to load base seed products: %w", err)
+	}
+	storedByID := make(map[string]*Product, len(stored))
+	for _, product := range stored {
+		storedByID[product.ID] = product
+	}
 
-		for _, key := range keys {
-			id := strings.TrimPrefix(key, productsKeyPrefix)
-			existing[id] = struct{}{}
+	var stale []*Product
+	for _, product := range seedProducts {
+		current, ok := storedByID[product.ID]
+		if !ok || sameSeedContent(current, product) {
+			continue
 		}
-
-		cursor = nextCursor
-		if cursor == 0 {
-			break
+		seed := *product
+		seed.CreatedAt = current.CreatedAt
+		seed.Version = current.Version + 1
+		seed.UpdatedAt = time.Now()
+		if seed.CreatedAt.IsZero() {
+			seed.CreatedAt = time.Now()
 		}
+		stale = append(stale, &seed)
 	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	if err := r.createProducts(ctx, stale, nil); err != nil {
+		return fmt.Errorf("failed to update base seed products: %w", err)
+	}
+	r.logger.Info("Updated changed base seed products", zap.Int("count", len(stale)))
+	return nil
+}
 
-	return existing, nil
+// sameSeedContent reports whether a stored product matches a seed on every
+// field the seed defines.
+func sameSeedContent(stored, seed *Product) bool {
+	return stored.Name == seed.Name &&
+		stored.Description == seed.Description &&
+		stored.Price == seed.Price &&
+		stored.Category == seed.Category &&
+		stored.Stock == seed.Stock
 }
 
+// errStopScan ends a scanProductKeys walk early without reporting an error.
+var errStopScan = errors.New("stop scan")
+
 func (r *RedisRepository) countProducts(ctx context.Context, shortCircuitAt int) (int, error) {
-	var cursor uint64
 	total := 0
-	pattern := productsKeyPrefix + "*"
-
-	for {
-		keys, nextCursor, err := r.client.Scan(ctx, cursor, pattern, int64(seedScanBatchSize)).Result()
-		if err != nil {
-			return 0, fmt.Errorf("failed to scan product keys: %w", err)
-		}
 
+	err := r.scanProductKeys(ctx, func(keys []string) error {
 		total += len(keys)
 		if shortCircuitAt > 0 && total >= shortCircuitAt {
-			return total, nil
-		}
-
-		cursor = nextCursor
-		if cursor == 0 {
-			break
+			return errStopScan
 		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return 0, err
 	}
 
 	return total, nil
 }
 
 func (r *RedisRepository) sampleProductID(ctx context.Context) (string, error) {
-	var cursor uint64
-	pattern := productsKeyPrefix + "*"
+	var id string
 
-	for {
-		keys, nextCursor, err := r.client.Scan(ctx, cursor, pattern, int64(seedScanBatchSize)).Result()
-		if err != nil {
-			return "", fmt.Errorf("failed to scan for sample product: %w", err)
-		}
-
-		if len(keys) > 0 {
-			return strings.TrimPrefix(keys[0], productsKeyPrefix), nil
-		}
-
-		cursor = nextCursor
-		if cursor == 0 {
-			break
-		}
+	err := r.scanProductKeys(ctx, func(keys []string) error {
+		id = strings.TrimPrefix(keys[0], productsKeyPrefix)
+		return errStopScan
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return "", fmt.Errorf("failed to scan for sample product: %w", err)
 	}
 
-	return "", nil
+	return id, nil
+}
+
+// newProductID returns a random UUID for products created without an ID.
+func newProductID() string {
+	return uuid.NewString()
 }
 
 func (r *RedisRepository) CreateProduct(ctx context.Context, product *Product) error {
-	if product.ID == "" {
-		product.ID = fmt.Sprintf("%d", time.Now().UnixNano())
-	}
-	if product.CreatedAt.IsZero() {
-		product.CreatedAt = time.Now()
+	if err := r.prepareNewProduct(ctx, product); err != nil {
+		return err
 	}
 
 	key := r.keyFor(product.ID)
@@ -311,143 +759,955 @@ func (r *RedisRepository) CreateProduct(ctx context.Context, product *Product) e
 		return fmt.Errorf("failed to marshal product: %w", err)
 	}
 
+	if err := r.reserveSKUs(ctx, []*Product{product})[0]; err != nil {
+		return err
+	}
 	if err := r.client.Set(ctx, key, data, 0).Err(); err != nil {
+		r.releaseSKU(ctx, product.SKU, product.ID)
 		return fmt.Errorf("failed to set product: %w", err)
 	}
+	r.products.invalidate(product.ID)
+	r.memIndex.add(product)
+	r.noteCategories(product)
 
 	// Index in RedisSearch
-	if r.searchEnabled && r.search != nil {
-		doc := redisearch.NewDocument(key, 1.0)
-		doc.Set("name", product.Name).
-			Set("description", product.Description).
-			Set("category", product.Category).
-			Set("price", product.Price).
-			Set("stock", product.Stock)
+	r.indexProduct(ctx, product, redisearch.DefaultIndexingOptions)
+	r.addSuggestions(ctx, []*Product{product})
+
+	return nil
+}
 
-		if err := r.search.Index([]redisearch.Document{doc}...); err != nil {
-			r.logger.Warn("Failed to index product", zap.Error(err))
+// prepareNewProduct fills in the ID, timestamps, versions and currency of a
+// product about to be created and enriches it. A price more precise than
+// the currency fails with ErrPricePrecision.
+func (r *RedisRepository) prepareNewProduct(ctx context.Context, product *Product) error {
+	if product.ID == "" {
+		product.ID = newProductID()
+	}
+	if product.CreatedAt.IsZero() {
+		product.CreatedAt = time.Now()
+	}
+	product.UpdatedAt = product.CreatedAt
+	product.SchemaVersion = CurrentSchemaVersion
+	product.Version = 1
+	product.Tags = normalizeTags(product.Tags)
+	if product.Currency == "" {
+		product.Currency = DefaultCurrency
+	}
+	if err := CheckPricePrecision(product.Price, product.Currency); err != nil {
+		return err
+	}
+	normalizePrice(product)
+	if err := r.enricher.Enrich(ctx, product); err != nil {
+		return fmt.Errorf("failed to enrich product: %w", err)
+	}
+	return nil
+}
+
+// createProducts stores products in a single pipeline and indexes them with
+// one batched search call, handed to indexer unless it is nil. It is the
+// bulk counterpart of CreateProduct.
+func (r *RedisRepository) createProducts(ctx context.Context, products []*Product, indexer *bulkIndexer) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	for _, product := range products {
+		product.SchemaVersion = CurrentSchemaVersion
+		if product.Version == 0 {
+			product.Version = 1
+		}
+		normalizePrice(product)
+		if product.UpdatedAt.IsZero() {
+			product.UpdatedAt = product.CreatedAt
+		}
+		data, err := json.Marshal(product)
+		if err != nil {
+			return fmt.Errorf("failed to marshal product %s: %w", product.ID, err)
 		}
+		pipe.Set(ctx, r.keyFor(product.ID), data, 0)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set products: %w", err)
 	}
 
+	r.productsStored(ctx, products, indexer)
 	return nil
 }
 
+// insertProducts stores the products whose IDs are not taken yet with SET NX
+// in a single pipeline, leaving existing products untouched, and returns how
+// many were inserted. The inserted products are indexed by indexer, or
+// before returning when it is nil.
+func (r *RedisRepository) insertProducts(ctx context.Context, products []*Product, indexer *bulkIndexer) (int, error) {
+	if len(products) == 0 {
+		return 0, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(products))
+	for i, product := range products {
+		product.SchemaVersion = CurrentSchemaVersion
+		if product.Version == 0 {
+			product.Version = 1
+		}
+		normalizePrice(product)
+		if product.UpdatedAt.IsZero() {
+			product.UpdatedAt = product.CreatedAt
+		}
+		data, err := json.Marshal(product)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal product %s: %w", product.ID, err)
+		}
+		cmds[i] = pipe.SetNX(ctx, r.keyFor(product.ID), data, 0)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to insert products: %w", err)
+	}
+
+	inserted := make([]*Product, 0, len(products))
+	for i, cmd := range cmds {
+		if cmd.Val() {
+			inserted = append(inserted, products[i])
+		}
+	}
+
+	r.productsStored(ctx, inserted, indexer)
+	return len(inserted), nil
+}
+
+// productsStored updates the caches, the in-memory index and the search
+// index after products were written in bulk. Search indexing is handed to
+// indexer when one is given.
+func (r *RedisRepository) productsStored(ctx context.Context, products []*Product, indexer *bulkIndexer) {
+	if len(products) == 0 {
+		return
+	}
+
+	for _, product := range products {
+		r.products.invalidate(product.ID)
+		r.memIndex.add(product)
+	}
+	r.noteCategories(products...)
+
+	if indexer != nil {
+		indexer.index(ctx, products)
+		return
+	}
+	// Replace so that overwritten products, such as upserted seeds, are
+	// reindexed rather than rejected as duplicates.
+	r.indexProducts(ctx, products, redisearch.IndexingOptions{Replace: true})
+	r.addSuggestions(ctx, products)
+}
+
+// indexProduct adds the product to the search index, if there is one.
+// Indexing failures are logged rather than returned since the product itself
+// has already been stored.
+func (r *RedisRepository) indexProduct(ctx context.Context, product *Product, opts redisearch.IndexingOptions) {
+	if !r.searchEnabled || r.search == nil {
+		return
+	}
+
+	if err := r.search.IndexOptions(opts, r.productDocument(product)); err != nil {
+		r.log(ctx).Warn("Failed to index product", zap.String("id", product.ID), zap.Error(err))
+	}
+}
+
+// indexProducts adds a batch of products to the search index in one
+// pipelined call. Like indexProduct, failures are only logged.
+func (r *RedisRepository) indexProducts(ctx context.Context, products []*Product, opts redisearch.IndexingOptions) {
+	if !r.searchEnabled || r.search == nil || len(products) == 0 {
+		return
+	}
+
+	docs := make([]redisearch.Document, len(products))
+	for i, product := range products {
+		docs[i] = r.productDocument(product)
+	}
+	if err := r.search.IndexOptions(opts, docs...); err != nil {
+		r.log(ctx).Warn("Failed to index product batch", zap.Int("count", len(docs)), zap.Error(err))
+	}
+}
+
+func (r *RedisRepository) productDocument(product *Product) redisearch.Document {
+	doc := redisearch.NewDocument(r.keyFor(product.ID), 1.0)
+	doc.Set("name", product.Name).
+		Set("description", product.Description).
+		Set("category", product.Category).
+		Set(categoryTagField, product.Category).
+		Set("price", product.Price).
+		Set("stock", product.Stock).
+		Set("created_at", product.CreatedAt.Unix()).
+		Set("updated_at", product.UpdatedAt.Unix()).
+		Set(tagsField, tagsValue(product.Tags)).
+		Set(currencyField, product.Currency)
+	return doc
+}
+
+// ReadSource says where a read was served from.
+type ReadSource string
+
+const (
+	ReadSourceCache   ReadSource = "cache"
+	ReadSourcePrimary ReadSource = "primary"
+)
+
+// GetProduct reads through the product cache when one is configured.
 func (r *RedisRepository) GetProduct(ctx context.Context, id string) (*Product, error) {
+	product, _, err := r.GetProductWithin(ctx, id, -1)
+	return product, err
+}
+
+func (r *RedisRepository) GetProductWithin(ctx context.Context, id string, maxStaleness time.Duration) (*Product, ReadSource, error) {
+	if product, ok := r.products.get(ctx, id, maxStaleness); ok {
+		return product, ReadSourceCache, nil
+	}
+
+	generation := r.products.currentGeneration()
 	key := r.keyFor(id)
 	data, err := r.client.Get(ctx, key).Result()
 	if errors.Is(err, redis.Nil) {
-		return nil, fmt.Errorf("product not found: %s", id)
+		return nil, ReadSourcePrimary, fmt.Errorf("%w: %s", ErrProductNotFound, id)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get product: %w", err)
+		return nil, ReadSourcePrimary, fmt.Errorf("failed to get product: %w", err)
 	}
 
-	var product Product
-	if err := json.Unmarshal([]byte(data), &product); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal product: %w", err)
+	product, migrated, err := decodeProduct([]byte(data))
+	if err != nil {
+		return nil, ReadSourcePrimary, fmt.Errorf("failed to unmarshal product: %w", err)
+	}
+	if migrated && r.rewriteMigrations {
+		r.rewriteMigrated(ctx, product, data)
 	}
 
-	return &product, nil
+	r.products.add(product, generation)
+	return product, ReadSourcePrimary, nil
 }
 
-func (r *RedisRepository) ListProducts(ctx context.Context, page, pageSize int32, category, searchQuery string) ([]*Product, int32, error) {
-	useSearch := searchQuery != "" && r.searchEnabled && r.search != nil
+func (r *RedisRepository) ListProducts(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 10
+	}
 
-	if useSearch {
-		query := redisearch.NewQuery(searchQuery)
-		if category != "" {
-			query = redisearch.NewQuery(fmt.Sprintf("%s @category:{%s}", searchQuery, category))
-		}
-		query.SetSortBy("price", false)
-		query.Limit(int((page-1)*pageSize), int(pageSize))
+	token, err := decodePageToken(opts.PageToken)
+	if err != nil {
+		return nil, err
+	}
 
-		docs, totalResults, err := r.search.Search(query)
+	// Category- and tag-only filters go through the index too, so that
+	// totals and pagination agree with category plus search queries. Unfiltered
+	// listings use a wildcard query, sparing a scan of the whole keyspace.
+	useIndex := opts.SearchQuery != "" || opts.Category != "" || len(opts.Tags) > 0 ||
+		!opts.ModifiedSince.IsZero() || r.listAllWithSearch
+	if useIndex && r.searchEnabled && r.search != nil {
+		return r.listWithSearch(ctx, opts, token)
+	}
+	if r.scanPaged(opts) {
+		return r.listWithScanCursor(ctx, opts, token)
+	}
+	return r.listWithScan(ctx, opts, token)
+}
+
+// ListModifiedSince lists by ascending update time, so a sync can resume
+// from the update time of the last product it received. Updates are
+// tracked to the second, so products changed in that second are returned
+// again.
+func (r *RedisRepository) ListModifiedSince(ctx context.Context, since time.Time, pageSize int32, pageToken string) (*ListResult, error) {
+	return r.ListProducts(ctx, ListOptions{
+		PageSize:      pageSize,
+		PageToken:     pageToken,
+		SortBy:        SortByUpdatedAt,
+		ModifiedSince: since,
+	})
+}
+
+func (r *RedisRepository) listWithSearch(ctx context.Context, opts ListOptions, token *pageToken) (*ListResult, error) {
+	// Relevance is RediSearch's own order, so it needs no SORTBY and
+	// always reports scores.
+	relevance := opts.SortBy == SortByRelevance
+	var sortable sortableIndexField
+	if !relevance {
+		var err error
+		sortable, err = r.indexSortField(opts.SortBy)
 		if err != nil {
-			return nil, 0, fmt.Errorf("search failed: %w", err)
+			return nil, err
+		}
+	}
+	withScores := opts.IncludeScore || relevance
+	field := sortable.name
+	queryString := buildSearchQuery(opts)
+	offset := int((opts.Page - 1) * opts.PageSize)
+
+	if token != nil {
+		switch {
+		case token.Keyset:
+			if token.Field != field || token.Desc != opts.SortDesc {
+				return nil, fmt.Errorf("%w: sort order changed", ErrInvalidPageToken)
+			}
+			if opts.SortDesc {
+				queryString += fmt.Sprintf(" @%s:[-inf %s]", field, formatFloat(token.After))
+			} else {
+				queryString += fmt.Sprintf(" @%s:[%s +inf]", field, formatFloat(token.After))
+			}
+			offset = token.Skip
+		default:
+			offset = token.Offset
 		}
+	}
 
-		products := make([]*Product, 0, len(docs))
-		for _, doc := range docs {
-			data, err := r.client.Get(ctx, doc.Id).Result()
-			if err != nil {
-				r.logger.Warn("Failed to get product", zap.String("key", doc.Id), zap.Error(err))
+	query := redisearch.NewQuery(queryString)
+	if !relevance {
+		query.SetSortBy(field, !opts.SortDesc)
+	}
+	query.Limit(offset, int(opts.PageSize))
+	if withScores {
+		query.SetFlags(redisearch.QueryWithScores)
+	}
+	highlight := opts.Highlight && opts.SearchQuery != ""
+	if highlight {
+		query.Highlight(highlightFields, highlightOpenTag, highlightCloseTag)
+	}
+
+	docs, totalResults, err := r.search.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	keys := make([]string, 0, len(docs))
+	scores := make(map[string]float64, len(docs))
+	highlighted := make(map[string]redisearch.Document, len(docs))
+	duplicates := 0
+	for _, doc := range docs {
+		if _, seen := scores[doc.Id]; seen {
+			duplicates++
+			if r.dedupResults {
 				continue
 			}
+		}
+		keys = append(keys, doc.Id)
+		scores[doc.Id] = float64(doc.Score)
+		if highlight {
+			highlighted[doc.Id] = doc
+		}
+	}
+	if duplicates > 0 {
+		searchDuplicates.Add(ctx, int64(duplicates))
+		r.log(ctx).Warn("Search returned duplicate documents; the index may have drifted",
+			zap.Int("duplicates", duplicates),
+			zap.Bool("dropped", r.dedupResults),
+		)
+	}
 
-			var product Product
-			if err := json.Unmarshal([]byte(data), &product); err != nil {
-				r.logger.Warn("Failed to unmarshal product", zap.String("key", doc.Id), zap.Error(err))
-				continue
+	products, err := r.fetchProducts(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(products) < len(keys) {
+		r.log(ctx).Warn("Some search results could not be fetched",
+			zap.Int("matched", len(keys)),
+			zap.Int("fetched", len(products)),
+		)
+	}
+
+	if withScores {
+		for _, product := range products {
+			product.Score = scores[r.keyFor(product.ID)]
+		}
+	}
+	if highlight {
+		for _, product := range products {
+			// The highlighted copy falls back to the stored text for a
+			// field without a match.
+			doc := highlighted[r.keyFor(product.ID)]
+			product.HighlightedName = product.Name
+			if name, ok := doc.Properties["name"].(string); ok {
+				product.HighlightedName = name
+			}
+			product.HighlightedDescription = product.Description
+			if description, ok := doc.Properties["description"].(string); ok {
+				product.HighlightedDescription = description
 			}
+		}
+	}
+
+	total := totalResults
+	if token != nil && token.Keyset {
+		total = token.Total
+	}
+
+	result := &ListResult{Products: products, Total: int32(total)}
+	if len(docs) == 0 || offset+len(docs) >= totalResults {
+		return result, nil
+	}
+
+	if relevance || !sortable.numeric {
+		result.NextPageToken = encodePageToken(pageToken{Offset: offset + len(docs), Total: total})
+		return result, nil
+	}
 
-			products = append(products, &product)
+	sortValue := func(doc redisearch.Document) (float64, bool) {
+		value, ok := doc.Properties[field].(string)
+		if !ok {
+			return 0, false
 		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		return parsed, err == nil
+	}
 
-		return products, int32(totalResults), nil
+	last, ok := sortValue(docs[len(docs)-1])
+	if !ok {
+		// Without the sort value there is nothing to continue from
+		result.NextPageToken = encodePageToken(pageToken{Offset: offset + len(docs), Total: total})
+		return result, nil
 	}
 
-	allKeys, err := r.client.Keys(ctx, productsKeyPrefix+"*").Result()
+	ties := 0
+	for i := len(docs) - 1; i >= 0; i-- {
+		value, ok := sortValue(docs[i])
+		if !ok || value != last {
+			break
+		}
+		ties++
+	}
+	if ties == len(docs) && token != nil && token.Keyset && token.After == last {
+		ties += token.Skip
+	}
+
+	result.NextPageToken = encodePageToken(pageToken{
+		Keyset: true,
+		After:  last,
+		Skip:   ties,
+		Field:  field,
+		Desc:   opts.SortDesc,
+		Total:  total,
+	})
+	return result, nil
+}
+
+func (r *RedisRepository) listWithScan(ctx context.Context, opts ListOptions, token *pageToken) (*ListResult, error) {
+	allKeys, err := r.listCandidateKeys(ctx, opts)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to get keys: %w", err)
+		return nil, fmt.Errorf("failed to get keys: %w", err)
 	}
 
-	searchQueryLower := strings.ToLower(searchQuery)
 	filtered := make([]*Product, 0, len(allKeys))
 
 	for _, key := range allKeys {
+		// A caller that gave up shouldn't keep driving reads for every key
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("listing stopped: %w", err)
+		}
+
 		data, err := r.client.Get(ctx, key).Result()
 		if err != nil {
-			r.logger.Warn("Failed to get product", zap.String("key", key), zap.Error(err))
+			r.log(ctx).Warn("Failed to get product", zap.String("key", key), zap.Error(err))
 			continue
 		}
 
-		var product Product
-		if err := json.Unmarshal([]byte(data), &product); err != nil {
-			r.logger.Warn("Failed to unmarshal product", zap.String("key", key), zap.Error(err))
+		product, _, err := decodeProduct([]byte(data))
+		if err != nil {
+			r.log(ctx).Warn("Failed to unmarshal product", zap.String("key", key), zap.Error(err))
 			continue
 		}
 
-		if category != "" && product.Category != category {
+		if !matchesFilters(product, opts) {
 			continue
 		}
 
-		if searchQuery != "" {
-			nameMatch := strings.Contains(strings.ToLower(product.Name), searchQueryLower)
-			descMatch := strings.Contains(strings.ToLower(product.Description), searchQueryLower)
-			if !nameMatch && !descMatch {
-				continue
-			}
+		if opts.IncludeScore || opts.SortBy == SortByRelevance {
+			product.Score = NoScore
 		}
 
-		filtered = append(filtered, &product)
+		filtered = append(filtered, product)
 	}
 
 	total := int32(len(filtered))
 	if total == 0 {
-		return []*Product{}, 0, nil
+		return &ListResult{Products: []*Product{}}, nil
 	}
 
-	if page < 1 {
-		page = 1
-	}
-	if pageSize <= 0 {
-		pageSize = 10
-	}
+	sortProducts(filtered, opts.SortBy, opts.SortDesc)
 
-	start := int((page - 1) * pageSize)
+	start := int((opts.Page - 1) * opts.PageSize)
+	if token != nil {
+		switch {
+		case token.Keyset:
+			return nil, fmt.Errorf("%w: token was issued by the search index", ErrInvalidPageToken)
+		case token.Scan:
+			return nil, fmt.Errorf("%w: sort order changed", ErrInvalidPageToken)
+		}
+		start = token.Offset
+	}
 	if start >= len(filtered) {
-		return []*Product{}, total, nil
+		return &ListResult{Products: []*Product{}, Total: total}, nil
 	}
 
-	end := start + int(pageSize)
+	end := start + int(opts.PageSize)
 	if end > len(filtered) {
 		end = len(filtered)
 	}
 
-	return filtered[start:end], total, nil
+	result := &ListResult{Products: filtered[start:end], Total: total}
+	if opts.Highlight && opts.SearchQuery != "" {
+		for _, product := range result.Products {
+			highlightProduct(product, opts.SearchQuery)
+		}
+	}
+	if end < len(filtered) {
+		result.NextPageToken = encodePageToken(pageToken{Offset: end, Total: int(total)})
+	}
+	return result, nil
+}
+
+// listScanBatchSize is the SCAN COUNT of listings paged by SCAN cursor.
+const listScanBatchSize = 100
+
+// scanPaged reports whether a fallback listing is paged by SCAN cursor: one
+// without an explicit sort, on a single node, that the in-memory index
+// can't narrow down. Cluster nodes each have their own cursor.
+func (r *RedisRepository) scanPaged(opts ListOptions) bool {
+	if opts.SortBy != "" || r.isCluster() {
+		return false
+	}
+	_, ok := r.memIndex.candidates(opts)
+	return !ok
+}
+
+// listWithScanCursor lists the products matching opts in keyspace order.
+// Its page tokens hold the SCAN cursor of the batch with the next match, so
+// later pages only read as far as they need. Without a token the whole
+// keyspace is read, to count the matches and to skip to opts.Page.
+func (r *RedisRepository) listWithScanCursor(ctx context.Context, opts ListOptions, token *pageToken) (*ListResult, error) {
+	node, ok := r.client.(*redis.Client)
+	if !ok {
+		return nil, fmt.Errorf("unsupported redis client %T", r.client)
+	}
+
+	var cursor uint64
+	skip := int((opts.Page - 1) * opts.PageSize)
+	offset, total := skip, 0
+	if token != nil {
+		if !token.Scan {
+			return nil, fmt.Errorf("%w: sort order changed", ErrInvalidPageToken)
+		}
+		cursor, skip, offset, total = token.Cursor, token.Skip, token.Offset, token.Total
+	}
+	counting := token == nil
+
+	products := make([]*Product, 0, opts.PageSize)
+	var next *pageToken
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("listing stopped: %w", err)
+		}
+
+		keys, nextCursor, err := node.Scan(ctx, cursor, productsKeyPrefix+"*", listScanBatchSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product keys: %w", err)
+		}
+		batch, err := r.fetchProducts(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+
+		matches := 0
+		for _, product := range batch {
+			if !matchesFilters(product, opts) {
+				continue
+			}
+			matches++
+			if counting {
+				total++
+			}
+			switch {
+			case skip > 0:
+				skip--
+			case len(products) < int(opts.PageSize):
+				products = append(products, product)
+			case next == nil:
+				next = &pageToken{Scan: true, Cursor: cursor, Skip: matches - 1, Offset: offset + len(products)}
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 || (next != nil && !counting) {
+			break
+		}
+	}
+
+	for _, product := range products {
+		if opts.IncludeScore || opts.SortBy == SortByRelevance {
+			product.Score = NoScore
+		}
+		if opts.Highlight && opts.SearchQuery != "" {
+			highlightProduct(product, opts.SearchQuery)
+		}
+	}
+	result := &ListResult{Products: products, Total: int32(total)}
+	if next != nil {
+		next.Total = total
+		result.NextPageToken = encodePageToken(*next)
+	}
+	return result, nil
+}
+
+// listCandidateKeys returns the keys listWithScan has to check: those the
+// in-memory index considers possible matches, or every product key.
+func (r *RedisRepository) listCandidateKeys(ctx context.Context, opts ListOptions) ([]string, error) {
+	if ids, ok := r.memIndex.candidates(opts); ok {
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = r.keyFor(id)
+		}
+		return keys, nil
+	}
+
+	var allKeys []string
+	err := r.scanProductKeys(ctx, func(keys []string) error {
+		allKeys = append(allKeys, keys...)
+		return nil
+	})
+	return allKeys, err
+}
+
+// StreamProducts invokes fn for every product matching the filters in opts.
+// Pagination and sort options are ignored.
+func (r *RedisRepository) StreamProducts(ctx context.Context, opts ListOptions, fn func(*Product) error) error {
+	return r.StreamAll(ctx, func(product *Product) error {
+		if !matchesFilters(product, opts) {
+			return nil
+		}
+		return fn(product)
+	})
+}
+
+// StreamAll scans the whole keyspace and invokes fn for every product.
+// Products are fetched one SCAN batch at a time, so the catalog is never held
+// in memory. Scanning stops when ctx is done or fn returns an error.
+func (r *RedisRepository) StreamAll(ctx context.Context, fn func(*Product) error) error {
+	return r.scanProductKeys(ctx, func(keys []string) error {
+		products, err := r.fetchProducts(ctx, keys)
+		if err != nil {
+			return err
+		}
+
+		for _, product := range products {
+			if err := fn(product); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// scanProductKeys walks the product keyspace with SCAN, passing each batch of
+// keys to fn. In cluster mode every master is scanned in turn. It stops early
+// when ctx is done or fn returns an error.
+func (r *RedisRepository) scanProductKeys(ctx context.Context, fn func(keys []string) error) error {
+	pattern := productsKeyPrefix + "*"
+
+	return r.forEachNode(ctx, func(ctx context.Context, node *redis.Client) error {
+		var cursor uint64
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			keys, nextCursor, err := node.Scan(ctx, cursor, pattern, int64(seedScanBatchSize)).Result()
+			if err != nil {
+				return fmt.Errorf("failed to scan product keys: %w", err)
+			}
+
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+
+			cursor = nextCursor
+			if cursor == 0 {
+				return nil
+			}
+		}
+	})
+}
+
+// matchesFilters applies the category, price, stock, tag and search filters of opts to a
+// single product, mirroring what the search index does in the search path.
+func matchesFilters(product *Product, opts ListOptions) bool {
+	if opts.Category != "" && categoryTag(product.Category) != categoryTag(opts.Category) {
+		return false
+	}
+
+	if product.Price < opts.MinPrice {
+		return false
+	}
+	if opts.MaxPrice > 0 && product.Price > opts.MaxPrice {
+		return false
+	}
+	if !opts.ModifiedSince.IsZero() && product.UpdatedAt.Unix() < opts.ModifiedSince.Unix() {
+		return false
+	}
+	if opts.MinStock > 0 && product.Stock < opts.MinStock {
+		return false
+	}
+	if len(opts.Tags) > 0 && !hasTags(product, opts.Tags) {
+		return false
+	}
+
+	if opts.SearchQuery != "" && opts.Fuzzy {
+		if !fuzzyContains(product.Name, opts.SearchQuery) && !fuzzyContains(product.Description, opts.SearchQuery) {
+			return false
+		}
+	} else if opts.SearchQuery != "" {
+		searchQueryLower := strings.ToLower(opts.SearchQuery)
+		nameMatch := strings.Contains(strings.ToLower(product.Name), searchQueryLower)
+		descMatch := strings.Contains(strings.ToLower(product.Description), searchQueryLower)
+		if !nameMatch && !descMatch {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortField(field string) string {
+	if field == "" {
+		return SortByPrice
+	}
+	return field
+}
+
+// sortProducts orders products in place the same way the search index would,
+// breaking ties by ID so pagination is stable.
+func sortProducts(products []*Product, field string, desc bool) {
+	compare := func(a, b *Product) int {
+		switch sortField(field) {
+		case SortByName:
+			return strings.Compare(a.Name, b.Name)
+		case SortByStock:
+			return int(a.Stock) - int(b.Stock)
+		case SortByCreatedAt:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case SortByUpdatedAt:
+			return a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			switch {
+			case a.Price < b.Price:
+				return -1
+			case a.Price > b.Price:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		c := compare(products[i], products[j])
+		if c == 0 {
+			return products[i].ID < products[j].ID
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// categoryTag normalizes a category the way RediSearch normalizes the values
+// of the case-insensitive category TAG field, so that filtering compares
+// categories alike with and without the index.
+func categoryTag(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// escapeTagValue backslash-escapes every character of a TAG query value
+// that RediSearch treats as syntax, i.e. everything but letters, digits and
+// underscores, so that values with braces, pipes or spaces match literally.
+func escapeTagValue(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// buildSearchQuery translates the list filters into a RediSearch query
+// string, matching every product when there are none.
+func buildSearchQuery(opts ListOptions) string {
+	var clauses []string
+	if opts.SearchQuery != "" {
+		query := opts.SearchQuery
+		if opts.Fuzzy {
+			query = fuzzySearchQuery(query)
+		}
+		clauses = append(clauses, query)
+	}
+	if opts.Category != "" {
+		clauses = append(clauses, fmt.Sprintf("@%s:{%s}", categoryTagField, escapeTagValue(categoryTag(opts.Category))))
+	}
+	if opts.MinPrice > 0 || opts.MaxPrice > 0 {
+		upper := "+inf"
+		if opts.MaxPrice > 0 {
+			upper = formatFloat(opts.MaxPrice)
+		}
+		clauses = append(clauses, fmt.Sprintf("@price:[%s %s]", formatFloat(opts.MinPrice), upper))
+	}
+	if !opts.ModifiedSince.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("@%s:[%d +inf]", SortByUpdatedAt, opts.ModifiedSince.Unix()))
+	}
+	if opts.MinStock > 0 {
+		clauses = append(clauses, fmt.Sprintf("@%s:[%d +inf]", SortByStock, opts.MinStock))
+	}
+	for _, tag := range opts.Tags {
+		clauses = append(clauses, fmt.Sprintf("@%s:{%s}", tagsField, escapeTagValue(categoryTag(tag))))
+	}
+	if len(clauses) == 0 {
+		return "*"
+	}
+	return strings.Join(clauses, " ")
+}
+
+// fetchProducts loads the given keys with MGET, preserving the order of keys
+// in the result. Keys are split into chunks of mgetBatchSize that are fetched
+// concurrently, at most mgetParallelism at a time. Missing or undecodable
+// entries are skipped.
+func (r *RedisRepository) fetchProducts(ctx context.Context, keys []string) ([]*Product, error) {
+	if len(keys) == 0 {
+		return []*Product{}, nil
+	}
+
+	batchSize := r.mgetBatchSize
+	if batchSize <= 0 || batchSize > len(keys) {
+		batchSize = len(keys)
+	}
+	parallelism := r.mgetParallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	chunks := make([][]string, 0, (len(keys)+batchSize-1)/batchSize)
+	for start := 0; start < len(keys); start += batchSize {
+		end := start + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunks = append(chunks, keys[start:end])
+	}
+
+	if len(chunks) == 1 {
+		return r.mgetProducts(ctx, chunks[0])
+	}
+
+	results := make([][]*Product, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for i, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			wg.Wait()
+			return nil, err
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, chunk []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = r.mgetProducts(ctx, chunk)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	products := make([]*Product, 0, len(keys))
+	for i := range chunks {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		products = append(products, results[i]...)
+	}
+
+	return products, nil
+}
+
+// WatchExpirations invokes fn with the ID of every product key that expires
+// until ctx is cancelled or fn returns an error. Redis must have expired
+// keyspace events enabled (notify-keyspace-events containing "Ex"). A node
+// only publishes the expiry of its own keys, so in cluster mode every
+// master is subscribed to; masters added after the call starts are not.
+func (r *RedisRepository) WatchExpirations(ctx context.Context, fn func(id string) error) error {
+	var subscriptions []*redis.PubSub
+	defer func() {
+		for _, pubsub := range subscriptions {
+			pubsub.Close()
+		}
+	}()
+	err := r.forEachNode(ctx, func(ctx context.Context, node *redis.Client) error {
+		pubsub := node.PSubscribe(ctx, expiredEventsPattern)
+		subscriptions = append(subscriptions, pubsub)
+		_, err := pubsub.Receive(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to expiry events: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan *redis.Message)
+	for _, pubsub := range subscriptions {
+		go func(messages <-chan *redis.Message) {
+			for msg := range messages {
+				select {
+				case events <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(pubsub.Channel())
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-events:
+			if !strings.HasPrefix(msg.Payload, productsKeyPrefix) {
+				continue
+			}
+			if err := fn(strings.TrimPrefix(msg.Payload, productsKeyPrefix)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Ping checks that Redis is reachable.
+func (r *RedisRepository) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// SearchEnabled reports whether RediSearch backs search and filtering.
+func (r *RedisRepository) SearchEnabled() bool {
+	return r.searchEnabled && r.search != nil
+}
+
+// log returns the request-scoped logger for ctx, so warnings raised while
+// serving a call can be tied back to it.
+func (r *RedisRepository) log(ctx context.Context) *zap.Logger {
+	return observability.LoggerFromContext(ctx, r.logger)
 }
 
 func (r *RedisRepository) Close() error {
+	r.indexer.wait()
 	return r.client.Close()
 }
 
@@ -455,21 +1715,34 @@ func (r *RedisRepository) keyFor(id string) string {
 	return fmt.Sprintf("%s%s", productsKeyPrefix, id)
 }
 
-func (r *RedisRepository) detectRediSearch(ctx context.Context) error {
-	if _, err := r.client.Do(ctx, "FT._LIST").Result(); err != nil {
+// detectRediSearch checks that the search endpoint serves RediSearch. That
+// is Redis itself unless SearchAddr points elsewhere.
+func (r *RedisRepository) detectRediSearch(ctx context.Context, opts Options) error {
+	client := redis.UniversalClient(r.client)
+	if opts.SearchAddr != "" {
+		search := redis.NewClient(&redis.Options{
+			Addr:        opts.SearchAddr,
+			DialTimeout: opts.DialTimeout,
+			ReadTimeout: opts.ReadTimeout,
+		})
+		defer search.Close()
+		client = search
+	}
+
+	if _, err := client.Do(ctx, "FT._LIST").Result(); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (r *RedisRepository) verifySeedData(ctx context.Context) error {
-	total, err := r.countProducts(ctx, targetSeedProducts)
+	total, err := r.countProducts(ctx, r.seedTarget)
 	if err != nil {
 		return err
 	}
 
-	if total < targetSeedProducts {
-		return fmt.Errorf("insufficient seed data: have %d products, expected at least %d", total, targetSeedProducts)
+	if total < r.seedTarget {
+		return fmt.Errorf("insufficient seed data: have %d products, expected at least %d", total, r.seedTarget)
 	}
 
 	sampleID, err := r.sampleProductID(ctx)
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"go.uber.org/zap"
+)
+
+// newTestRepository returns a repository backed by an in-process Redis
+// without RediSearch, so listings take the scan path. opts may adjust the
+// options before the repository is created; the Redis address is filled in.
+func newTestRepository(tb testing.TB, opts ...func(*Options)) (*RedisRepository, *miniredis.Miniredis) {
+	tb.Helper()
+
+	server := miniredis.RunT(tb)
+	options := Options{
+		RedisMode:       RedisModeSingle,
+		RedisAddrs:      []string{server.Addr()},
+		MGetBatchSize:   100,
+		MGetParallelism: 4,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	repo, err := NewRedisRepository(options, zap.NewNop())
+	if err != nil {
+		tb.Fatalf("NewRedisRepository: %v", err)
+	}
+	tb.Cleanup(func() { repo.Close() })
+	return repo, server
+}
+
+// createTestProducts creates n products named "Product <i>" with prices
+// 1 to n.
+func createTestProducts(tb testing.TB, repo *RedisRepository, n int) []*Product {
+	tb.Helper()
+
+	products := make([]*Product, n)
+	for i := range products {
+		products[i] = &Product{
+			ID:       createTestID(i),
+			Name:     fmt.Sprintf("Product %d", i+1),
+			Category: "Test",
+			Price:    float64(i + 1),
+			Stock:    10,
+		}
+		if err := repo.CreateProduct(context.Background(), products[i]); err != nil {
+			tb.Fatalf("CreateProduct(%s): %v", products[i].ID, err)
+		}
+	}
+	return products
+}
+
+// createTestID is the ID createTestProducts gives its i-th product.
+func createTestID(i int) string {
+	return fmt.Sprintf("p%03d", i+1)
+}
+
+func productIDs(products []*Product) []string {
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	return ids
+}
+
+func TestCreateProductConcurrentIDsAreUnique(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+
+	const creates = 200
+	ids := make([]string, creates)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			product := &Product{Name: fmt.Sprintf("Product %d", i), Category: "Test", Price: 1}
+			if err := repo.CreateProduct(ctx, product); err != nil {
+				t.Errorf("CreateProduct %d: %v", i, err)
+				return
+			}
+			ids[i] = product.ID
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool, creates)
+	for i, id := range ids {
+		if id == "" {
+			continue
+		}
+		if seen[id] {
+			t.Errorf("create %d reused the ID %s", i, id)
+		}
+		seen[id] = true
+	}
+
+	count, err := repo.countProducts(ctx, 0)
+	if err != nil {
+		t.Fatalf("countProducts: %v", err)
+	}
+	if count != creates {
+		t.Errorf("stored %d products, want %d", count, creates)
+	}
+
+	// A caller-supplied ID is kept.
+	product := &Product{ID: "chosen", Name: "Chosen", Category: "Test", Price: 1}
+	if err := repo.CreateProduct(ctx, product); err != nil {
+		t.Fatalf("CreateProduct with an ID: %v", err)
+	}
+	if product.ID != "chosen" {
+		t.Errorf("CreateProduct replaced the ID chosen with %s", product.ID)
+	}
+}
+
+// fakeSearchFilter matches the clauses of the RediSearch queries
+// buildSearchQuery writes for category and tag filters, and the price
+// ranges of keyset page tokens. fakeFieldClause matches any field clause,
+// leaving the free-text terms.
+var (
+	fakeCategoryClause = regexp.MustCompile(`@category_tag:\{((?:\\.|[^\\}])*)\}`)
+	fakePriceClause    = regexp.MustCompile(`@price:\[(\S+) (\S+)\]`)
+	fakeTagsClause     = regexp.MustCompile(`@tags:\{((?:\\.|[^\\}])*)\}`)
+	fakeFieldClause    = regexp.MustCompile(`@\w+:(?:\{(?:\\.|[^\\}])*\}|\[[^\]]*\])`)
+)
+
+// enableFakeSearch points repo at an FT.SEARCH served by redis over the
+// given products, so tests can compare the search path with the scan
+// path. The fake understands category and tag filters, price ranges and
+// free-text terms, which it scores by their occurrences in the name,
+// weighted like the index, and in the description. It sorts by price, or best score first
+// when the query has no SORTBY, reports scores WITHSCORES and pages with
+// LIMIT; it does not index later writes.
+func enableFakeSearch(tb testing.TB, repo *RedisRepository, redis *miniredis.Miniredis, products []*Product) {
+	tb.Helper()
+
+	err := redis.Server().Register("FT.SEARCH", func(c *server.Peer, cmd string, args []string) {
+		query := args[1]
+		var category string
+		filtered := false
+		if m := fakeCategoryClause.FindStringSubmatch(query); m != nil {
+			category, filtered = strings.ReplaceAll(m[1], `\`, ""), true
+		}
+		low, high := math.Inf(-1), math.Inf(1)
+		if m := fakePriceClause.FindStringSubmatch(query); m != nil {
+			low, _ = strconv.ParseFloat(m[1], 64)
+			high, _ = strconv.ParseFloat(m[2], 64)
+		}
+		var tags []string
+		for _, m := range fakeTagsClause.FindAllStringSubmatch(query, -1) {
+			tags = append(tags, strings.ReplaceAll(m[1], `\`, ""))
+		}
+		var terms []string
+		for _, term := range strings.Fields(fakeFieldClause.ReplaceAllString(query, "")) {
+			if term != "*" {
+				terms = append(terms, strings.ToLower(term))
+			}
+		}
+		offset, limit := 0, 10
+		sorted, withScores := false, false
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "LIMIT":
+				if i+2 < len(args) {
+					offset, _ = strconv.Atoi(args[i+1])
+					limit, _ = strconv.Atoi(args[i+2])
+				}
+			case "SORTBY":
+				sorted = true
+			case "WITHSCORES":
+				withScores = true
+			}
+		}
+
+		var matches []*Product
+		scores := make(map[string]float64)
+		for _, product := range products {
+			if filtered && categoryTag(product.Category) != category {
+				continue
+			}
+			if product.Price < low || product.Price > high {
+				continue
+			}
+			if !hasTags(product, tags) {
+				continue
+			}
+			score := 1.0
+			if len(terms) > 0 {
+				score = 0
+				for _, term := range terms {
+					score += 2*float64(strings.Count(strings.ToLower(product.Name), term)) +
+						float64(strings.Count(strings.ToLower(product.Description), term))
+				}
+				if score == 0 {
+					continue
+				}
+			}
+			scores[product.ID] = score
+			matches = append(matches, product)
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			if !sorted && scores[matches[i].ID] != scores[matches[j].ID] {
+				return scores[matches[i].ID] > scores[matches[j].ID]
+			}
+			if sorted && matches[i].Price != matches[j].Price {
+				return matches[i].Price < matches[j].Price
+			}
+			return matches[i].ID < matches[j].ID
+		})
+		page := matches[min(offset, len(matches)):min(offset+limit, len(matches))]
+
+		perDoc := 2
+		if withScores {
+			perDoc = 3
+		}
+		c.WriteLen(1 + perDoc*len(page))
+		c.WriteInt(len(matches))
+		for _, product := range page {
+			c.WriteBulk(repo.keyFor(product.ID))
+			if withScores {
+				c.WriteBulk(strconv.FormatFloat(scores[product.ID], 'f', -1, 64))
+			}
+			c.WriteLen(0)
+		}
+	})
+	if err != nil {
+		tb.Fatalf("register FT.SEARCH: %v", err)
+	}
+	repo.searchEnabled = true
+	repo.search = redisearch.NewClient(redis.Addr(), repo.indexName)
+}
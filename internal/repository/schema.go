@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// CurrentSchemaVersion is the layout of newly stored products. Records
+// written before versioning was introduced have no schema_version and decode
+// as version 0.
+const CurrentSchemaVersion = 4
+
+// schemaMigrations[v] upgrades a product from version v to v+1, backfilling
+// defaults for the fields that version introduced. Append a step whenever a
+// field is added whose zero value is not a safe default, and bump
+// CurrentSchemaVersion.
+var schemaMigrations = []func(*Product){
+	// 0 -> 1: versioning introduced; the layout is otherwise unchanged.
+	func(p *Product) {},
+	// 1 -> 2: write versions introduced; existing products start at 1.
+	func(p *Product) {
+		if p.Version == 0 {
+			p.Version = 1
+		}
+	},
+	// 2 -> 3: update times introduced; existing products were last updated
+	// at creation as far as is known.
+	func(p *Product) {
+		if p.UpdatedAt.IsZero() {
+			p.UpdatedAt = p.CreatedAt
+		}
+	},
+	// 3 -> 4: currencies and integer minor-unit prices introduced; existing
+	// prices are in DefaultCurrency.
+	func(p *Product) {
+		normalizePrice(p)
+	},
+}
+
+// migrateProduct upgrades p to CurrentSchemaVersion in place and reports
+// whether anything changed.
+func migrateProduct(p *Product) bool {
+	if p.SchemaVersion >= CurrentSchemaVersion {
+		return false
+	}
+	for v := p.SchemaVersion; v < CurrentSchemaVersion; v++ {
+		schemaMigrations[v](p)
+	}
+	p.SchemaVersion = CurrentSchemaVersion
+	return true
+}
+
+// decodeProduct unmarshals a stored product and upgrades it to the current
+// schema. migrated reports whether the stored record is outdated.
+func decodeProduct(data []byte) (product *Product, migrated bool, err error) {
+	var p Product
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, false, err
+	}
+	migrated = migrateProduct(&p)
+	// The float price is a view of the stored minor units.
+	p.Price = fromMinorUnits(p.PriceMinor, p.Currency)
+	return &p, migrated, nil
+}
+
+// rewriteMigrated stores an upgraded product over the outdated record it was
+// decoded from, unless the record changed in the meantime. Failures are
+// logged since the caller already has the upgraded product.
+func (r *RedisRepository) rewriteMigrated(ctx context.Context, product *Product, stored string) {
+	key := r.keyFor(product.ID)
+	data, err := json.Marshal(product)
+	if err != nil {
+		r.log(ctx).Warn("Failed to marshal migrated product", zap.String("id", product.ID), zap.Error(err))
+		return
+	}
+
+	err = r.client.Watch(ctx, func(tx *redis.Tx) error {
+		current, err := tx.Get(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if current != stored {
+			return nil
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, redis.KeepTTL)
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil && !errors.Is(err, redis.Nil) && !errors.Is(err, redis.TxFailedErr) {
+		r.log(ctx).Warn("Failed to rewrite migrated product",
+			zap.String("id", product.ID),
+			zap.Error(fmt.Errorf("schema version %d: %w", CurrentSchemaVersion, err)),
+		)
+	}
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// skuKeyPrefix prefixes the secondary index keys mapping a SKU to the ID of
+// the product that has it.
+const skuKeyPrefix = "sku:"
+
+// ErrDuplicateSKU is returned when a product is stored with a SKU another
+// product already has.
+var ErrDuplicateSKU = errors.New("sku already in use")
+
+// releaseSKUScript deletes a SKU mapping only if it still points at the
+// given product, so a product losing its SKU can't drop another's claim.
+var releaseSKUScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func skuKey(sku string) string {
+	return skuKeyPrefix + sku
+}
+
+// GetProductBySKU returns the product with the given SKU. A mapping left
+// behind by a product that was deleted, expired or given another SKU is
+// removed and reported as ErrProductNotFound.
+func (r *RedisRepository) GetProductBySKU(ctx context.Context, sku string) (*Product, error) {
+	id, err := r.client.Get(ctx, skuKey(sku)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: sku %s", ErrProductNotFound, sku)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up sku: %w", err)
+	}
+
+	product, err := r.GetProduct(ctx, id)
+	if errors.Is(err, ErrProductNotFound) || (err == nil && product.SKU != sku) {
+		r.releaseSKU(ctx, sku, id)
+		return nil, fmt.Errorf("%w: sku %s", ErrProductNotFound, sku)
+	}
+	return product, err
+}
+
+// reserveSKUs claims the SKUs of products about to be stored for their IDs,
+// in one pipeline, and returns an error per product: ErrDuplicateSKU when
+// another product, or an earlier one in products, has the SKU. Products
+// without a SKU, or with a SKU they already hold, always succeed. A mapping
+// whose product no longer has the SKU is taken over.
+func (r *RedisRepository) reserveSKUs(ctx context.Context, products []*Product) []error {
+	errs := make([]error, len(products))
+	cmds := make([]*redis.BoolCmd, len(products))
+	seen := make(map[string]bool)
+
+	pipe := r.client.Pipeline()
+	for i, product := range products {
+		if product.SKU == "" {
+			continue
+		}
+		if seen[product.SKU] {
+			errs[i] = fmt.Errorf("%w: %s", ErrDuplicateSKU, product.SKU)
+			continue
+		}
+		seen[product.SKU] = true
+		cmds[i] = pipe.SetNX(ctx, skuKey(product.SKU), product.ID, 0)
+	}
+	if pipe.Len() == 0 {
+		return errs
+	}
+	// Per-command errors are inspected below; Exec only reports the first.
+	_, _ = pipe.Exec(ctx)
+
+	for i, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		claimed, err := cmd.Result()
+		switch {
+		case err != nil:
+			errs[i] = fmt.Errorf("failed to reserve sku: %w", err)
+		case !claimed:
+			errs[i] = r.claimStaleSKU(ctx, products[i])
+		}
+	}
+	return errs
+}
+
+// claimStaleSKU takes over the SKU mapping of product's SKU unless it
+// belongs to a stored product that still has the SKU.
+func (r *RedisRepository) claimStaleSKU(ctx context.Context, product *Product) error {
+	key := skuKey(product.SKU)
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		owner, err := tx.Get(ctx, key).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return fmt.Errorf("failed to look up sku: %w", err)
+		}
+		if owner == product.ID {
+			return nil
+		}
+		if owner != "" {
+			stored, err := r.GetProduct(ctx, owner)
+			switch {
+			case err == nil && stored.SKU == product.SKU:
+				return fmt.Errorf("%w: %s", ErrDuplicateSKU, product.SKU)
+			case err != nil && !errors.Is(err, ErrProductNotFound):
+				return err
+			}
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, product.ID, 0)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		// Someone else claimed the SKU meanwhile
+		return fmt.Errorf("%w: %s", ErrDuplicateSKU, product.SKU)
+	}
+	return err
+}
+
+// releaseSKU removes the mapping of sku if it still points at id. Failures
+// are logged; a stale mapping is cleaned up when GetProductBySKU finds it.
+func (r *RedisRepository) releaseSKU(ctx context.Context, sku, id string) {
+	if sku == "" {
+		return
+	}
+	if err := releaseSKUScript.Run(ctx, r.client, []string{skuKey(sku)}, id).Err(); err != nil {
+		r.log(ctx).Warn("Failed to release sku", zap.String("sku", sku), zap.String("id", id), zap.Error(err))
+	}
+}
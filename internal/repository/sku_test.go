@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetProductBySKU(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	if err := repo.CreateProduct(ctx, &Product{ID: "p1", Name: "Laptop", Category: "Electronics", Price: 1000, Currency: "USD", SKU: "LAP-1"}); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	product, err := repo.GetProductBySKU(ctx, "LAP-1")
+	if err != nil {
+		t.Fatalf("GetProductBySKU: %v", err)
+	}
+	if product.ID != "p1" {
+		t.Errorf("GetProductBySKU(LAP-1) = %s, want p1", product.ID)
+	}
+
+	if _, err := repo.GetProductBySKU(ctx, "NONE"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("GetProductBySKU(NONE) = %v, want ErrProductNotFound", err)
+	}
+
+	// Moving the SKU to a new one frees the old one.
+	if _, err := repo.UpdateProduct(ctx, &Product{ID: "p1", Name: "Laptop", Category: "Electronics", Price: 1000, Currency: "USD", SKU: "LAP-2"}, 0); err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+	if _, err := repo.GetProductBySKU(ctx, "LAP-1"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("GetProductBySKU(LAP-1) after change = %v, want ErrProductNotFound", err)
+	}
+	if product, err := repo.GetProductBySKU(ctx, "LAP-2"); err != nil || product.ID != "p1" {
+		t.Errorf("GetProductBySKU(LAP-2) = %v, %v; want p1", product, err)
+	}
+}
+
+func TestDuplicateSKU(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	if err := repo.CreateProduct(ctx, &Product{ID: "p1", Name: "Laptop", Category: "Electronics", Price: 1000, Currency: "USD", SKU: "LAP-1"}); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	t.Run("create", func(t *testing.T) {
+		err := repo.CreateProduct(ctx, &Product{ID: "p2", Name: "Other", Category: "Electronics", Price: 10, Currency: "USD", SKU: "LAP-1"})
+		if !errors.Is(err, ErrDuplicateSKU) {
+			t.Errorf("CreateProduct = %v, want ErrDuplicateSKU", err)
+		}
+	})
+
+	t.Run("batch create", func(t *testing.T) {
+		errs := repo.CreateProducts(ctx, []*Product{
+			{ID: "p3", Name: "Mouse", Category: "Electronics", Price: 20, Currency: "USD", SKU: "MOU-1"},
+			{ID: "p4", Name: "Mouse copy", Category: "Electronics", Price: 20, Currency: "USD", SKU: "MOU-1"},
+		})
+		if errs[0] != nil || !errors.Is(errs[1], ErrDuplicateSKU) {
+			t.Errorf("CreateProducts = %v, want the second to fail with ErrDuplicateSKU", errs)
+		}
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		err := repo.UpsertProducts(ctx, []*Product{
+			{ID: "p5", Name: "Keyboard", Category: "Electronics", Price: 50, Currency: "USD", SKU: "KEY-1"},
+			{ID: "p6", Name: "Imposter", Category: "Electronics", Price: 50, Currency: "USD", SKU: "LAP-1"},
+		})
+		if !errors.Is(err, ErrDuplicateSKU) {
+			t.Fatalf("UpsertProducts = %v, want ErrDuplicateSKU", err)
+		}
+		for _, id := range []string{"p5", "p6"} {
+			if _, err := repo.GetProduct(ctx, id); !errors.Is(err, ErrProductNotFound) {
+				t.Errorf("GetProduct(%s) after the failed upsert = %v, want ErrProductNotFound", id, err)
+			}
+		}
+		if product, err := repo.GetProductBySKU(ctx, "LAP-1"); err != nil || product.ID != "p1" {
+			t.Errorf("GetProductBySKU(LAP-1) = %v, %v; want p1", product, err)
+		}
+
+		// The SKU claimed by the failed batch can still be imported, and a
+		// product re-imported with its own SKU keeps it.
+		err = repo.UpsertProducts(ctx, []*Product{
+			{ID: "p5", Name: "Keyboard", Category: "Electronics", Price: 50, Currency: "USD", SKU: "KEY-1"},
+			{ID: "p1", Name: "Laptop", Category: "Electronics", Price: 900, Currency: "USD", SKU: "LAP-1"},
+		})
+		if err != nil {
+			t.Fatalf("UpsertProducts: %v", err)
+		}
+		if product, err := repo.GetProductBySKU(ctx, "KEY-1"); err != nil || product.ID != "p5" {
+			t.Errorf("GetProductBySKU(KEY-1) = %v, %v; want p5", product, err)
+		}
+	})
+}
+
+func TestContentDigestDistinguishesSKUs(t *testing.T) {
+	repo, _ := newTestRepository(t, func(o *Options) { o.CreateDedupWindow = time.Minute })
+	ctx := context.Background()
+
+	first, err := repo.CreateProductIdempotent(ctx, "", &Product{Name: "Cable", Category: "Electronics", Price: 5, Currency: "USD", SKU: "CAB-1"})
+	if err != nil {
+		t.Fatalf("first create: %v", err)
+	}
+	second, err := repo.CreateProductIdempotent(ctx, "", &Product{Name: "Cable", Category: "Electronics", Price: 5, Currency: "USD", SKU: "CAB-2"})
+	if err != nil {
+		t.Fatalf("second create: %v", err)
+	}
+	if first.ID == second.ID {
+		t.Errorf("products with different SKUs were deduplicated to %s", first.ID)
+	}
+}
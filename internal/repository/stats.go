@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+)
+
+// StatsSummary aggregates the prices and stock of a set of products in one
+// currency.
+type StatsSummary struct {
+	Currency   string
+	Count      int64
+	TotalPrice float64
+	MinPrice   float64
+	MaxPrice   float64
+	TotalStock int64
+}
+
+// AveragePrice is the mean price, or 0 for an empty set.
+func (s StatsSummary) AveragePrice() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalPrice / float64(s.Count)
+}
+
+func (s *StatsSummary) add(product *Product) {
+	s.merge(StatsSummary{
+		Currency:   product.Currency,
+		Count:      1,
+		TotalPrice: product.Price,
+		MinPrice:   product.Price,
+		MaxPrice:   product.Price,
+		TotalStock: int64(product.Stock),
+	})
+}
+
+// merge adds other, which must be in the same currency, to s.
+func (s *StatsSummary) merge(other StatsSummary) {
+	if other.Count == 0 {
+		return
+	}
+	if s.Count == 0 {
+		*s = other
+		return
+	}
+	s.Count += other.Count
+	s.TotalPrice += other.TotalPrice
+	s.MinPrice = min(s.MinPrice, other.MinPrice)
+	s.MaxPrice = max(s.MaxPrice, other.MaxPrice)
+	s.TotalStock += other.TotalStock
+}
+
+// CategoryStats summarizes the products in one category, with one summary
+// per currency in currency code order.
+type CategoryStats struct {
+	Category   string
+	Currencies []StatsSummary
+}
+
+// CatalogStats summarizes the whole catalog per currency, in currency code
+// order, and broken down by category in category name order. Prices in
+// different currencies are never added up.
+type CatalogStats struct {
+	Currencies []StatsSummary
+	Categories []CategoryStats
+}
+
+// groupStats is the summary of the products of one category in one
+// currency, as aggregated or tallied.
+type groupStats struct {
+	category string
+	StatsSummary
+}
+
+// newCatalogStats assembles the catalog stats from the summaries of every
+// category and currency.
+func newCatalogStats(groups []groupStats) *CatalogStats {
+	totals := make(map[string]*StatsSummary)
+	categories := make(map[string][]StatsSummary)
+	for _, group := range groups {
+		total, ok := totals[group.Currency]
+		if !ok {
+			total = &StatsSummary{}
+			totals[group.Currency] = total
+		}
+		total.merge(group.StatsSummary)
+		categories[group.category] = append(categories[group.category], group.StatsSummary)
+	}
+
+	stats := &CatalogStats{
+		Currencies: make([]StatsSummary, 0, len(totals)),
+		Categories: make([]CategoryStats, 0, len(categories)),
+	}
+	for _, total := range totals {
+		stats.Currencies = append(stats.Currencies, *total)
+	}
+	sortByCurrency(stats.Currencies)
+	for category, summaries := range categories {
+		sortByCurrency(summaries)
+		stats.Categories = append(stats.Categories, CategoryStats{Category: category, Currencies: summaries})
+	}
+	sort.Slice(stats.Categories, func(i, j int) bool {
+		return stats.Categories[i].Category < stats.Categories[j].Category
+	})
+	return stats
+}
+
+func sortByCurrency(summaries []StatsSummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Currency < summaries[j].Currency
+	})
+}
+
+// statsCache holds the most recent CatalogStats result for ttl.
+type statsCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	stats     *CatalogStats
+	expiresAt time.Time
+}
+
+func (c *statsCache) get() (*CatalogStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stats == nil || time.Now().After(c.expiresAt) {
+		return nil, false
+	}
+	return c.stats, true
+}
+
+// clear drops the cached stats after a change they no longer reflect.
+func (c *statsCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats = nil
+}
+
+func (c *statsCache) set(stats *CatalogStats) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats = stats
+	c.expiresAt = time.Now().Add(c.ttl)
+}
+
+// CatalogStats returns the product count, price and stock totals of the
+// catalog and of each category, per currency. Like ListCategories it
+// aggregates over the search index when there is one and scans every
+// product otherwise, so the result is cached for the configured TTL.
+func (r *RedisRepository) CatalogStats(ctx context.Context) (*CatalogStats, error) {
+	if stats, ok := r.stats.get(); ok {
+		return stats, nil
+	}
+
+	var (
+		groups []groupStats
+		err    error
+	)
+	if r.searchEnabled && r.search != nil {
+		groups, err = r.aggregateGroupStats()
+	} else {
+		groups, err = r.tallyGroupStats(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stats := newCatalogStats(groups)
+	r.stats.set(stats)
+	return stats, nil
+}
+
+func (r *RedisRepository) aggregateGroupStats() ([]groupStats, error) {
+	query := redisearch.NewAggregateQuery().
+		SetQuery(redisearch.NewQuery("*")).
+		Load([]string{"@category", "@" + currencyField}).
+		GroupBy(*redisearch.NewGroupBy().
+			AddFields([]string{"@category", "@" + currencyField}).
+			Reduce(*redisearch.NewReducerAlias(redisearch.GroupByReducerCount, []string{}, "count")).
+			Reduce(*redisearch.NewReducerAlias(redisearch.GroupByReducerSum, []string{"@price"}, "total_price")).
+			Reduce(*redisearch.NewReducerAlias(redisearch.GroupByReducerMin, []string{"@price"}, "min_price")).
+			Reduce(*redisearch.NewReducerAlias(redisearch.GroupByReducerMax, []string{"@price"}, "max_price")).
+			Reduce(*redisearch.NewReducerAlias(redisearch.GroupByReducerSum, []string{"@stock"}, "total_stock"))).
+		Limit(0, maxAggregatedCategories)
+
+	_, rows, err := r.search.AggregateQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("catalog stats aggregation failed: %w", err)
+	}
+
+	groups := make([]groupStats, 0, len(rows))
+	for _, row := range rows {
+		var values [5]float64
+		for i, field := range []string{"count", "total_price", "min_price", "max_price", "total_stock"} {
+			value, _ := row[field].(string)
+			values[i], err = strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", field, value, err)
+			}
+		}
+
+		category, _ := row["category"].(string)
+		// Documents indexed before currencies were lack one until the
+		// index is repopulated; their prices are in the default currency.
+		currency, _ := row[currencyField].(string)
+		if currency == "" {
+			currency = DefaultCurrency
+		}
+		groups = append(groups, groupStats{
+			category: category,
+			StatsSummary: StatsSummary{
+				Currency:   strings.ToUpper(currency),
+				Count:      int64(values[0]),
+				TotalPrice: values[1],
+				MinPrice:   values[2],
+				MaxPrice:   values[3],
+				TotalStock: int64(values[4]),
+			},
+		})
+	}
+	return mergeGroupStats(groups), nil
+}
+
+// mergeGroupStats merges the summaries of groups with the same category and
+// currency, which the aggregation reports apart for documents with and
+// without a currency.
+func mergeGroupStats(groups []groupStats) []groupStats {
+	type groupKey struct{ category, currency string }
+	index := make(map[groupKey]int, len(groups))
+	merged := groups[:0]
+	for _, group := range groups {
+		key := groupKey{group.category, group.Currency}
+		if i, ok := index[key]; ok {
+			merged[i].merge(group.StatsSummary)
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, group)
+	}
+	return merged
+}
+
+func (r *RedisRepository) tallyGroupStats(ctx context.Context) ([]groupStats, error) {
+	type groupKey struct{ category, currency string }
+	tally := make(map[groupKey]*StatsSummary)
+
+	err := r.StreamAll(ctx, func(product *Product) error {
+		key := groupKey{product.Category, product.Currency}
+		summary, ok := tally[key]
+		if !ok {
+			summary = &StatsSummary{}
+			tally[key] = summary
+		}
+		summary.add(product)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]groupStats, 0, len(tally))
+	for key, summary := range tally {
+		groups = append(groups, groupStats{category: key.category, StatsSummary: *summary})
+	}
+	return groups, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCatalogStats(t *testing.T) {
+	repo, _ := newTestRepository(t, func(o *Options) { o.StatsCacheTTL = time.Minute })
+	ctx := context.Background()
+	for _, product := range []*Product{
+		{ID: "p1", Name: "Laptop", Category: "Electronics", Price: 1000, Currency: "USD", Stock: 5},
+		{ID: "p2", Name: "Mouse", Category: "Electronics", Price: 20, Currency: "USD", Stock: 50},
+		{ID: "p3", Name: "Keyboard", Category: "Electronics", Price: 90, Currency: "EUR", Stock: 10},
+		{ID: "p4", Name: "Lamp", Category: "Home", Price: 30, Currency: "EUR", Stock: 0},
+		{ID: "p5", Name: "Rug", Category: "Home", Price: 150, Currency: "EUR", Stock: 3},
+	} {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct(%s): %v", product.ID, err)
+		}
+	}
+
+	stats, err := repo.CatalogStats(ctx)
+	if err != nil {
+		t.Fatalf("CatalogStats: %v", err)
+	}
+
+	want := &CatalogStats{
+		Currencies: []StatsSummary{
+			{Currency: "EUR", Count: 3, TotalPrice: 270, MinPrice: 30, MaxPrice: 150, TotalStock: 13},
+			{Currency: "USD", Count: 2, TotalPrice: 1020, MinPrice: 20, MaxPrice: 1000, TotalStock: 55},
+		},
+		Categories: []CategoryStats{
+			{Category: "Electronics", Currencies: []StatsSummary{
+				{Currency: "EUR", Count: 1, TotalPrice: 90, MinPrice: 90, MaxPrice: 90, TotalStock: 10},
+				{Currency: "USD", Count: 2, TotalPrice: 1020, MinPrice: 20, MaxPrice: 1000, TotalStock: 55},
+			}},
+			{Category: "Home", Currencies: []StatsSummary{
+				{Currency: "EUR", Count: 2, TotalPrice: 180, MinPrice: 30, MaxPrice: 150, TotalStock: 3},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("CatalogStats =\n%+v\nwant\n%+v", stats, want)
+	}
+	if got := stats.Currencies[0].AveragePrice(); got != 90 {
+		t.Errorf("EUR average price = %v, want 90", got)
+	}
+	if got := stats.Currencies[1].AveragePrice(); got != 510 {
+		t.Errorf("USD average price = %v, want 510", got)
+	}
+}
+
+func TestMergeGroupStats(t *testing.T) {
+	// The aggregation reports documents indexed without a currency apart
+	// from those in the default currency.
+	got := mergeGroupStats([]groupStats{
+		{category: "Home", StatsSummary: StatsSummary{Currency: "USD", Count: 1, TotalPrice: 10, MinPrice: 10, MaxPrice: 10, TotalStock: 1}},
+		{category: "Home", StatsSummary: StatsSummary{Currency: "EUR", Count: 1, TotalPrice: 7, MinPrice: 7, MaxPrice: 7}},
+		{category: "Home", StatsSummary: StatsSummary{Currency: "USD", Count: 2, TotalPrice: 50, MinPrice: 5, MaxPrice: 45, TotalStock: 4}},
+	})
+	want := []groupStats{
+		{category: "Home", StatsSummary: StatsSummary{Currency: "USD", Count: 3, TotalPrice: 60, MinPrice: 5, MaxPrice: 45, TotalStock: 5}},
+		{category: "Home", StatsSummary: StatsSummary{Currency: "EUR", Count: 1, TotalPrice: 7, MinPrice: 7, MaxPrice: 7}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeGroupStats = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+	"github.com/redis/go-redis/v9"
+)
+
+// adjustStockScript atomically applies a stock delta to a stored product,
+// refusing to let the stock fall below zero or overflow int32, bumps its
+// write version and sets its update time to ARGV[2], keeping the key's TTL.
+// It replies with the updated product JSON, or with one of the errors in
+// stockScriptErrors.
+var adjustStockScript = redis.NewScript(`
+local data = redis.call('GET', KEYS[1])
+if not data then
+  return redis.error_reply('NOT_FOUND product not found')
+end
+local product = cjson.decode(data)
+local stock = (product.stock or 0) + tonumber(ARGV[1])
+if stock < 0 then
+  return redis.error_reply('INSUFFICIENT_STOCK stock would go negative')
+end
+if stock > 2147483647 then
+  return redis.error_reply('STOCK_OVERFLOW stock would overflow')
+end
+product.stock = stock
+local version = tonumber(product.version) or 0
+if version == 0 then
+  version = 1
+end
+product.version = version + 1
+product.updated_at = ARGV[2]
+local encoded = cjson.encode(product)
+redis.call('SET', KEYS[1], encoded, 'KEEPTTL')
+return encoded
+`)
+
+var (
+	ErrStockOverflow     = errors.New("stock would overflow")
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// stockScriptErrors maps the codes of adjustStockScript's error replies,
+// their first word like WRONGTYPE in Redis's own errors, to the errors they
+// are reported as.
+var stockScriptErrors = map[string]error{
+	"NOT_FOUND":          ErrProductNotFound,
+	"INSUFFICIENT_STOCK": ErrInsufficientStock,
+	"STOCK_OVERFLOW":     ErrStockOverflow,
+}
+
+func (r *RedisRepository) IncrementStock(ctx context.Context, id string, quantity int32) (*Product, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive, got %d", quantity)
+	}
+	return r.adjustStock(ctx, id, int64(quantity))
+}
+
+func (r *RedisRepository) DecrementStock(ctx context.Context, id string, quantity int32) (*Product, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive, got %d", quantity)
+	}
+	return r.adjustStock(ctx, id, -int64(quantity))
+}
+
+// CountLowStock returns the number of products whose stock is below
+// threshold. It uses a numeric range query when search is enabled and falls
+// back to scanning the keyspace otherwise.
+func (r *RedisRepository) CountLowStock(ctx context.Context, threshold int32) (int64, error) {
+	if r.searchEnabled && r.search != nil {
+		query := redisearch.NewQuery(fmt.Sprintf("@stock:[-inf (%d]", threshold)).
+			SetFlags(redisearch.QueryNoContent).
+			Limit(0, 0)
+
+		_, total, err := r.search.Search(query)
+		if err != nil {
+			return 0, fmt.Errorf("low stock search failed: %w", err)
+		}
+		return int64(total), nil
+	}
+
+	var count int64
+	err := r.StreamAll(ctx, func(product *Product) error {
+		if product.Stock < threshold {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *RedisRepository) adjustStock(ctx context.Context, id string, delta int64) (*Product, error) {
+	data, err := adjustStockScript.Run(ctx, r.client, []string{r.keyFor(id)}, delta, time.Now().Format(time.RFC3339Nano)).Text()
+	if err != nil {
+		var reply redis.Error
+		if errors.As(err, &reply) {
+			code, _, _ := strings.Cut(reply.Error(), " ")
+			if scriptErr, ok := stockScriptErrors[code]; ok {
+				return nil, fmt.Errorf("%w: %s", scriptErr, id)
+			}
+		}
+		return nil, fmt.Errorf("failed to adjust stock: %w", err)
+	}
+
+	product, _, err := decodeProduct([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal product: %w", err)
+	}
+
+	r.products.invalidate(id)
+	r.indexProduct(ctx, product, redisearch.IndexingOptions{Replace: true, Partial: true})
+
+	return product, nil
+}
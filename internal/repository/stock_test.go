@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdjustStockConcurrentIncrementsAndDecrements(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	product := &Product{ID: "widget", Name: "Widget", Price: 5, Stock: 100}
+	if err := repo.CreateProduct(ctx, product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	const workers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := repo.IncrementStock(ctx, product.ID, 3)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := repo.DecrementStock(ctx, product.ID, 2)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("adjusting stock: %v", err)
+		}
+	}
+
+	stored, err := repo.GetProduct(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if want := int32(100 + workers*3 - workers*2); stored.Stock != want {
+		t.Errorf("stock = %d, want %d", stored.Stock, want)
+	}
+	if want := int64(1 + 2*workers); stored.Version != want {
+		t.Errorf("version = %d, want %d", stored.Version, want)
+	}
+}
+
+func TestAdjustStockKeepsTTL(t *testing.T) {
+	repo, server := newTestRepository(t)
+	ctx := context.Background()
+	product := &Product{ID: "expiring", Name: "Expiring", Price: 5, Stock: 10}
+	if err := repo.CreateProduct(ctx, product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	server.SetTTL(repo.keyFor(product.ID), time.Hour)
+
+	if _, err := repo.IncrementStock(ctx, product.ID, 1); err != nil {
+		t.Fatalf("IncrementStock: %v", err)
+	}
+	if _, err := repo.DecrementStock(ctx, product.ID, 1); err != nil {
+		t.Fatalf("DecrementStock: %v", err)
+	}
+	if ttl := server.TTL(repo.keyFor(product.ID)); ttl != time.Hour {
+		t.Errorf("TTL = %v, want %v", ttl, time.Hour)
+	}
+}
+
+func TestAdjustStockErrors(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	product := &Product{ID: "widget", Name: "Widget", Price: 5, Stock: math.MaxInt32 - 1}
+	if err := repo.CreateProduct(ctx, product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	if _, err := repo.IncrementStock(ctx, "missing", 1); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("IncrementStock(missing) error = %v, want ErrProductNotFound", err)
+	}
+	if _, err := repo.IncrementStock(ctx, product.ID, 2); !errors.Is(err, ErrStockOverflow) {
+		t.Errorf("IncrementStock past MaxInt32 error = %v, want ErrStockOverflow", err)
+	}
+	if _, err := repo.DecrementStock(ctx, product.ID, math.MaxInt32); !errors.Is(err, ErrInsufficientStock) {
+		t.Errorf("DecrementStock below zero error = %v, want ErrInsufficientStock", err)
+	}
+}
+
+func TestDecrementStockNeverGoesNegative(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	product := &Product{ID: "widget", Name: "Widget", Price: 5, Stock: 30}
+	if err := repo.CreateProduct(ctx, product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	const orders = 100
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		sold, denied int
+	)
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			updated, err := repo.DecrementStock(ctx, product.ID, 1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, ErrInsufficientStock):
+				denied++
+			case err != nil:
+				t.Errorf("DecrementStock: %v", err)
+			case updated.Stock < 0:
+				t.Errorf("stock went negative: %d", updated.Stock)
+			default:
+				sold++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sold != 30 || denied != orders-30 {
+		t.Errorf("sold %d and denied %d, want 30 and %d", sold, denied, orders-30)
+	}
+	stored, err := repo.GetProduct(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if stored.Stock != 0 {
+		t.Errorf("stock = %d, want 0", stored.Stock)
+	}
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+	"go.uber.org/zap"
+)
+
+// suggestionsKey is the RediSearch suggestion dictionary holding product
+// names.
+const suggestionsKey = "products:suggestions"
+
+// nameCache holds every product name for the scan fallback of
+// SuggestProducts, refreshed after ttl.
+type nameCache struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	names     []string
+	expiresAt time.Time
+}
+
+func (c *nameCache) get() ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.names == nil || time.Now().After(c.expiresAt) {
+		return nil, false
+	}
+	return c.names, true
+}
+
+// clear drops the cached names so that the next lookup rescans them.
+func (c *nameCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.names = nil
+}
+
+func (c *nameCache) set(names []string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.names = names
+	c.expiresAt = time.Now().Add(c.ttl)
+}
+
+// addSuggestions adds the names of products to the suggestion dictionary.
+// Like indexing, failures are logged since the products are already stored.
+func (r *RedisRepository) addSuggestions(ctx context.Context, products []*Product) {
+	if r.suggester == nil || len(products) == 0 {
+		return
+	}
+
+	terms := make([]redisearch.Suggestion, 0, len(products))
+	for _, product := range products {
+		if product.Name == "" {
+			continue
+		}
+		terms = append(terms, redisearch.Suggestion{Term: product.Name, Score: 1})
+	}
+	if err := r.suggester.AddTerms(terms...); err != nil {
+		r.log(ctx).Warn("Failed to add product name suggestions", zap.Int("count", len(terms)), zap.Error(err))
+	}
+}
+
+// removeSuggestions removes names that no product has any more from the
+// suggestion dictionary, after the products with them were deleted or
+// renamed. Names still in use, or that can't be checked, are kept; failures
+// are logged like in addSuggestions.
+func (r *RedisRepository) removeSuggestions(ctx context.Context, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	r.names.clear()
+	if r.suggester == nil {
+		return
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		inUse, err := r.productNameInUse(name)
+		if err != nil {
+			r.log(ctx).Warn("Failed to check whether a product name is still in use", zap.String("name", name), zap.Error(err))
+			continue
+		}
+		if inUse {
+			continue
+		}
+		if err := r.suggester.DeleteTerms(redisearch.Suggestion{Term: name}); err != nil {
+			r.log(ctx).Warn("Failed to remove product name suggestion", zap.String("name", name), zap.Error(err))
+		}
+	}
+}
+
+// nameInUseSearchLimit bounds the products productNameInUse compares.
+const nameInUseSearchLimit = 100
+
+// productNameInUse reports whether an indexed product is named exactly
+// name. The index is searched for the words of name, then the names of the
+// matches are compared; a name without words, or with more matches than
+// could be compared, is assumed to be in use.
+func (r *RedisRepository) productNameInUse(name string) (bool, error) {
+	words := strings.FieldsFunc(name, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	if len(words) == 0 {
+		return true, nil
+	}
+
+	query := redisearch.NewQuery(fmt.Sprintf(`@name:"%s"`, strings.Join(words, " "))).
+		SetReturnFields("name").
+		Limit(0, nameInUseSearchLimit)
+	docs, total, err := r.search.Search(query)
+	if err != nil {
+		return false, err
+	}
+	for _, doc := range docs {
+		if doc.Properties["name"] == name {
+			return true, nil
+		}
+	}
+	return total > len(docs), nil
+}
+
+// SuggestProducts returns up to limit product names starting with prefix.
+// With RediSearch the names come ranked from the suggestion dictionary;
+// otherwise they are matched case-insensitively against the cached names of
+// every product and returned in alphabetical order.
+func (r *RedisRepository) SuggestProducts(ctx context.Context, prefix string, limit int) ([]string, error) {
+	if r.suggester != nil {
+		suggestions, err := r.suggester.SuggestOpts(prefix, redisearch.SuggestOptions{Num: limit})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get suggestions: %w", err)
+		}
+		names := make([]string, len(suggestions))
+		for i, s := range suggestions {
+			names[i] = s.Term
+		}
+		return names, nil
+	}
+
+	names, ok := r.names.get()
+	if !ok {
+		var err error
+		if names, err = r.collectProductNames(ctx); err != nil {
+			return nil, err
+		}
+		r.names.set(names)
+	}
+
+	prefix = strings.ToLower(prefix)
+	matches := make([]string, 0, limit)
+	for _, name := range names {
+		if strings.HasPrefix(strings.ToLower(name), prefix) {
+			matches = append(matches, name)
+			if len(matches) == limit {
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+// collectProductNames returns the distinct names of every product, sorted.
+func (r *RedisRepository) collectProductNames(ctx context.Context) ([]string, error) {
+	seen := make(map[string]struct{})
+
+	err := r.StreamAll(ctx, func(product *Product) error {
+		seen[product.Name] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
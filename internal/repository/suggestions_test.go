@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSuggestProducts(t *testing.T) {
+	repo, _ := newTestRepository(t, func(o *Options) { o.SuggestionsCacheTTL = time.Minute })
+	ctx := context.Background()
+	for _, product := range []*Product{
+		{ID: "p1", Name: "Laptop Pro 15", Category: "Electronics", Price: 1500, Currency: "USD"},
+		{ID: "p2", Name: "Lamp", Category: "Home", Price: 30, Currency: "USD"},
+		{ID: "p3", Name: "Phone", Category: "Electronics", Price: 700, Currency: "USD"},
+	} {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct(%s): %v", product.ID, err)
+		}
+	}
+
+	got, err := repo.SuggestProducts(ctx, "Lap", 5)
+	if err != nil {
+		t.Fatalf("SuggestProducts: %v", err)
+	}
+	if want := []string{"Laptop Pro 15"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SuggestProducts(Lap) = %q, want %q", got, want)
+	}
+}
+
+func TestSuggestProductsAfterRename(t *testing.T) {
+	repo, _ := newTestRepository(t, func(o *Options) { o.SuggestionsCacheTTL = time.Minute })
+	ctx := context.Background()
+	product := &Product{ID: "p1", Name: "Laptop Pro 15", Category: "Electronics", Price: 1500, Currency: "USD"}
+	if err := repo.CreateProduct(ctx, product); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	// Fill the cached names before the rename.
+	if _, err := repo.SuggestProducts(ctx, "Lap", 5); err != nil {
+		t.Fatalf("SuggestProducts: %v", err)
+	}
+
+	renamed := *product
+	renamed.Name = "Notebook Pro 15"
+	if _, err := repo.UpdateProduct(ctx, &renamed, 0); err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+
+	for prefix, want := range map[string][]string{
+		"Lap":  {},
+		"Note": {"Notebook Pro 15"},
+	} {
+		got, err := repo.SuggestProducts(ctx, prefix, 5)
+		if err != nil {
+			t.Fatalf("SuggestProducts(%s): %v", prefix, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("SuggestProducts(%s) after rename = %q, want %q", prefix, got, want)
+		}
+	}
+}
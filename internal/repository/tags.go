@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"strings"
+)
+
+// tagsField is the TAG index field holding a product's tags, separated by
+// categoryTagSeparator so that tags may contain commas.
+const tagsField = "tags"
+
+// normalizeTags trims the tags and drops empty ones and repeats, comparing
+// them like the index does, keeping the first spelling of each. It returns
+// nil for nil, so that UpdateProduct can tell "keep the tags" from "clear
+// them".
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := categoryTag(tag)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// hasTags reports whether product carries every one of tags, ignoring case
+// and surrounding whitespace like the TAG index.
+func hasTags(product *Product, tags []string) bool {
+	have := make(map[string]bool, len(product.Tags))
+	for _, tag := range product.Tags {
+		have[categoryTag(tag)] = true
+	}
+	for _, tag := range tags {
+		if !have[categoryTag(tag)] {
+			return false
+		}
+	}
+	return true
+}
+
+// tagsValue is the index value of a product's tags.
+func tagsValue(tags []string) string {
+	return strings.Join(tags, string(categoryTagSeparator))
+}
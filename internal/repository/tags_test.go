@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestListProductsTagFilters(t *testing.T) {
+	repo, redis := newTestRepository(t)
+	ctx := context.Background()
+
+	products := []*Product{
+		{ID: "p1", Name: "Mouse", Category: "Electronics", Price: 10, Tags: []string{"wireless", "usb"}},
+		{ID: "p2", Name: "Keyboard", Category: "Electronics", Price: 20, Tags: []string{"Wireless"}},
+		{ID: "p3", Name: "Cable", Category: "Electronics", Price: 30, Tags: []string{"usb"}},
+		{ID: "p4", Name: "Lamp", Category: "Home", Price: 40},
+	}
+	for _, product := range products {
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{name: "single tag", tags: []string{"usb"}, want: []string{"p1", "p3"}},
+		{name: "tags ignore case", tags: []string{" WIRELESS "}, want: []string{"p1", "p2"}},
+		{name: "several tags must all match", tags: []string{"wireless", "usb"}, want: []string{"p1"}},
+		{name: "unknown tag", tags: []string{"usb", "bluetooth"}, want: nil},
+	}
+	check := func(path string) {
+		t.Helper()
+		for _, tc := range cases {
+			result, err := repo.ListProducts(ctx, ListOptions{Tags: tc.tags})
+			if err != nil {
+				t.Fatalf("%s path ListProducts(%v): %v", path, tc.tags, err)
+			}
+			got := productIDs(result.Products)
+			slices.Sort(got)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("%s path, %s: listed %v, want %v", path, tc.name, got, tc.want)
+			}
+			if int(result.Total) != len(tc.want) {
+				t.Errorf("%s path, %s: total = %d, want %d", path, tc.name, result.Total, len(tc.want))
+			}
+		}
+	}
+
+	check("scan")
+	enableFakeSearch(t, repo, redis, products)
+	check("search")
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RediSearch/redisearch-go/v2/redisearch"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrVersionConflict is returned when a product changed since the version
+// an update was based on.
+var ErrVersionConflict = errors.New("product version conflict")
+
+// UpdateProduct replaces the name, description, price, category and stock of
+// the stored product with product.ID, its SKU and currency when product has
+// them and its tags and attributes unless product.Tags or product.Attributes
+// is nil, and returns the result with its version incremented. A new SKU is
+// claimed before the update, failing with ErrDuplicateSKU if another product
+// has it. When expectedVersion is non-zero the update only applies if the
+// stored product is still at that version; otherwise it fails with
+// ErrVersionConflict. A concurrent write during the update is reported as a
+// conflict as well. A price more precise than the resulting currency fails
+// with ErrPricePrecision.
+func (r *RedisRepository) UpdateProduct(ctx context.Context, product *Product, expectedVersion int64) (*Product, error) {
+	key := r.keyFor(product.ID)
+
+	if product.SKU != "" {
+		if err := r.reserveSKUs(ctx, []*Product{product})[0]; err != nil {
+			return nil, err
+		}
+	}
+
+	var updated *Product
+	var storedSKU, storedName string
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				return fmt.Errorf("%w: %s", ErrProductNotFound, product.ID)
+			}
+			return fmt.Errorf("failed to get product: %w", err)
+		}
+
+		stored, _, err := decodeProduct(data)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal product: %w", err)
+		}
+		storedSKU = stored.SKU
+		storedName = stored.Name
+		if expectedVersion != 0 && stored.Version != expectedVersion {
+			return fmt.Errorf("%w: %s is at version %d, expected %d",
+				ErrVersionConflict, product.ID, stored.Version, expectedVersion)
+		}
+
+		next := *stored
+		next.Name = product.Name
+		next.Description = product.Description
+		next.Price = product.Price
+		next.Category = product.Category
+		next.Stock = product.Stock
+		if product.SKU != "" {
+			next.SKU = product.SKU
+		}
+		if product.Tags != nil {
+			next.Tags = normalizeTags(product.Tags)
+		}
+		if product.Attributes != nil {
+			next.Attributes = product.Attributes
+		}
+		if product.Currency != "" {
+			next.Currency = product.Currency
+		}
+		if err := CheckPricePrecision(next.Price, next.Currency); err != nil {
+			return err
+		}
+		normalizePrice(&next)
+		next.Version = stored.Version + 1
+		next.UpdatedAt = time.Now()
+		next.SchemaVersion = CurrentSchemaVersion
+		if err := r.enricher.Enrich(ctx, &next); err != nil {
+			return fmt.Errorf("failed to enrich product: %w", err)
+		}
+
+		encoded, err := json.Marshal(&next)
+		if err != nil {
+			return fmt.Errorf("failed to marshal product: %w", err)
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, encoded, redis.KeepTTL)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		updated = &next
+		return nil
+	}, key)
+	if err != nil {
+		if product.SKU != storedSKU {
+			r.releaseSKU(ctx, product.SKU, product.ID)
+		}
+		if errors.Is(err, redis.TxFailedErr) {
+			return nil, fmt.Errorf("%w: %s was modified concurrently", ErrVersionConflict, product.ID)
+		}
+		return nil, err
+	}
+
+	if storedSKU != updated.SKU {
+		r.releaseSKU(ctx, storedSKU, updated.ID)
+	}
+	r.products.invalidate(updated.ID)
+	r.memIndex.add(updated)
+	r.noteCategories(updated)
+	r.indexProduct(ctx, updated, redisearch.IndexingOptions{Replace: true})
+	r.addSuggestions(ctx, []*Product{updated})
+	if storedName != updated.Name {
+		r.removeSuggestions(ctx, []string{storedName})
+	}
+
+	return updated, nil
+}
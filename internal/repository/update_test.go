@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestUpdateProductRacingUpdatesOneWins(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	createTestProducts(t, repo, 1)
+	id := createTestID(0)
+
+	const updaters = 10
+	var (
+		start sync.WaitGroup
+		done  sync.WaitGroup
+		errs  = make([]error, updaters)
+	)
+	start.Add(1)
+	for i := range errs {
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			start.Wait()
+			product := &Product{ID: id, Name: fmt.Sprintf("Update %d", i), Category: "Test", Price: 1}
+			_, errs[i] = repo.UpdateProduct(ctx, product, 1)
+		}()
+	}
+	start.Done()
+	done.Wait()
+
+	var winner string
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if winner != "" {
+				t.Fatalf("updates %s and %d both succeeded at version 1", winner, i)
+			}
+			winner = fmt.Sprintf("Update %d", i)
+		case !errors.Is(err, ErrVersionConflict):
+			t.Errorf("update %d = %v, want ErrVersionConflict", i, err)
+		}
+	}
+	if winner == "" {
+		t.Fatal("no update succeeded")
+	}
+
+	product, err := repo.GetProduct(ctx, id)
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if product.Name != winner || product.Version != 2 {
+		t.Errorf("stored product = %q at version %d, want %q at version 2", product.Name, product.Version, winner)
+	}
+}
+
+func TestUpdateProductVersionConflict(t *testing.T) {
+	repo, _ := newTestRepository(t)
+	ctx := context.Background()
+	createTestProducts(t, repo, 1)
+	id := createTestID(0)
+
+	updated, err := repo.UpdateProduct(ctx, &Product{ID: id, Name: "First", Category: "Test", Price: 1}, 1)
+	if err != nil {
+		t.Fatalf("UpdateProduct at version 1: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("version after update = %d, want 2", updated.Version)
+	}
+
+	if _, err := repo.UpdateProduct(ctx, &Product{ID: id, Name: "Stale", Category: "Test", Price: 1}, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("UpdateProduct at stale version = %v, want ErrVersionConflict", err)
+	}
+	// Without an expected version the update applies unconditionally.
+	if _, err := repo.UpdateProduct(ctx, &Product{ID: id, Name: "Forced", Category: "Test", Price: 1}, 0); err != nil {
+		t.Errorf("unconditional UpdateProduct: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chirik/products/proto"
+	"google.golang.org/grpc/codes"
+)
+
+func TestCreateProductsBatchMixedResults(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	if _, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Mouse", Category: "Electronics", Price: 19.99, Sku: "MOUSE-1"}); err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	resp, err := client.CreateProductsBatch(ctx, &proto.CreateProductsBatchRequest{Products: []*proto.CreateProductRequest{
+		{Name: "Keyboard", Category: "Electronics", Price: 49.99, Stock: 3, Tags: []string{"input"}},
+		{Name: "", Category: "Electronics", Price: 10},
+		{Name: "Monitor", Category: "Electronics", Price: 199.99, Sku: "MOUSE-1"},
+		{Name: "Cable", Category: "Electronics", Price: -1},
+		{Name: "Headset", Category: "Electronics", Price: 59.99, Sku: "HEAD-1"},
+	}})
+	if err != nil {
+		t.Fatalf("CreateProductsBatch: %v", err)
+	}
+
+	want := []struct {
+		name string
+		code codes.Code
+	}{
+		{name: "Keyboard", code: codes.OK},
+		{code: codes.InvalidArgument},
+		{code: codes.AlreadyExists},
+		{code: codes.InvalidArgument},
+		{name: "Headset", code: codes.OK},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, result := range resp.Results {
+		if got := codes.Code(result.Code); got != want[i].code {
+			t.Errorf("result %d code = %v (%q), want %v", i, got, result.Error, want[i].code)
+		}
+		if want[i].code != codes.OK {
+			if result.Product != nil || result.Error == "" {
+				t.Errorf("failed result %d = %v, want an error and no product", i, result)
+			}
+			continue
+		}
+		if result.Product == nil || result.Product.Id == "" || result.Product.Name != want[i].name {
+			t.Errorf("result %d product = %v, want %s with an ID", i, result.Product, want[i].name)
+		}
+	}
+	if tags := resp.Results[0].Product.GetTags(); len(tags) != 1 || tags[0] != "input" {
+		t.Errorf("created product tags = %q, want [input]", tags)
+	}
+	if resp.Created != 2 || resp.Failed != 3 {
+		t.Errorf("created %d, failed %d; want 2 and 3", resp.Created, resp.Failed)
+	}
+
+	list, err := client.ListProducts(ctx, &proto.ListProductsRequest{})
+	if err != nil {
+		t.Fatalf("ListProducts: %v", err)
+	}
+	if list.Total != 3 {
+		t.Errorf("catalog has %d products, want the 2 created and the existing one", list.Total)
+	}
+}
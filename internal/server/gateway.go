@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/chirik/products/proto"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+)
+
+// gatewayHeaders are the request headers forwarded to the service as gRPC
+// metadata, on top of the gateway's default Grpc-Metadata- prefix.
+var gatewayHeaders = map[string]bool{
+	"x-api-key":       true,
+	"x-api-version":   true,
+	"idempotency-key": true,
+	"x-request-id":    true,
+}
+
+// GatewayHandler serves a JSON REST front end over the gRPC service at
+// endpoint, following the google.api.http annotations in products.proto:
+//
+//	GET  /v1/products       ListProducts, fields taken from the query string
+//	GET  /v1/products/{id}  GetProduct
+//	POST /v1/products       CreateProduct, the body is a CreateProductRequest
+//
+// Calls go through a gRPC client dialled with opts, so the server's
+// interceptors (authentication, rate limits, logging, metrics) apply to
+// REST traffic as well. The connection is closed once ctx is done.
+func GatewayHandler(ctx context.Context, endpoint string, opts []grpc.DialOption) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+			if gatewayHeaders[strings.ToLower(key)] {
+				return key, true
+			}
+			return runtime.DefaultHeaderMatcher(key)
+		}),
+	)
+	if err := proto.RegisterProductsServiceHandlerFromEndpoint(ctx, mux, endpoint, opts); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
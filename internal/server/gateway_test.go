@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chirik/products/internal/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newTestGateway serves the REST gateway over a test server that requires
+// the API key "test-key", so the tests see whether REST calls pass through
+// the gRPC interceptors.
+func newTestGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	keys := []string{"test-key"}
+	listener, _ := newTestServer(t, []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(middleware.APIKeyAuthInterceptor(keys)),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	handler, err := GatewayHandler(ctx, "passthrough:///bufnet", []grpc.DialOption{
+		bufconnDialer(listener),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	})
+	if err != nil {
+		t.Fatalf("GatewayHandler: %v", err)
+	}
+	gateway := httptest.NewServer(handler)
+	t.Cleanup(gateway.Close)
+	return gateway
+}
+
+// gatewayRequest sends a request with the test API key and decodes the JSON
+// response into out, returning the status code.
+func gatewayRequest(t *testing.T, method, url, body string, out any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("X-Api-Key", "test-key")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("%s %s Content-Type = %q, want application/json", method, url, got)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s %s response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestGatewayRoundTrip(t *testing.T) {
+	gateway := newTestGateway(t)
+
+	var created struct {
+		ID       string  `json:"id"`
+		Name     string  `json:"name"`
+		Category string  `json:"category"`
+		Price    float64 `json:"price"`
+	}
+	code := gatewayRequest(t, http.MethodPost, gateway.URL+"/v1/products",
+		`{"name": "Mouse", "category": "Electronics", "price": 19.99, "stock": 5}`, &created)
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/products = %d, want 200", code)
+	}
+	if created.ID == "" || created.Name != "Mouse" || created.Price != 19.99 {
+		t.Errorf("created product = %+v, want Mouse at 19.99 with an ID", created)
+	}
+
+	var fetched struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if code := gatewayRequest(t, http.MethodGet, gateway.URL+"/v1/products/"+created.ID, "", &fetched); code != http.StatusOK {
+		t.Fatalf("GET /v1/products/%s = %d, want 200", created.ID, code)
+	}
+	if fetched.ID != created.ID || fetched.Name != "Mouse" {
+		t.Errorf("fetched product = %+v, want %s named Mouse", fetched, created.ID)
+	}
+
+	var list struct {
+		Products []struct {
+			ID string `json:"id"`
+		} `json:"products"`
+		Total int32 `json:"total"`
+	}
+	code = gatewayRequest(t, http.MethodGet, gateway.URL+"/v1/products?category=Electronics&page=1&page_size=5", "", &list)
+	if code != http.StatusOK {
+		t.Fatalf("GET /v1/products = %d, want 200", code)
+	}
+	if list.Total != 1 || len(list.Products) != 1 || list.Products[0].ID != created.ID {
+		t.Errorf("listed %+v, want only %s", list, created.ID)
+	}
+
+	code = gatewayRequest(t, http.MethodGet, gateway.URL+"/v1/products?category=Books", "", &list)
+	if code != http.StatusOK || len(list.Products) != 0 {
+		t.Errorf("GET /v1/products?category=Books = %d with %d products, want 200 with none", code, len(list.Products))
+	}
+
+	if code := gatewayRequest(t, http.MethodGet, gateway.URL+"/v1/products/missing", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /v1/products/missing = %d, want 404", code)
+	}
+}
+
+func TestGatewayRunsInterceptors(t *testing.T) {
+	gateway := newTestGateway(t)
+
+	// The API key is checked by the gRPC interceptor, not by the gateway.
+	resp, err := http.Get(gateway.URL + "/v1/products")
+	if err != nil {
+		t.Fatalf("GET /v1/products: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /v1/products without a key = %d, want 401", resp.StatusCode)
+	}
+}
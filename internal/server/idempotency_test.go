@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/chirik/products/proto"
+	"google.golang.org/grpc/metadata"
+	gproto "google.golang.org/protobuf/proto"
+)
+
+func TestCreateProductIdempotencyKeyReplay(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), idempotencyKeyHeader, "order-42")
+	req := &proto.CreateProductRequest{Name: "Mouse", Category: "Electronics", Price: 19.99, Stock: 5}
+
+	first, err := client.CreateProduct(ctx, req)
+	if err != nil {
+		t.Fatalf("first CreateProduct: %v", err)
+	}
+	second, err := client.CreateProduct(ctx, req)
+	if err != nil {
+		t.Fatalf("retried CreateProduct: %v", err)
+	}
+	if !gproto.Equal(first, second) {
+		t.Errorf("retried create returned\n%v\nwant the original\n%v", second, first)
+	}
+
+	list, err := client.ListProducts(ctx, &proto.ListProductsRequest{})
+	if err != nil {
+		t.Fatalf("ListProducts: %v", err)
+	}
+	if list.Total != 1 {
+		t.Errorf("catalog has %d products after a retried create, want 1", list.Total)
+	}
+
+	// Another key creates another product.
+	other := metadata.AppendToOutgoingContext(context.Background(), idempotencyKeyHeader, "order-43")
+	third, err := client.CreateProduct(other, req)
+	if err != nil {
+		t.Fatalf("CreateProduct with another key: %v", err)
+	}
+	if third.Id == first.Id {
+		t.Errorf("create with another key returned the product %s of the first key", first.Id)
+	}
+}
+
+func TestCreateProductIdempotencyKeyConcurrent(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), idempotencyKeyHeader, "order-44")
+	req := &proto.CreateProductRequest{Name: "Mouse", Category: "Electronics", Price: 19.99}
+
+	const creates = 8
+	ids := make([]string, creates)
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			product, err := client.CreateProduct(ctx, req)
+			if err != nil {
+				t.Errorf("CreateProduct %d: %v", i, err)
+				return
+			}
+			ids[i] = product.Id
+		}()
+	}
+	wg.Wait()
+
+	for i, id := range ids {
+		if id != ids[0] {
+			t.Errorf("create %d returned %s, want %s like the first", i, id, ids[0])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/chirik/products/internal/observability"
+	"github.com/chirik/products/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ndjsonFlushEvery is how many products are written between flushes, so the
+// export reaches the client incrementally without a write per product.
+const ndjsonFlushEvery = 100
+
+// NDJSONExportHandler streams the catalog as newline-delimited JSON, one
+// product per line, optionally restricted by the category query parameter.
+// Products are written as they are read, so memory use does not grow with
+// the catalog.
+func NDJSONExportHandler(repo repository.Repository, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+		flusher, _ := w.(http.Flusher)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+
+		encoder := json.NewEncoder(w)
+		written := 0
+		err := repo.StreamProducts(ctx, repository.ListOptions{
+			Category: r.URL.Query().Get("category"),
+		}, func(p *repository.Product) error {
+			if err := encoder.Encode(p); err != nil {
+				return err
+			}
+			written++
+			if flusher != nil && written%ndjsonFlushEvery == 0 {
+				flusher.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			// The status line is already sent once a product was written,
+			// so a truncated body is all the client can be told.
+			observability.LoggerFromContext(ctx, logger).Error("Failed to export products",
+				zap.Int("written", written),
+				zap.Error(err),
+			)
+			if written == 0 {
+				http.Error(w, "failed to export products", http.StatusInternalServerError)
+			}
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	})
+}
@@ -2,24 +2,69 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/chirik/products/internal/middleware"
+	"github.com/chirik/products/internal/observability"
 	"github.com/chirik/products/internal/repository"
 	"github.com/chirik/products/proto"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// readSourceHeader reports whether GetProduct was served from the cache or
+// from Redis.
+const readSourceHeader = "x-read-source"
+
 type ProductsServer struct {
 	proto.UnimplementedProductsServiceServer
 	repo   repository.Repository
 	logger *zap.Logger
+	opts   Options
+}
+
+// Options tune how the server validates and presents products.
+type Options struct {
+	// LowStockThreshold is the stock below which a product is reported as
+	// LOW_STOCK rather than IN_STOCK.
+	LowStockThreshold int32
+
+	// DefaultPageSize applies to ListProducts requests without a page size;
+	// larger page sizes than MaxPageSize are rejected.
+	DefaultPageSize int32
+	MaxPageSize     int32
+
+	// MaxPrice and MaxStock bound the price and stock of created and
+	// updated products.
+	MaxPrice float64
+	MaxStock int32
+
+	// MaxBatchSize is the most products one CreateProductsBatch call may
+	// create.
+	MaxBatchSize int
+
+	// MaxTags and MaxTagLength cap the tags of created and updated
+	// products, and MaxAttributes and MaxAttributeLength their attribute
+	// entries.
+	MaxTags            int
+	MaxTagLength       int
+	MaxAttributes      int
+	MaxAttributeLength int
 }
 
-func NewProductsServer(repo repository.Repository, logger *zap.Logger) *ProductsServer {
+func NewProductsServer(repo repository.Repository, logger *zap.Logger, opts Options) *ProductsServer {
 	return &ProductsServer{
 		repo:   repo,
 		logger: logger,
+		opts:   opts,
 	}
 }
 
@@ -28,42 +73,129 @@ func (s *ProductsServer) ListProducts(ctx context.Context, req *proto.ListProduc
 		req.Page = 1
 	}
 	if req.PageSize <= 0 {
-		req.PageSize = 10
+		req.PageSize = s.opts.DefaultPageSize
 	}
-	if req.PageSize > 100 {
-		req.PageSize = 100
+	if req.PageSize > s.opts.MaxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page size %d exceeds the maximum of %d", req.PageSize, s.opts.MaxPageSize)
 	}
 
-	products, total, err := s.repo.ListProducts(
-		ctx,
-		req.Page,
-		req.PageSize,
-		req.Category,
-		req.SearchQuery,
-	)
+	if req.MinPrice < 0 || req.MaxPrice < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "price bounds must be non-negative")
+	}
+	if req.MaxPrice > 0 && req.MinPrice > req.MaxPrice {
+		return nil, status.Errorf(codes.InvalidArgument, "min price must not exceed max price")
+	}
+	if req.MinStock < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "min stock must be non-negative")
+	}
+	if slices.ContainsFunc(req.Tags, isBlank) {
+		return nil, status.Errorf(codes.InvalidArgument, "tag filters must not be blank")
+	}
+
+	if !repository.ValidSortField(req.SortBy) {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported sort field: %s", req.SortBy)
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	result, err := s.repo.ListProducts(ctx, repository.ListOptions{
+		Page:        req.Page,
+		PageSize:    req.PageSize,
+		Category:    req.Category,
+		SearchQuery: req.SearchQuery,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		SortBy:      req.SortBy,
+		SortDesc:    req.SortDesc,
+		Fuzzy:       req.Fuzzy,
+		MinStock:    minStock(req),
+		Tags:        req.Tags,
+		Highlight:   req.Highlight,
+
+		IncludeScore: req.IncludeScore,
+		PageToken:    req.PageToken,
+	})
+	done()
 	if err != nil {
-		s.logger.Error("Failed to list products", zap.Error(err))
+		switch {
+		case errors.Is(err, repository.ErrInvalidPageToken) || errors.Is(err, repository.ErrUnsortableField):
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			return nil, status.FromContextError(err).Err()
+		}
+		s.log(ctx).Error("Failed to list products", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to list products: %v", err)
 	}
 
-	protoProducts := make([]*proto.Product, len(products))
-	for i, p := range products {
-		protoProducts[i] = &proto.Product{
-			Id:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-			Category:    p.Category,
-			Stock:       p.Stock,
-			CreatedAt:   p.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	done = observability.StartTiming(ctx, "serialization")
+	version := middleware.APIVersionFromContext(ctx)
+	protoProducts := make([]*proto.Product, len(result.Products))
+	for i, p := range result.Products {
+		protoProducts[i] = toProtoProduct(p, version)
+		if req.StockAsStatus {
+			s.replaceStockWithStatus(protoProducts[i])
+		}
+		if req.Highlight && req.SearchQuery != "" {
+			protoProducts[i].HighlightedName = &p.HighlightedName
+			protoProducts[i].HighlightedDescription = &p.HighlightedDescription
 		}
 	}
+	done()
 
 	return &proto.ListProductsResponse{
-		Products: protoProducts,
-		Total:    total,
-		Page:     req.Page,
-		PageSize: req.PageSize,
+		Products:      protoProducts,
+		Total:         result.Total,
+		Page:          req.Page,
+		PageSize:      req.PageSize,
+		NextPageToken: result.NextPageToken,
+		ApiVersion:    version,
+	}, nil
+}
+
+func (s *ProductsServer) ListModifiedSince(ctx context.Context, req *proto.ListModifiedSinceRequest) (*proto.ListProductsResponse, error) {
+	if req.Since == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "since is required")
+	}
+	if err := req.Since.CheckValid(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid since: %v", err)
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = s.opts.DefaultPageSize
+	}
+	if req.PageSize > s.opts.MaxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page size %d exceeds the maximum of %d", req.PageSize, s.opts.MaxPageSize)
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	result, err := s.repo.ListModifiedSince(ctx, req.Since.AsTime(), req.PageSize, req.PageToken)
+	done()
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInvalidPageToken):
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			return nil, status.FromContextError(err).Err()
+		}
+		s.log(ctx).Error("Failed to list modified products", zap.Time("since", req.Since.AsTime()), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list modified products: %v", err)
+	}
+
+	done = observability.StartTiming(ctx, "serialization")
+	version := middleware.APIVersionFromContext(ctx)
+	protoProducts := make([]*proto.Product, len(result.Products))
+	for i, p := range result.Products {
+		protoProducts[i] = toProtoProduct(p, version)
+		if req.StockAsStatus {
+			s.replaceStockWithStatus(protoProducts[i])
+		}
+	}
+	done()
+
+	return &proto.ListProductsResponse{
+		Products:      protoProducts,
+		Total:         result.Total,
+		PageSize:      req.PageSize,
+		NextPageToken: result.NextPageToken,
+		ApiVersion:    version,
 	}, nil
 }
 
@@ -71,30 +203,61 @@ func (s *ProductsServer) GetProduct(ctx context.Context, req *proto.GetProductRe
 	if req.Id == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "product id is required")
 	}
+	maxStaleness := time.Duration(-1)
+	if req.MaxStalenessMs != nil {
+		if *req.MaxStalenessMs < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "max_staleness_ms must not be negative")
+		}
+		maxStaleness = time.Duration(*req.MaxStalenessMs) * time.Millisecond
+	}
 
-	product, err := s.repo.GetProduct(ctx, req.Id)
+	done := observability.StartTiming(ctx, "repository")
+	product, source, err := s.repo.GetProductWithin(ctx, req.Id, maxStaleness)
+	done()
 	if err != nil {
-		s.logger.Error("Failed to get product", zap.String("id", req.Id), zap.Error(err))
+		s.log(ctx).Error("Failed to get product", zap.String("id", req.Id), zap.Error(err))
 		return nil, status.Errorf(codes.NotFound, "product not found: %v", err)
 	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(readSourceHeader, string(source))); err != nil {
+		return nil, err
+	}
 
-	return &proto.Product{
-		Id:          product.ID,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		Category:    product.Category,
-		Stock:       product.Stock,
-		CreatedAt:   product.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-	}, nil
+	protoProduct := toProtoProduct(product, middleware.APIVersionFromContext(ctx))
+	if req.StockAsStatus {
+		s.replaceStockWithStatus(protoProduct)
+	}
+	return protoProduct, nil
 }
 
-func (s *ProductsServer) CreateProduct(ctx context.Context, req *proto.CreateProductRequest) (*proto.Product, error) {
-	if req.Name == "" {
-		return nil, status.Errorf(codes.InvalidArgument, "product name is required")
+func (s *ProductsServer) GetProductBySKU(ctx context.Context, req *proto.GetProductBySKURequest) (*proto.Product, error) {
+	if req.Sku == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "sku is required")
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	product, err := s.repo.GetProductBySKU(ctx, req.Sku)
+	done()
+	if err != nil {
+		if errors.Is(err, repository.ErrProductNotFound) {
+			return nil, status.Errorf(codes.NotFound, "product not found: %v", err)
+		}
+		s.log(ctx).Error("Failed to get product by sku", zap.String("sku", req.Sku), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get product: %v", err)
 	}
-	if req.Price < 0 {
-		return nil, status.Errorf(codes.InvalidArgument, "product price must be non-negative")
+
+	return toProtoProduct(product, middleware.APIVersionFromContext(ctx)), nil
+}
+
+func (s *ProductsServer) CreateProduct(ctx context.Context, req *proto.CreateProductRequest) (*proto.Product, error) {
+	var violations fieldViolations
+	violations.validateProductFields(s.opts, req.Name, req.Price, req.Stock)
+	violations.validateSKU(req.Sku)
+	violations.validateTags(s.opts, req.Tags)
+	violations.validateAttributes(s.opts, req.Attributes)
+	currency := violations.validateCurrency(req.Currency)
+	violations.validatePricePrecision(req.Price, defaultCurrency(req.Currency, currency))
+	if err := violations.err(); err != nil {
+		return nil, err
 	}
 
 	product := &repository.Product{
@@ -103,21 +266,520 @@ func (s *ProductsServer) CreateProduct(ctx context.Context, req *proto.CreatePro
 		Price:       req.Price,
 		Category:    req.Category,
 		Stock:       req.Stock,
+		SKU:         req.Sku,
+		Tags:        req.Tags,
+		Attributes:  req.Attributes,
+		Currency:    currency,
 	}
 
-	if err := s.repo.CreateProduct(ctx, product); err != nil {
-		s.logger.Error("Failed to create product", zap.Error(err))
+	done := observability.StartTiming(ctx, "repository")
+	product, err := s.repo.CreateProductIdempotent(ctx, idempotencyKey(ctx), product)
+	done()
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrIdempotencyInProgress):
+			return nil, status.Errorf(codes.Aborted, "%v", err)
+		case errors.Is(err, repository.ErrDuplicateSKU):
+			return nil, status.Errorf(codes.AlreadyExists, "%v", err)
+		case errors.Is(err, repository.ErrPricePrecision):
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		s.log(ctx).Error("Failed to create product", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to create product: %v", err)
 	}
 
-	return &proto.Product{
-		Id:          product.ID,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		Category:    product.Category,
-		Stock:       product.Stock,
-		CreatedAt:   product.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	return toProtoProduct(product, middleware.APIVersionFromContext(ctx)), nil
+}
+
+func (s *ProductsServer) CreateProductsBatch(ctx context.Context, req *proto.CreateProductsBatchRequest) (*proto.CreateProductsBatchResponse, error) {
+	if len(req.Products) > s.opts.MaxBatchSize {
+		var violations fieldViolations
+		violations.add("products", fmt.Sprintf("a batch may create at most %d products", s.opts.MaxBatchSize))
+		return nil, violations.err()
+	}
+
+	results := make([]*proto.CreateProductsBatchResult, len(req.Products))
+	products := make([]*repository.Product, 0, len(req.Products))
+	positions := make([]int, 0, len(req.Products))
+	for i, item := range req.Products {
+		var violations fieldViolations
+		violations.validateProductFields(s.opts, item.Name, item.Price, item.Stock)
+		violations.validateSKU(item.Sku)
+		violations.validateTags(s.opts, item.Tags)
+		violations.validateAttributes(s.opts, item.Attributes)
+		currency := violations.validateCurrency(item.Currency)
+		violations.validatePricePrecision(item.Price, defaultCurrency(item.Currency, currency))
+		if err := violations.err(); err != nil {
+			results[i] = batchFailure(err)
+			continue
+		}
+		products = append(products, &repository.Product{
+			Name:        item.Name,
+			Description: item.Description,
+			Price:       item.Price,
+			Category:    item.Category,
+			Stock:       item.Stock,
+			SKU:         item.Sku,
+			Tags:        item.Tags,
+			Attributes:  item.Attributes,
+			Currency:    currency,
+		})
+		positions = append(positions, i)
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	errs := s.repo.CreateProducts(ctx, products)
+	done()
+
+	version := middleware.APIVersionFromContext(ctx)
+	for j, err := range errs {
+		i := positions[j]
+		if err != nil {
+			if errors.Is(err, repository.ErrDuplicateSKU) {
+				results[i] = batchFailure(status.Errorf(codes.AlreadyExists, "%v", err))
+				continue
+			}
+			if errors.Is(err, repository.ErrPricePrecision) {
+				results[i] = batchFailure(status.Errorf(codes.InvalidArgument, "%v", err))
+				continue
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				results[i] = batchFailure(status.FromContextError(ctxErr).Err())
+				continue
+			}
+			s.log(ctx).Error("Failed to create batch product", zap.Int("index", i), zap.Error(err))
+			results[i] = batchFailure(status.Errorf(codes.Internal, "failed to create product: %v", err))
+			continue
+		}
+		results[i] = &proto.CreateProductsBatchResult{Product: toProtoProduct(products[j], version)}
+	}
+
+	resp := &proto.CreateProductsBatchResponse{Results: results}
+	for _, result := range results {
+		if result.Product != nil {
+			resp.Created++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp, nil
+}
+
+// defaultCurrency returns the currency a create stores: canonical, the
+// validated currency, or the default when the request has none. An invalid
+// currency gives "", which skips the checks that depend on it.
+func defaultCurrency(requested, canonical string) string {
+	if requested == "" {
+		return repository.DefaultCurrency
+	}
+	return canonical
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+// minStock combines the stock filters of a listing request.
+func minStock(req *proto.ListProductsRequest) int32 {
+	if req.InStockOnly && req.MinStock < 1 {
+		return 1
+	}
+	return req.MinStock
+}
+
+// batchFailure reports a failed CreateProductsBatch item with err's status.
+func batchFailure(err error) *proto.CreateProductsBatchResult {
+	st := status.Convert(err)
+	return &proto.CreateProductsBatchResult{Code: int32(st.Code()), Error: st.Message()}
+}
+
+func (s *ProductsServer) UpdateProduct(ctx context.Context, req *proto.UpdateProductRequest) (*proto.Product, error) {
+	var violations fieldViolations
+	if req.Id == "" {
+		violations.add("id", "product id is required")
+	}
+	violations.validateProductFields(s.opts, req.Name, req.Price, req.Stock)
+	violations.validateSKU(req.Sku)
+	if req.ExpectedVersion < 0 {
+		violations.add("expected_version", "expected version must be non-negative")
+	}
+	violations.validateTags(s.opts, req.Tags)
+	currency := violations.validateCurrency(req.Currency)
+	violations.validatePricePrecision(req.Price, currency)
+	if req.ClearTags && len(req.Tags) > 0 {
+		violations.add("clear_tags", "clear_tags cannot be combined with tags")
+	}
+	violations.validateAttributes(s.opts, req.Attributes)
+	if req.ClearAttributes && len(req.Attributes) > 0 {
+		violations.add("clear_attributes", "clear_attributes cannot be combined with attributes")
+	}
+	if err := violations.err(); err != nil {
+		return nil, err
+	}
+
+	// Nil tags and attributes keep the stored ones; empty ones clear them.
+	tags, attributes := req.Tags, req.Attributes
+	switch {
+	case req.ClearTags:
+		tags = []string{}
+	case len(tags) == 0:
+		tags = nil
+	}
+	switch {
+	case req.ClearAttributes:
+		attributes = map[string]string{}
+	case len(attributes) == 0:
+		attributes = nil
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	product, err := s.repo.UpdateProduct(ctx, &repository.Product{
+		ID:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Category:    req.Category,
+		Stock:       req.Stock,
+		SKU:         req.Sku,
+		Tags:        tags,
+		Attributes:  attributes,
+		Currency:    currency,
+	}, req.ExpectedVersion)
+	done()
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProductNotFound):
+			return nil, status.Errorf(codes.NotFound, "product not found: %v", err)
+		case errors.Is(err, repository.ErrVersionConflict):
+			return nil, status.Errorf(codes.Aborted, "%v", err)
+		case errors.Is(err, repository.ErrDuplicateSKU):
+			return nil, status.Errorf(codes.AlreadyExists, "%v", err)
+		case errors.Is(err, repository.ErrPricePrecision):
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		s.log(ctx).Error("Failed to update product", zap.String("id", req.Id), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to update product: %v", err)
+	}
+
+	return toProtoProduct(product, middleware.APIVersionFromContext(ctx)), nil
+}
+
+func (s *ProductsServer) IncrementStock(ctx context.Context, req *proto.IncrementStockRequest) (*proto.Product, error) {
+	if req.Id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "product id is required")
+	}
+	if req.Quantity <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must be positive")
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	product, err := s.repo.IncrementStock(ctx, req.Id, req.Quantity)
+	done()
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProductNotFound):
+			return nil, status.Errorf(codes.NotFound, "product not found: %v", err)
+		case errors.Is(err, repository.ErrStockOverflow):
+			return nil, status.Errorf(codes.OutOfRange, "stock would overflow: %v", err)
+		}
+		s.log(ctx).Error("Failed to increment stock", zap.String("id", req.Id), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to increment stock: %v", err)
+	}
+
+	return toProtoProduct(product, middleware.APIVersionFromContext(ctx)), nil
+}
+
+func (s *ProductsServer) DecrementStock(ctx context.Context, req *proto.DecrementStockRequest) (*proto.Product, error) {
+	if req.Id == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "product id is required")
+	}
+	if req.Quantity <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must be positive")
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	product, err := s.repo.DecrementStock(ctx, req.Id, req.Quantity)
+	done()
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProductNotFound):
+			return nil, status.Errorf(codes.NotFound, "product not found: %v", err)
+		case errors.Is(err, repository.ErrInsufficientStock):
+			return nil, status.Errorf(codes.FailedPrecondition, "insufficient stock: %v", err)
+		}
+		s.log(ctx).Error("Failed to decrement stock", zap.String("id", req.Id), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to decrement stock: %v", err)
+	}
+
+	return toProtoProduct(product, middleware.APIVersionFromContext(ctx)), nil
+}
+
+func (s *ProductsServer) StreamProducts(req *proto.ListProductsRequest, stream proto.ProductsService_StreamProductsServer) error {
+	if req.MinPrice < 0 || req.MaxPrice < 0 {
+		return status.Errorf(codes.InvalidArgument, "price bounds must be non-negative")
+	}
+	if req.MaxPrice > 0 && req.MinPrice > req.MaxPrice {
+		return status.Errorf(codes.InvalidArgument, "min price must not exceed max price")
+	}
+	if req.MinStock < 0 {
+		return status.Errorf(codes.InvalidArgument, "min stock must be non-negative")
+	}
+	if slices.ContainsFunc(req.Tags, isBlank) {
+		return status.Errorf(codes.InvalidArgument, "tag filters must not be blank")
+	}
+
+	opts := repository.ListOptions{
+		Category:    req.Category,
+		SearchQuery: req.SearchQuery,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		Fuzzy:       req.Fuzzy,
+		MinStock:    minStock(req),
+		Tags:        req.Tags,
+	}
+
+	version := middleware.APIVersionFromContext(stream.Context())
+	err := s.repo.StreamProducts(stream.Context(), opts, func(p *repository.Product) error {
+		protoProduct := toProtoProduct(p, version)
+		if req.StockAsStatus {
+			s.replaceStockWithStatus(protoProduct)
+		}
+		return stream.Send(protoProduct)
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return status.FromContextError(err).Err()
+		}
+		s.log(stream.Context()).Error("Failed to stream products", zap.Error(err))
+		return status.Errorf(codes.Internal, "failed to stream products: %v", err)
+	}
+	return nil
+}
+
+func (s *ProductsServer) ListCategories(ctx context.Context, req *proto.ListCategoriesRequest) (*proto.ListCategoriesResponse, error) {
+	done := observability.StartTiming(ctx, "repository")
+	counts, err := s.repo.ListCategories(ctx)
+	done()
+	if err != nil {
+		s.log(ctx).Error("Failed to list categories", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list categories: %v", err)
+	}
+
+	categories := make([]*proto.CategoryCount, len(counts))
+	for i, c := range counts {
+		categories[i] = &proto.CategoryCount{
+			Category: c.Category,
+			Count:    c.Count,
+		}
+	}
+
+	return &proto.ListCategoriesResponse{Categories: categories}, nil
+}
+
+const (
+	defaultSuggestionLimit = 5
+	maxSuggestionLimit     = 50
+)
+
+func (s *ProductsServer) SuggestProducts(ctx context.Context, req *proto.SuggestProductsRequest) (*proto.SuggestProductsResponse, error) {
+	if strings.TrimSpace(req.Prefix) == "" {
+		return nil, status.Error(codes.InvalidArgument, "prefix is required")
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultSuggestionLimit
+	}
+	if limit > maxSuggestionLimit {
+		limit = maxSuggestionLimit
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	suggestions, err := s.repo.SuggestProducts(ctx, req.Prefix, limit)
+	done()
+	if err != nil {
+		s.log(ctx).Error("Failed to suggest products", zap.String("prefix", req.Prefix), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to suggest products: %v", err)
+	}
+
+	return &proto.SuggestProductsResponse{Suggestions: suggestions}, nil
+}
+
+func (s *ProductsServer) GetCatalogChecksum(ctx context.Context, req *proto.GetCatalogChecksumRequest) (*proto.CatalogChecksum, error) {
+	done := observability.StartTiming(ctx, "repository")
+	checksum, count, err := s.repo.CatalogChecksum(ctx)
+	done()
+	if err != nil {
+		s.log(ctx).Error("Failed to compute catalog checksum", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to compute catalog checksum: %v", err)
+	}
+
+	return &proto.CatalogChecksum{
+		Checksum:     checksum,
+		ProductCount: count,
 	}, nil
 }
 
+func (s *ProductsServer) GetCatalogStats(ctx context.Context, req *proto.GetCatalogStatsRequest) (*proto.CatalogStats, error) {
+	done := observability.StartTiming(ctx, "repository")
+	stats, err := s.repo.CatalogStats(ctx)
+	done()
+	if err != nil {
+		s.log(ctx).Error("Failed to compute catalog stats", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to compute catalog stats: %v", err)
+	}
+
+	categories := make([]*proto.CategoryStats, len(stats.Categories))
+	for i, c := range stats.Categories {
+		categories[i] = &proto.CategoryStats{
+			Category: c.Category,
+			Stats:    toProtoStatsSummaries(c.Currencies),
+		}
+	}
+
+	return &proto.CatalogStats{
+		Totals:     toProtoStatsSummaries(stats.Currencies),
+		Categories: categories,
+	}, nil
+}
+
+func toProtoStatsSummaries(summaries []repository.StatsSummary) []*proto.StatsSummary {
+	result := make([]*proto.StatsSummary, len(summaries))
+	for i, s := range summaries {
+		result[i] = toProtoStatsSummary(s)
+	}
+	return result
+}
+
+func toProtoStatsSummary(s repository.StatsSummary) *proto.StatsSummary {
+	return &proto.StatsSummary{
+		Currency:     s.Currency,
+		ProductCount: s.Count,
+		TotalPrice:   s.TotalPrice,
+		AveragePrice: s.AveragePrice(),
+		MinPrice:     s.MinPrice,
+		MaxPrice:     s.MaxPrice,
+		TotalStock:   s.TotalStock,
+	}
+}
+
+func (s *ProductsServer) ReindexProducts(req *proto.ReindexProductsRequest, stream proto.ProductsService_ReindexProductsServer) error {
+	err := s.repo.Reindex(stream.Context(), req.Restart, func(p repository.ReindexProgress) error {
+		return stream.Send(&proto.ReindexProgress{
+			Processed: p.Processed,
+			Total:     p.Total,
+			Percent:   p.Percent(),
+			Done:      p.Done,
+		})
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrSearchUnavailable):
+			return status.Errorf(codes.FailedPrecondition, "%v", err)
+		case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			return status.FromContextError(err).Err()
+		}
+		s.log(stream.Context()).Error("Failed to reindex products", zap.Error(err))
+		return status.Errorf(codes.Internal, "failed to reindex products: %v", err)
+	}
+	return nil
+}
+
+func (s *ProductsServer) WatchExpirations(req *proto.WatchExpirationsRequest, stream proto.ProductsService_WatchExpirationsServer) error {
+	err := s.repo.WatchExpirations(stream.Context(), func(id string) error {
+		return stream.Send(&proto.ProductExpiration{
+			Id:        id,
+			ExpiredAt: time.Now().Format("2006-01-02T15:04:05Z07:00"),
+		})
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		s.log(stream.Context()).Error("Failed to watch expirations", zap.Error(err))
+		return status.Errorf(codes.Internal, "failed to watch expirations: %v", err)
+	}
+	return nil
+}
+
+func (s *ProductsServer) DeleteProductsByCategory(ctx context.Context, req *proto.DeleteProductsByCategoryRequest) (*proto.DeleteProductsByCategoryResponse, error) {
+	if strings.TrimSpace(req.Category) == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "category is required")
+	}
+
+	done := observability.StartTiming(ctx, "repository")
+	deleted, err := s.repo.DeleteProductsByCategory(ctx, req.Category)
+	done()
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, status.FromContextError(err).Err()
+		}
+		s.log(ctx).Error("Failed to delete products by category",
+			zap.String("category", req.Category),
+			zap.Int32("deleted", deleted),
+			zap.Error(err),
+		)
+		return nil, status.Errorf(codes.Internal, "failed to delete products after removing %d: %v", deleted, err)
+	}
+
+	s.log(ctx).Info("Deleted products by category",
+		zap.String("category", req.Category),
+		zap.Int32("deleted", deleted),
+	)
+	return &proto.DeleteProductsByCategoryResponse{Deleted: deleted}, nil
+}
+
+// idempotencyKeyHeader carries a client-chosen key that makes retried
+// CreateProduct calls return the product created by the first attempt.
+const idempotencyKeyHeader = "idempotency-key"
+
+func idempotencyKey(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, idempotencyKeyHeader); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+// log returns the request-scoped logger for ctx.
+func (s *ProductsServer) log(ctx context.Context) *zap.Logger {
+	return observability.LoggerFromContext(ctx, s.logger)
+}
+
+// replaceStockWithStatus swaps the exact stock of product for a coarse
+// status, for clients that must not see inventory levels.
+func (s *ProductsServer) replaceStockWithStatus(product *proto.Product) {
+	switch {
+	case product.Stock <= 0:
+		product.StockStatus = proto.StockStatus_OUT_OF_STOCK
+	case product.Stock < s.opts.LowStockThreshold:
+		product.StockStatus = proto.StockStatus_LOW_STOCK
+	default:
+		product.StockStatus = proto.StockStatus_IN_STOCK
+	}
+	product.Stock = 0
+}
+
+// toProtoProduct converts a product for the negotiated API version: version 1
+// clients get string timestamps, later versions get protobuf Timestamps.
+func toProtoProduct(p *repository.Product, version int32) *proto.Product {
+	product := &proto.Product{
+		Id:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		Category:    p.Category,
+		Stock:       p.Stock,
+		Score:       p.Score,
+		Tags:        p.Tags,
+		Attributes:  p.Attributes,
+		Version:     p.Version,
+		Slug:        p.Slug,
+		Sku:         p.SKU,
+		Currency:    p.Currency,
+	}
+	if version >= middleware.APIVersion2 {
+		product.CreatedTime = timestamppb.New(p.CreatedAt)
+		product.UpdatedTime = timestamppb.New(p.UpdatedAt)
+	} else {
+		product.CreatedAt = p.CreatedAt.Format("2006-01-02T15:04:05Z07:00")
+		product.UpdatedAt = p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return product
+}
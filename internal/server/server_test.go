@@ -0,0 +1,316 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/chirik/products/internal/repository"
+	"github.com/chirik/products/proto"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testOptions are the server options the tests run with.
+var testOptions = Options{
+	DefaultPageSize:    10,
+	MaxPageSize:        100,
+	MaxPrice:           1_000_000,
+	MaxStock:           1_000_000,
+	MaxBatchSize:       100,
+	MaxTags:            5,
+	MaxTagLength:       20,
+	MaxAttributes:      3,
+	MaxAttributeLength: 20,
+}
+
+// newTestClient serves a ProductsServer over an in-memory connection and
+// returns a client for it, along with the repository behind the server,
+// which is backed by an in-process Redis without RediSearch. opts may
+// adjust the repository options.
+func newTestClient(t *testing.T, opts ...func(*repository.Options)) (proto.ProductsServiceClient, *repository.RedisRepository) {
+	t.Helper()
+
+	listener, repo := newTestServer(t, nil, opts...)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		bufconnDialer(listener),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("grpc.NewClient: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewProductsServiceClient(conn), repo
+}
+
+// newTestServer serves a ProductsServer created with serverOpts on an
+// in-memory listener, returning the listener and the repository behind the
+// server as newTestClient does.
+func newTestServer(t *testing.T, serverOpts []grpc.ServerOption, opts ...func(*repository.Options)) (*bufconn.Listener, *repository.RedisRepository) {
+	t.Helper()
+
+	redis := miniredis.RunT(t)
+	options := repository.Options{
+		RedisMode:       repository.RedisModeSingle,
+		RedisAddrs:      []string{redis.Addr()},
+		MGetBatchSize:   100,
+		MGetParallelism: 4,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	repo, err := repository.NewRedisRepository(options, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRedisRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(serverOpts...)
+	proto.RegisterProductsServiceServer(server, NewProductsServer(repo, zap.NewNop(), testOptions))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener, repo
+}
+
+// bufconnDialer dials listener whatever the target.
+func bufconnDialer(listener *bufconn.Listener) grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+}
+
+// violatedFields returns the fields of the google.rpc.BadRequest violations
+// of err, failing the test unless err is an InvalidArgument status.
+func violatedFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("error = %v, want InvalidArgument", err)
+	}
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				fields = append(fields, violation.Field)
+			}
+		}
+	}
+	return fields
+}
+
+func TestListProductsPageSize(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	for i := 0; i < 15; i++ {
+		if _, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Mouse", Category: "Electronics", Price: float64(i + 1)}); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		pageSize int32
+		want     int32
+		wantCode codes.Code
+	}{
+		{name: "unset uses the default", pageSize: 0, want: testOptions.DefaultPageSize},
+		{name: "negative uses the default", pageSize: -3, want: testOptions.DefaultPageSize},
+		{name: "below the max", pageSize: 12, want: 12},
+		{name: "at the max", pageSize: testOptions.MaxPageSize, want: testOptions.MaxPageSize},
+		{name: "over the max is rejected", pageSize: testOptions.MaxPageSize + 1, wantCode: codes.InvalidArgument},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := client.ListProducts(ctx, &proto.ListProductsRequest{PageSize: tc.pageSize})
+			if code := status.Code(err); code != tc.wantCode {
+				t.Fatalf("ListProducts(page_size %d) = %v, want %v", tc.pageSize, err, tc.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if resp.PageSize != tc.want {
+				t.Errorf("page size = %d, want %d", resp.PageSize, tc.want)
+			}
+			if want := min(tc.want, 15); int32(len(resp.Products)) != want {
+				t.Errorf("listed %d products, want %d", len(resp.Products), want)
+			}
+		})
+	}
+}
+
+func TestCreateProductKeepsTagsAndAttributes(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateProduct(ctx, &proto.CreateProductRequest{
+		Name:       "Mug",
+		Price:      8,
+		Tags:       []string{"kitchen", "gift"},
+		Attributes: map[string]string{"color": "blue"},
+	})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	got, err := client.GetProduct(ctx, &proto.GetProductRequest{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if strings.Join(got.Tags, ",") != "kitchen,gift" || got.Attributes["color"] != "blue" || len(got.Attributes) != 1 {
+		t.Errorf("stored tags = %q, attributes = %v; want [kitchen gift] and color=blue", got.Tags, got.Attributes)
+	}
+}
+
+func TestCreateProductCapsTagsAndAttributes(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	long := strings.Repeat("x", 21)
+	for _, tc := range []struct {
+		name       string
+		tags       []string
+		attributes map[string]string
+		want       string
+	}{
+		{name: "too many tags", tags: []string{"a", "b", "c", "d", "e", "f"}, want: "at most 5 tags"},
+		{name: "long tag", tags: []string{"a", long}, want: "at most 20 characters"},
+		{name: "too many attributes", attributes: map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}, want: "at most 3 attributes"},
+		{name: "long attribute name", attributes: map[string]string{long: "1"}, want: "at most 20 characters"},
+		{name: "long attribute value", attributes: map[string]string{"color": long}, want: "at most 20 characters"},
+		{name: "blank attribute name", attributes: map[string]string{" ": "1"}, want: "must not be blank"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Mug", Price: 8, Tags: tc.tags, Attributes: tc.attributes})
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("CreateProduct = %v, want InvalidArgument mentioning %q", err, tc.want)
+			}
+		})
+	}
+
+	// Tags and attributes exactly at the caps are accepted, and a tag's
+	// surrounding whitespace doesn't count towards its length.
+	_, err := client.CreateProduct(ctx, &proto.CreateProductRequest{
+		Name:       "Mug",
+		Price:      8,
+		Tags:       []string{"a", "b", "c", "d", "  " + long[1:] + "  "},
+		Attributes: map[string]string{"a": long[1:], "b": "2", "c": "3"},
+	})
+	if err != nil {
+		t.Errorf("CreateProduct at the caps: %v", err)
+	}
+}
+
+func TestUpdateProductTagsAndAttributes(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateProduct(ctx, &proto.CreateProductRequest{
+		Name:       "Mug",
+		Price:      8,
+		Tags:       []string{"kitchen"},
+		Attributes: map[string]string{"color": "blue"},
+	})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	update := func(req *proto.UpdateProductRequest) *proto.Product {
+		t.Helper()
+		req.Id, req.Name, req.Price = created.Id, "Mug", 8
+		updated, err := client.UpdateProduct(ctx, req)
+		if err != nil {
+			t.Fatalf("UpdateProduct: %v", err)
+		}
+		return updated
+	}
+
+	got := update(&proto.UpdateProductRequest{})
+	if strings.Join(got.Tags, ",") != "kitchen" || got.Attributes["color"] != "blue" {
+		t.Errorf("update without tags or attributes left %q and %v, want them kept", got.Tags, got.Attributes)
+	}
+	got = update(&proto.UpdateProductRequest{Tags: []string{"gift"}, Attributes: map[string]string{"size": "L"}})
+	if strings.Join(got.Tags, ",") != "gift" || len(got.Attributes) != 1 || got.Attributes["size"] != "L" {
+		t.Errorf("update with tags and attributes left %q and %v, want them replaced", got.Tags, got.Attributes)
+	}
+	got = update(&proto.UpdateProductRequest{ClearTags: true, ClearAttributes: true})
+	if len(got.Tags) != 0 || len(got.Attributes) != 0 {
+		t.Errorf("clearing update left %q and %v, want none", got.Tags, got.Attributes)
+	}
+
+	_, err = client.UpdateProduct(ctx, &proto.UpdateProductRequest{Id: created.Id, Name: "Mug", Tags: []string{"a", "b", "c", "d", "e", "f"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("UpdateProduct with too many tags = %v, want InvalidArgument", err)
+	}
+	_, err = client.UpdateProduct(ctx, &proto.UpdateProductRequest{Id: created.Id, Name: "Mug", ClearAttributes: true, Attributes: map[string]string{"a": "1"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("UpdateProduct clearing and setting attributes = %v, want InvalidArgument", err)
+	}
+}
+
+func TestListProductsStockFilters(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+	for i, stock := range []int32{0, 0, 1, 4, 5, 9} {
+		if _, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Mouse", Category: "Electronics", Price: float64(i + 1), Stock: stock}); err != nil {
+			t.Fatalf("CreateProduct: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		req      *proto.ListProductsRequest
+		floor    int32
+		want     int32
+		wantCode codes.Code
+	}{
+		{name: "no filter keeps out-of-stock products", req: &proto.ListProductsRequest{}, want: 6},
+		{name: "in stock only hides zero stock", req: &proto.ListProductsRequest{InStockOnly: true}, floor: 1, want: 4},
+		{name: "min stock of one hides zero stock", req: &proto.ListProductsRequest{MinStock: 1}, floor: 1, want: 4},
+		{name: "min stock is inclusive", req: &proto.ListProductsRequest{MinStock: 5}, floor: 5, want: 2},
+		{name: "min stock wins over in stock only", req: &proto.ListProductsRequest{InStockOnly: true, MinStock: 5}, floor: 5, want: 2},
+		{name: "negative min stock is rejected", req: &proto.ListProductsRequest{MinStock: -1}, wantCode: codes.InvalidArgument},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Pages of two, so the total has to count past the first page.
+			tc.req.PageSize = 2
+			resp, err := client.ListProducts(ctx, tc.req)
+			if code := status.Code(err); code != tc.wantCode {
+				t.Fatalf("ListProducts = %v, want %v", err, tc.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if resp.Total != tc.want {
+				t.Errorf("total = %d, want %d", resp.Total, tc.want)
+			}
+
+			var seen int32
+			for {
+				for _, product := range resp.Products {
+					if product.Stock < tc.floor {
+						t.Errorf("listed %s with stock %d", product.Id, product.Stock)
+					}
+				}
+				seen += int32(len(resp.Products))
+				if resp.NextPageToken == "" {
+					break
+				}
+				tc.req.PageToken = resp.NextPageToken
+				if resp, err = client.ListProducts(ctx, tc.req); err != nil {
+					t.Fatalf("ListProducts next page: %v", err)
+				}
+			}
+			if seen != tc.want {
+				t.Errorf("paged through %d products, want %d", seen, tc.want)
+			}
+		})
+	}
+}
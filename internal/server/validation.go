@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/chirik/products/internal/repository"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	maxNameLength = 200
+	maxSKULength  = 64
+)
+
+// fieldViolations collects validation failures to report as a
+// google.rpc.BadRequest, so clients can tell which fields to fix.
+type fieldViolations []*errdetails.BadRequest_FieldViolation
+
+func (v *fieldViolations) add(field, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+}
+
+// err returns an InvalidArgument status carrying every violation, or nil
+// if there are none.
+func (v fieldViolations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	descriptions := make([]string, len(v))
+	for i, violation := range v {
+		descriptions[i] = violation.Field + ": " + violation.Description
+	}
+	st := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(descriptions, "; "))
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// validateProductFields checks the fields shared by CreateProduct and
+// UpdateProduct. NaN and infinite prices are rejected explicitly, since
+// they slip through range comparisons and break the numeric search index.
+func (v *fieldViolations) validateProductFields(opts Options, name string, price float64, stock int32) {
+	switch {
+	case name == "":
+		v.add("name", "product name is required")
+	case utf8.RuneCountInString(name) > maxNameLength:
+		v.add("name", fmt.Sprintf("product name must be at most %d characters", maxNameLength))
+	}
+	switch {
+	case math.IsNaN(price) || math.IsInf(price, 0):
+		v.add("price", "product price must be a finite number")
+	case price < 0 || price > opts.MaxPrice:
+		v.add("price", fmt.Sprintf("product price must be between 0 and %g", opts.MaxPrice))
+	}
+	switch {
+	case stock < 0:
+		v.add("stock", "product stock must be non-negative")
+	case stock > opts.MaxStock:
+		v.add("stock", fmt.Sprintf("product stock must be at most %d", opts.MaxStock))
+	}
+}
+
+// validateSKU checks an optional SKU, which becomes part of a Redis key and
+// is compared verbatim, so whitespace and control characters are rejected.
+func (v *fieldViolations) validateSKU(sku string) {
+	switch {
+	case len(sku) > maxSKULength:
+		v.add("sku", fmt.Sprintf("sku must be at most %d bytes", maxSKULength))
+	case strings.IndexFunc(sku, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0:
+		v.add("sku", "sku must not contain whitespace or control characters")
+	}
+}
+
+// validateTags applies the tag caps of opts. Blank tags are allowed here and
+// dropped when the product is stored.
+func (v *fieldViolations) validateTags(opts Options, tags []string) {
+	if len(tags) > opts.MaxTags {
+		v.add("tags", fmt.Sprintf("a product may have at most %d tags", opts.MaxTags))
+		return
+	}
+	for _, tag := range tags {
+		if utf8.RuneCountInString(strings.TrimSpace(tag)) > opts.MaxTagLength {
+			v.add("tags", fmt.Sprintf("tags must be at most %d characters", opts.MaxTagLength))
+			return
+		}
+	}
+}
+
+// validateAttributes applies the attribute caps of opts to both the names
+// and the values.
+func (v *fieldViolations) validateAttributes(opts Options, attributes map[string]string) {
+	if len(attributes) > opts.MaxAttributes {
+		v.add("attributes", fmt.Sprintf("a product may have at most %d attributes", opts.MaxAttributes))
+		return
+	}
+	for name, value := range attributes {
+		if strings.TrimSpace(name) == "" {
+			v.add("attributes", "attribute names must not be blank")
+			return
+		}
+		if utf8.RuneCountInString(name) > opts.MaxAttributeLength || utf8.RuneCountInString(value) > opts.MaxAttributeLength {
+			v.add("attributes", fmt.Sprintf("attribute names and values must be at most %d characters", opts.MaxAttributeLength))
+			return
+		}
+	}
+}
+
+// validateCurrency checks an optional ISO 4217 currency code and returns it
+// in canonical upper case.
+func (v *fieldViolations) validateCurrency(code string) string {
+	if code == "" {
+		return ""
+	}
+	canonical, err := repository.ParseCurrency(code)
+	if err != nil {
+		v.add("currency", err.Error())
+		return ""
+	}
+	return canonical
+}
+
+// validatePricePrecision rejects a price with more decimals than the minor
+// unit of currency, rather than letting it be rounded when stored. It is
+// skipped when currency is empty, i.e. invalid or, on updates, kept from
+// the stored product; the repository checks the price against it then.
+func (v *fieldViolations) validatePricePrecision(price float64, currency string) {
+	if currency == "" {
+		return
+	}
+	if err := repository.CheckPricePrecision(price, currency); err != nil {
+		v.add("price", err.Error())
+	}
+}
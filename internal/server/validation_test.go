@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chirik/products/proto"
+)
+
+func TestCreateProductPriceRoundTrip(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Mouse", Category: "Electronics", Price: 19.99, Currency: "usd"})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+	got, err := client.GetProduct(ctx, &proto.GetProductRequest{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if got.Price != 19.99 || got.Currency != "USD" {
+		t.Errorf("stored price = %v %s, want exactly 19.99 USD", got.Price, got.Currency)
+	}
+}
+
+func TestCreateProductRejectsInvalidPrices(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		price    float64
+		currency string
+		want     []string
+	}{
+		{name: "unknown currency", price: 10, currency: "XYZ", want: []string{"currency"}},
+		{name: "not a currency code", price: 10, currency: "dollars", want: []string{"currency"}},
+		{name: "cents of a currency without them", price: 19.99, currency: "JPY", want: []string{"price"}},
+		{name: "fractions of a cent", price: 19.999, currency: "USD", want: []string{"price"}},
+		{name: "fractions of a cent by default", price: 0.001, want: []string{"price"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Mouse", Price: tc.price, Currency: tc.currency})
+			if got := violatedFields(t, err); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("violated fields = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUpdateProductChecksPriceAgainstStoredCurrency(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Tea", Price: 500, Currency: "JPY"})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	// Without a currency the update keeps JPY, which has no minor unit.
+	_, err = client.UpdateProduct(ctx, &proto.UpdateProductRequest{Id: created.Id, Name: "Tea", Price: 499.5})
+	if got := violatedFields(t, err); got != nil {
+		t.Errorf("violated fields = %q, want a plain InvalidArgument from the repository", got)
+	}
+
+	updated, err := client.UpdateProduct(ctx, &proto.UpdateProductRequest{Id: created.Id, Name: "Tea", Price: 499.5, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("UpdateProduct to EUR: %v", err)
+	}
+	if updated.Price != 499.5 || updated.Currency != "EUR" {
+		t.Errorf("updated price = %v %s, want 499.5 EUR", updated.Price, updated.Currency)
+	}
+}
+
+func TestProductValidationFieldViolations(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateProduct(ctx, &proto.CreateProductRequest{Name: "Mouse", Price: 10, Stock: 1})
+	if err != nil {
+		t.Fatalf("CreateProduct: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		req  *proto.CreateProductRequest
+		want []string
+	}{
+		{name: "missing name", req: &proto.CreateProductRequest{Price: 10}, want: []string{"name"}},
+		{name: "long name", req: &proto.CreateProductRequest{Name: strings.Repeat("n", maxNameLength+1), Price: 10}, want: []string{"name"}},
+		{name: "negative stock", req: &proto.CreateProductRequest{Name: "Mouse", Price: 10, Stock: -1}, want: []string{"stock"}},
+		{name: "negative price", req: &proto.CreateProductRequest{Name: "Mouse", Price: -1}, want: []string{"price"}},
+		{name: "price above max", req: &proto.CreateProductRequest{Name: "Mouse", Price: testOptions.MaxPrice + 1}, want: []string{"price"}},
+		{name: "NaN price", req: &proto.CreateProductRequest{Name: "Mouse", Price: math.NaN()}, want: []string{"price"}},
+		{
+			name: "every field at once",
+			req: &proto.CreateProductRequest{
+				Price:      math.Inf(1),
+				Stock:      -5,
+				Tags:       []string{"a", "b", "c", "d", "e", "f"},
+				Sku:        "has space",
+				Attributes: map[string]string{"": "blank"},
+			},
+			want: []string{"name", "price", "stock", "sku", "tags", "attributes"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.CreateProduct(ctx, tc.req)
+			if got := violatedFields(t, err); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("CreateProduct violated fields = %q, want %q", got, tc.want)
+			}
+
+			_, err = client.UpdateProduct(ctx, &proto.UpdateProductRequest{
+				Id:         created.Id,
+				Name:       tc.req.Name,
+				Price:      tc.req.Price,
+				Stock:      tc.req.Stock,
+				Sku:        tc.req.Sku,
+				Tags:       tc.req.Tags,
+				Attributes: tc.req.Attributes,
+			})
+			if got := violatedFields(t, err); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("UpdateProduct violated fields = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}